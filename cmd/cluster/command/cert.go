@@ -0,0 +1,176 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newCertCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cert",
+		Short: "Manage the TLS certificates of a TiDB cluster",
+	}
+
+	cmd.AddCommand(newCertRotateCmd())
+	return cmd
+}
+
+func newCertRotateCmd() *cobra.Command {
+	var rotateCA, rotateCerts bool
+
+	cmd := &cobra.Command{
+		Use:   "rotate <cluster-name>",
+		Short: "Rotate the TLS certificates of a TiDB cluster",
+		Long: `Rotate the TLS certificates of a TiDB cluster, and restart the instances using
+them one by one, the leaders are transferred before restarting PD and TiKV.
+
+The certificates of the instances are signed again with the CA of the cluster by
+default. With --ca, a new CA is generated to sign the certificates, the previous
+CA is still trusted by the instances until the next rotation of the CA so that
+the cluster keeps working during the restart. Rotate both with:
+
+  tiup cluster cert rotate <cluster-name> --ca --certs`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot rotate the certificates of non-exists cluster %s", clusterName)
+			}
+
+			logger.EnableAuditLog()
+			unlock, err := lockCluster(clusterName)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
+			metadata, err := clusterMetadata(clusterName)
+			if err != nil {
+				return err
+			}
+			if !metadata.Topology.GlobalOptions.TLSEnabled {
+				return errors.Errorf("TLS is not enabled for cluster %s", clusterName)
+			}
+
+			if !rotateCA && !rotateCerts {
+				rotateCerts = true
+			}
+
+			ctx := newContext()
+			defer ctx.Close()
+			// the instances are restarted after the rotation of the CA, so that
+			// they trust the certificates signed by the new CA before getting
+			// them
+			if rotateCA {
+				if err := rotateCertificates(ctx, clusterName, metadata, true); err != nil {
+					return err
+				}
+				if !gOpt.DryRun {
+					if _, err := clusterMetadata(clusterName); err != nil {
+						return err
+					}
+				}
+			}
+			if rotateCerts {
+				if err := rotateCertificates(ctx, clusterName, metadata, false); err != nil {
+					return err
+				}
+			}
+
+			if gOpt.DryRun {
+				printDryRunPlan(ctx)
+				return nil
+			}
+
+			log.Infof("Rotated the certificates of cluster `%s` successfully", clusterName)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&rotateCA, "ca", false, "Generate a new CA to sign the certificates, the previous CA is trusted until the next rotation of the CA")
+	cmd.Flags().BoolVar(&rotateCerts, "certs", false, "Sign the certificates of the instances again with the CA, it's the default if --ca is not set")
+	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
+	addDryRunFlag(cmd)
+
+	return cmd
+}
+
+// rotateCertificates rotates the CA or the certificates of the instances, and
+// then restarts the instances like reload does
+func rotateCertificates(ctx *task.Context, clusterName string, metadata *meta.ClusterMeta, ca bool) error {
+	topo := metadata.Topology
+	var (
+		roles     []string
+		copyTasks []task.Task
+	)
+	topo.IterInstance(func(inst meta.Instance) {
+		if !topo.NeedTLSCert(inst.ComponentName()) {
+			return
+		}
+		// the instances are iterated component by component
+		if len(roles) == 0 || roles[len(roles)-1] != inst.ComponentName() {
+			roles = append(roles, inst.ComponentName())
+		}
+
+		deployDir := clusterutil.Abs(metadata.User, inst.DeployDir())
+		tb := task.NewBuilder()
+		if ca {
+			caDst, _, _ := meta.TLSCertKeyPaths(deployDir, inst.ComponentName())
+			tb.CopyFile(meta.ClusterPath(clusterName, meta.TLSCertKeyDir, meta.TLSCACert), caDst, inst.GetHost(), false)
+		} else {
+			tb.TLSCert(clusterName, inst, deployDir)
+		}
+		copyTasks = append(copyTasks, tb.Build())
+	})
+
+	options := gOpt
+	options.Roles = roles
+	b := task.NewBuilder().
+		SSHKeySet(
+			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+		ClusterSSH(topo, metadata.User, gOpt.SSHTimeout)
+	if ca {
+		b.TLSRotate(clusterName, true).
+			Parallel(copyTasks...)
+	} else {
+		// the client certificate is signed after the ones of the instances,
+		// see TLSRotate
+		b.Parallel(copyTasks...).
+			TLSRotate(clusterName, false)
+	}
+	t := b.ClusterOperate(topo, operator.UpgradeOperation, options).Build()
+
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return errors.Trace(err)
+	}
+	return nil
+}
@@ -15,8 +15,8 @@ package command
 
 import (
 	"fmt"
-	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/fatih/color"
@@ -24,9 +24,11 @@ import (
 	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil/prepare"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/module"
 	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
@@ -37,6 +39,7 @@ import (
 
 type checkOptions struct {
 	user         string // username to login to the SSH server
+	sshUser      string // user actually used to login, empty to use the one in the OpenSSH client config
 	identityFile string // path to the private key file
 	usePassword  bool   // use password instead of identity file for ssh connection
 	opr          *operator.CheckOptions
@@ -46,8 +49,7 @@ type checkOptions struct {
 
 func newCheckCmd() *cobra.Command {
 	opt := checkOptions{
-		opr:          &operator.CheckOptions{},
-		identityFile: path.Join(utils.UserHome(), ".ssh", "id_rsa"),
+		opr: &operator.CheckOptions{},
 	}
 	cmd := &cobra.Command{
 		Use:   "check <topology.yml | cluster-name>",
@@ -70,15 +72,24 @@ conflict checks with other clusters`,
 				if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
 					return errors.Errorf("cluster %s does not exist", clusterName)
 				}
+				// the fixes change the hosts of the cluster
+				if opt.applyFix {
+					unlock, err := lockCluster(clusterName)
+					if err != nil {
+						return err
+					}
+					defer unlock()
+				}
 				metadata, err := meta.ClusterMetadata(clusterName)
 				if err != nil {
 					return err
 				}
 				topo = *metadata.Topology
 			} else { // check before cluster is deployed
-				if err := utils.ParseTopologyYaml(args[0], &topo); err != nil {
+				if err := meta.ParseTopologyYaml(args[0], &topo); err != nil {
 					return err
 				}
+				warnLabelProblems(&topo)
 
 				// use a dummy cluster name, the real cluster name is set during deploy
 				if err := prepare.CheckClusterPortConflict("nonexist-dummy-tidb-cluster", &topo); err != nil {
@@ -89,6 +100,12 @@ conflict checks with other clusters`,
 				}
 			}
 
+			// the check tools are owned by the current user if the login user is
+			// left to the OpenSSH client config
+			opt.sshUser, opt.identityFile = executor.DefaultLogin(topo.GlobalOptions.SSHType, opt.user, opt.identityFile)
+			if opt.user == "" {
+				opt.user = utils.CurrentUser()
+			}
 			sshConnProps, err := cliutil.ReadIdentityFileOrPassword(opt.identityFile, opt.usePassword)
 			if err != nil {
 				return err
@@ -102,8 +119,8 @@ conflict checks with other clusters`,
 		},
 	}
 
-	cmd.Flags().StringVar(&opt.user, "user", utils.CurrentUser(), "The user name to login via SSH. The user must has root (or sudo) privilege.")
-	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", opt.identityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().StringVar(&opt.user, "user", "", "The user name to login via SSH, the current user by default, or the one in the OpenSSH client config if ssh_type is openssh. The user must has root (or sudo) privilege.")
+	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", "", "The path of the SSH identity file, ~/.ssh/id_rsa by default, or the ones in the OpenSSH client config if ssh_type is openssh. If specified, public key authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.usePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
 
 	cmd.Flags().BoolVar(&opt.opr.EnableCPU, "enable-cpu", false, "Enable CPU thread count check")
@@ -126,7 +143,7 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *meta.TopologySpecifica
 	)
 	insightVer := meta.ComponentVersion(meta.ComponentCheckCollector, "")
 
-	uniqueHosts := map[string]int{}             // host -> ssh-port
+	uniqueHosts := map[string]hostInfo{}        // host -> ssh-port, ssh-proxy
 	uniqueArchList := make(map[string]struct{}) // map["os-arch"]{}
 	topo.IterInstance(func(inst meta.Instance) {
 		archKey := fmt.Sprintf("%s-%s", inst.OS(), inst.Arch())
@@ -143,18 +160,23 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *meta.TopologySpecifica
 			downloadTasks = append(downloadTasks, t0)
 		}
 		if _, found := uniqueHosts[inst.GetHost()]; !found {
-			uniqueHosts[inst.GetHost()] = inst.GetSSHPort()
+			uniqueHosts[inst.GetHost()] = hostInfo{
+				ssh:   inst.GetSSHPort(),
+				proxy: inst.GetSSHProxy(),
+			}
 
 			// build system info collecting tasks
 			t1 := task.NewBuilder().
 				RootSSH(
 					inst.GetHost(),
 					inst.GetSSHPort(),
-					opt.user,
+					opt.sshUser,
 					s.Password,
 					s.IdentityFile,
 					s.IdentityFilePassphrase,
 					gOpt.SSHTimeout,
+					topo.GlobalOptions.SSHType,
+					inst.GetSSHProxy(),
 				).
 				Mkdir(opt.user, inst.GetHost(), filepath.Join(task.CheckToolsPathDir, "bin")).
 				CopyComponent(
@@ -258,11 +280,13 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *meta.TopologySpecifica
 				RootSSH(
 					inst.GetHost(),
 					inst.GetSSHPort(),
-					opt.user,
+					opt.sshUser,
 					s.Password,
 					s.IdentityFile,
 					s.IdentityFilePassphrase,
 					gOpt.SSHTimeout,
+					topo.GlobalOptions.SSHType,
+					inst.GetSSHProxy(),
 				).
 				Rmdir(inst.GetHost(), task.CheckToolsPathDir).
 				BuildAsStep(fmt.Sprintf("  - Cleanup check files on %s:%d", inst.GetHost(), inst.GetSSHPort()))
@@ -277,7 +301,8 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *meta.TopologySpecifica
 		ParallelStep("+ Cleanup check files", cleanTasks...).
 		Build()
 
-	ctx := task.NewContext()
+	ctx := newContext()
+	defer ctx.Close()
 	if err := t.Execute(ctx); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
@@ -296,12 +321,14 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *meta.TopologySpecifica
 		tf := task.NewBuilder().
 			RootSSH(
 				host,
-				uniqueHosts[host],
-				opt.user,
+				uniqueHosts[host].ssh,
+				opt.sshUser,
 				s.Password,
 				s.IdentityFile,
 				s.IdentityFilePassphrase,
 				gOpt.SSHTimeout,
+				topo.GlobalOptions.SSHType,
+				uniqueHosts[host].proxy,
 			)
 		resLines, err := handleCheckResults(ctx, host, opt, tf)
 		if err != nil {
@@ -410,6 +437,17 @@ func fixFailedChecks(ctx *task.Context, host string, res *operator.CheckResult,
 			),
 			true)
 		msg = fmt.Sprintf("will try to %s, reboot might be needed", color.HiBlueString("disable SELinux"))
+	case operator.CheckNameFirewall:
+		var rules []module.FirewallRule
+		for _, p := range strings.Split(res.Msg, ",") {
+			port, err := strconv.Atoi(p)
+			if err != nil {
+				return "", fmt.Errorf("can not open ports of firewall, %s", res.Msg)
+			}
+			rules = append(rules, module.FirewallRule{Port: port})
+		}
+		t.Firewall(host, rules, nil)
+		msg = fmt.Sprintf("will try to open the ports %s of firewall", color.HiBlueString(res.Msg))
 	case operator.CheckNameOSVer,
 		operator.CheckNameCPUThreads,
 		operator.CheckNameDisks,
@@ -15,11 +15,13 @@ package command
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
@@ -28,9 +30,11 @@ import (
 	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil/prepare"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/module"
 	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/report"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
@@ -40,6 +44,7 @@ import (
 	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
 	"github.com/pingcap/errors"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 )
 
 var (
@@ -64,20 +69,21 @@ type (
 		user         string // username to login to the SSH server
 		identityFile string // path to the private key file
 		usePassword  bool   // use password instead of identity file for ssh connection
+		resume       bool   // skip the tasks done by the last failed deploy
+		rollback     bool   // roll back the tasks done if it fails
 	}
 
 	hostInfo struct {
-		ssh  int    // ssh port of host
-		os   string // operating system
-		arch string // cpu architecture
+		ssh   int            // ssh port of host
+		proxy *meta.SSHProxy // ssh jump host of host
+		os    string         // operating system
+		arch  string         // cpu architecture
 		// vendor string
 	}
 )
 
 func newDeploy() *cobra.Command {
-	opt := deployOptions{
-		identityFile: path.Join(utils.UserHome(), ".ssh", "id_rsa"),
-	}
+	opt := deployOptions{}
 	cmd := &cobra.Command{
 		Use:          "deploy <cluster-name> <version> <topology.yaml>",
 		Short:        "Deploy a cluster for production",
@@ -97,9 +103,12 @@ func newDeploy() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&opt.user, "user", utils.CurrentUser(), "The user name to login via SSH. The user must has root (or sudo) privilege.")
-	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", opt.identityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().StringVar(&opt.user, "user", "", "The user name to login via SSH, the current user by default, or the one in the OpenSSH client config if ssh_type is openssh. The user must has root (or sudo) privilege.")
+	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", "", "The path of the SSH identity file, ~/.ssh/id_rsa by default, or the ones in the OpenSSH client config if ssh_type is openssh. If specified, public key authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.usePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+	cmd.Flags().BoolVar(&opt.resume, "resume", false, "Resume the last failed deploy, the steps done by it are skipped.")
+	cmd.Flags().BoolVar(&opt.rollback, "rollback", false, "Roll back the steps done if the deploy fails instead of keeping them to be resumed with --resume.")
+	addDryRunFlag(cmd)
 
 	return cmd
 }
@@ -146,6 +155,14 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 	if err := utils.ValidateClusterNameOrError(clusterName); err != nil {
 		return err
 	}
+	// the lock is taken before checking the name, so that the cluster is not
+	// deployed twice at the same time
+	unlock, err := lockCluster(clusterName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if tiuputils.IsExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
 		// FIXME: When change to use args, the suggestion text need to be updated.
 		return errDeployNameDuplicate.
@@ -154,7 +171,10 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 	}
 
 	var topo meta.TopologySpecification
-	if err := utils.ParseTopologyYaml(topoFile, &topo); err != nil {
+	if err := meta.ParseTopologyYaml(topoFile, &topo); err != nil {
+		return err
+	}
+	if err := confirmLabelProblems(&topo, nil); err != nil {
 		return err
 	}
 
@@ -169,32 +189,38 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 		return err
 	}
 
-	if !skipConfirm {
+	if !skipConfirm && !gOpt.DryRun {
 		if err := confirmTopology(clusterName, clusterVersion, &topo, set.NewStringSet()); err != nil {
 			return err
 		}
 	}
 
+	opt.user, opt.identityFile = executor.DefaultLogin(topo.GlobalOptions.SSHType, opt.user, opt.identityFile)
 	sshConnProps, err := cliutil.ReadIdentityFileOrPassword(opt.identityFile, opt.usePassword)
 	if err != nil {
 		return err
 	}
 
-	if err := os.MkdirAll(meta.ClusterPath(clusterName), 0755); err != nil {
-		return errorx.InitializationFailed.
-			Wrap(err, "Failed to create cluster metadata directory '%s'", meta.ClusterPath(clusterName)).
-			WithProperty(cliutil.SuggestionFromString("Please check file system permissions and try again."))
+	if !gOpt.DryRun {
+		if err := os.MkdirAll(meta.ClusterPath(clusterName), 0755); err != nil {
+			return errorx.InitializationFailed.
+				Wrap(err, "Failed to create cluster metadata directory '%s'", meta.ClusterPath(clusterName)).
+				WithProperty(cliutil.SuggestionFromString("Please check file system permissions and try again."))
+		}
 	}
 
 	var (
-		envInitTasks      []*task.StepDisplay // tasks which are used to initialize environment
-		downloadCompTasks []*task.StepDisplay // tasks which are used to download components
-		deployCompTasks   []*task.StepDisplay // tasks which are used to copy components to remote host
+		envInitTasks      = make(map[string]*task.StepDisplay) // host -> task which is used to initialize environment
+		hosts             []string                             // hosts in the order of initializing environment
+		downloadCompTasks []*task.StepDisplay                  // tasks which are used to download components
+		deployCompTasks   []*task.StepDisplay                  // tasks which are used to copy components to remote host
+		deployDeps        = make(map[task.Task][]task.Task)    // task -> tasks it waits for
 	)
 
 	// Initialize environment
 	uniqueHosts := make(map[string]hostInfo) // host -> ssh-port, os, arch
 	globalOptions := topo.GlobalOptions
+	firewallRules := topo.FirewallRules()
 	var iterErr error // error when itering over instances
 	iterErr = nil
 	topo.IterInstance(func(inst meta.Instance) {
@@ -209,9 +235,10 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 			}
 
 			uniqueHosts[inst.GetHost()] = hostInfo{
-				ssh:  inst.GetSSHPort(),
-				os:   inst.OS(),
-				arch: inst.Arch(),
+				ssh:   inst.GetSSHPort(),
+				proxy: inst.GetSSHProxy(),
+				os:    inst.OS(),
+				arch:  inst.Arch(),
 			}
 			var dirs []string
 			for _, dir := range []string{globalOptions.DeployDir, globalOptions.LogDir} {
@@ -233,11 +260,15 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 					sshConnProps.IdentityFile,
 					sshConnProps.IdentityFilePassphrase,
 					gOpt.SSHTimeout,
+					globalOptions.SSHType,
+					inst.GetSSHProxy(),
 				).
 				EnvInit(inst.GetHost(), globalOptions.User).
 				Mkdir(globalOptions.User, inst.GetHost(), dirs...).
+				Firewall(inst.GetHost(), firewallRules[inst.GetHost()], nil).
 				BuildAsStep(fmt.Sprintf("  - Prepare %s:%d", inst.GetHost(), inst.GetSSHPort()))
-			envInitTasks = append(envInitTasks, t)
+			envInitTasks[inst.GetHost()] = t
+			hosts = append(hosts, inst.GetHost())
 		}
 	})
 
@@ -246,7 +277,7 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 	}
 
 	// Download missing component
-	downloadCompTasks = prepare.BuildDownloadCompTasks(clusterVersion, &topo)
+	downloadCompTasks, downloadTaskMap := prepare.BuildDownloadCompTaskMap(clusterVersion, &topo)
 
 	// Deploy components to remote
 	topo.IterInstance(func(inst meta.Instance) {
@@ -257,8 +288,8 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 		// log dir will always be with values, but might not used by the component
 		logDir := clusterutil.Abs(globalOptions.User, inst.LogDir())
 		// Deploy component
-		t := task.NewBuilder().
-			UserSSH(inst.GetHost(), inst.GetSSHPort(), globalOptions.User, gOpt.SSHTimeout).
+		tb := task.NewBuilder().
+			UserSSH(inst.GetHost(), inst.GetSSHPort(), globalOptions.User, gOpt.SSHTimeout, globalOptions.SSHType, inst.GetSSHProxy()).
 			Mkdir(globalOptions.User, inst.GetHost(),
 				deployDir, logDir,
 				filepath.Join(deployDir, "bin"),
@@ -272,21 +303,27 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 				version,
 				inst.GetHost(),
 				deployDir,
-			).
-			InitConfig(
-				clusterName,
-				clusterVersion,
-				inst,
-				globalOptions.User,
-				meta.DirPaths{
-					Deploy: deployDir,
-					Data:   dataDirs,
-					Log:    logDir,
-					Cache:  meta.ClusterPath(clusterName, meta.TempConfigPath),
-				},
-			).
-			BuildAsStep(fmt.Sprintf("  - Copy %s -> %s", inst.ComponentName(), inst.GetHost()))
+			)
+		if topo.NeedTLSCert(inst.ComponentName()) {
+			tb.Mkdir(globalOptions.User, inst.GetHost(), filepath.Join(deployDir, meta.TLSCertKeyDir)).
+				TLSCert(clusterName, inst, deployDir)
+		}
+		t := tb.InitConfig(
+			clusterName,
+			clusterVersion,
+			inst,
+			globalOptions.User,
+			meta.DirPaths{
+				Deploy: deployDir,
+				Data:   dataDirs,
+				Log:    logDir,
+				Cache:  meta.ClusterPath(clusterName, meta.TempConfigPath),
+			},
+		).BuildAsStep(fmt.Sprintf("  - Copy %s -> %s", inst.ComponentName(), inst.GetHost()))
 		deployCompTasks = append(deployCompTasks, t)
+		// the component is copied once the host is ready, no matter whether the
+		// other hosts are
+		deployDeps[t] = []task.Task{envInitTasks[inst.GetHost()], downloadTaskMap[prepare.DownloadCompKey(inst)]}
 	})
 
 	nodeInfoTask := task.NewBuilder().Func("Check status", func(ctx *task.Context) error {
@@ -306,25 +343,50 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 		clusterVersion,
 	)
 	downloadCompTasks = append(downloadCompTasks, dlTasks...)
-	deployCompTasks = append(deployCompTasks, dpTasks...)
-	if report.Enable() {
-		deployCompTasks = append(deployCompTasks, nodeInfoTask)
+	for _, host := range hosts {
+		for _, t := range dpTasks[host] {
+			deployCompTasks = append(deployCompTasks, t)
+			// there are only a few downloads of the monitoring agents
+			deployDeps[t] = append(convertStepDisplaysToTasks(dlTasks), envInitTasks[host])
+		}
+	}
+	// each host is initialized and then gets its components copied without
+	// waiting for the other hosts
+	graph := task.NewGraph()
+	for _, t := range downloadCompTasks {
+		graph.Add(t)
+	}
+	for _, host := range hosts {
+		graph.Add(envInitTasks[host])
+	}
+	for _, t := range deployCompTasks {
+		graph.Add(t, deployDeps[t]...)
 	}
 
 	builder := task.NewBuilder().
 		Step("+ Generate SSH keys",
-			task.NewBuilder().SSHKeyGen(meta.ClusterPath(clusterName, "ssh", "id_rsa")).Build()).
-		ParallelStep("+ Download TiDB components", downloadCompTasks...).
-		ParallelStep("+ Initialize target host environments", envInitTasks...).
-		ParallelStep("+ Copy files", deployCompTasks...)
+			task.NewBuilder().SSHKeyGen(meta.ClusterPath(clusterName, "ssh", "id_rsa")).Build())
+	if globalOptions.TLSEnabled {
+		builder.Step("+ Generate TLS certificates",
+			task.NewBuilder().TLSCAGen(clusterName).Build())
+	}
+	builder.GraphStep("+ Download components, initialize hosts and copy files", graph)
 
-	if report.Enable() {
+	if report.Enable() && !gOpt.DryRun {
 		builder.ParallelStep("+ Check status", nodeInfoTask)
 	}
 
 	t := builder.Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	ctx := newContext()
+	defer ctx.Close()
+	if !gOpt.DryRun {
+		if ctx.Checkpoint, err = newCheckpoint(clusterName, "deploy", clusterVersion, &topo, opt.resume); err != nil {
+			return err
+		}
+	}
+	if err := t.Execute(ctx); err != nil {
+		rollbackOnFailure(ctx, t, opt.rollback)
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -332,6 +394,11 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 		return errors.Trace(err)
 	}
 
+	if gOpt.DryRun {
+		printDryRunPlan(ctx)
+		return nil
+	}
+
 	err = meta.SaveClusterMeta(clusterName, &meta.ClusterMeta{
 		User:     globalOptions.User,
 		Version:  clusterVersion,
@@ -340,19 +407,43 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if err := ctx.Checkpoint.Remove(); err != nil {
+		return err
+	}
 
 	hint := color.New(color.Bold).Sprintf("%s start %s", cliutil.OsArgs0(), clusterName)
 	log.Infof("Deployed cluster `%s` successfully, you can start the cluster via `%s`", clusterName, hint)
 	return nil
 }
 
+// newCheckpoint creates the checkpoint of the operation on the cluster, which
+// is only resumed if the operation is run with the same version and topology
+func newCheckpoint(clusterName, operation, version string, topo *meta.TopologySpecification, resume bool) (*task.Checkpoint, error) {
+	data, err := yaml.Marshal(topo)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	digest := sha256.Sum256(append([]byte(version+"\n"), data...))
+	path := meta.ClusterPath(clusterName, meta.CheckpointDirName, operation)
+	cp, err := task.NewCheckpoint(path, hex.EncodeToString(digest[:]), resume)
+	if err != nil {
+		return nil, err
+	}
+	if n := cp.Len(); n > 0 {
+		log.Infof("Resuming the last %s of cluster `%s`, %d steps done by it will be skipped", operation, clusterName, n)
+	}
+	return cp, nil
+}
+
 func buildMonitoredDeployTask(
 	clusterName string,
 	uniqueHosts map[string]hostInfo, // host -> ssh-port, os, arch
 	globalOptions meta.GlobalOptions,
 	monitoredOptions meta.MonitoredOptions,
 	version string,
-) (downloadCompTasks []*task.StepDisplay, deployCompTasks []*task.StepDisplay) {
+) (downloadCompTasks []*task.StepDisplay, deployCompTasks map[string][]*task.StepDisplay) {
+	deployCompTasks = make(map[string][]*task.StepDisplay) // host -> tasks
+
 	uniqueCompOSArch := make(map[string]struct{}) // comp-os-arch -> {}
 	// monitoring agents
 	for _, comp := range []string{meta.ComponentNodeExporter, meta.ComponentBlackboxExporter} {
@@ -379,7 +470,7 @@ func buildMonitoredDeployTask(
 			logDir := clusterutil.Abs(globalOptions.User, monitoredOptions.LogDir)
 			// Deploy component
 			t := task.NewBuilder().
-				UserSSH(host, info.ssh, globalOptions.User, gOpt.SSHTimeout).
+				UserSSH(host, info.ssh, globalOptions.User, gOpt.SSHTimeout, globalOptions.SSHType, info.proxy).
 				Mkdir(globalOptions.User, host,
 					deployDir, dataDir, logDir,
 					filepath.Join(deployDir, "bin"),
@@ -408,8 +499,30 @@ func buildMonitoredDeployTask(
 					},
 				).
 				BuildAsStep(fmt.Sprintf("  - Copy %s -> %s", comp, host))
-			deployCompTasks = append(deployCompTasks, t)
+			deployCompTasks[host] = append(deployCompTasks[host], t)
 		}
 	}
 	return
 }
+
+// buildFirewallTasks builds the tasks changing the firewalls of hosts from the
+// old rules to the new ones
+func buildFirewallTasks(old, new map[string][]module.FirewallRule) []task.Task {
+	allow, remove := meta.DiffFirewallRules(old, new)
+	var hosts []string
+	for host := range allow {
+		hosts = append(hosts, host)
+	}
+	for host := range remove {
+		if _, ok := allow[host]; !ok {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+
+	var tasks []task.Task
+	for _, host := range hosts {
+		tasks = append(tasks, task.NewBuilder().Firewall(host, allow[host], remove[host]).Build())
+	}
+	return tasks
+}
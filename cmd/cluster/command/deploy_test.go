@@ -0,0 +1,42 @@
+package command
+
+import (
+	"path/filepath"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/check"
+	"github.com/spf13/cobra"
+)
+
+type deploySuite struct{}
+
+var _ = check.Suite(&deploySuite{})
+
+func (s *deploySuite) TestSSHLoginDefaults(c *check.C) {
+	for _, cmd := range []*cobra.Command{newDeploy(), newScaleOutCmd(), newCheckCmd()} {
+		c.Assert(cmd.ParseFlags(nil), check.IsNil)
+		user, err := cmd.Flags().GetString("user")
+		c.Assert(err, check.IsNil)
+		identityFile, err := cmd.Flags().GetString("identity_file")
+		c.Assert(err, check.IsNil)
+
+		// left to the OpenSSH client config if the flags are not set
+		u, k := executor.DefaultLogin(executor.SSHTypeOpenSSH, user, identityFile)
+		c.Assert(u, check.Equals, "")
+		c.Assert(k, check.Equals, "")
+
+		u, k = executor.DefaultLogin(executor.SSHTypeBuiltin, user, identityFile)
+		c.Assert(u, check.Equals, utils.CurrentUser())
+		c.Assert(k, check.Equals, filepath.Join(utils.UserHome(), ".ssh", "id_rsa"))
+	}
+
+	// the flags set take precedence over the config
+	cmd := newDeploy()
+	c.Assert(cmd.ParseFlags([]string{"--user", "admin", "-i", "/key"}), check.IsNil)
+	user, _ := cmd.Flags().GetString("user")
+	identityFile, _ := cmd.Flags().GetString("identity_file")
+	u, k := executor.DefaultLogin(executor.SSHTypeOpenSSH, user, identityFile)
+	c.Assert(u, check.Equals, "admin")
+	c.Assert(k, check.Equals, "/key")
+}
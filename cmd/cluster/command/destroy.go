@@ -44,12 +44,18 @@ func newDestroyCmd() *cobra.Command {
 			}
 
 			logger.EnableAuditLog()
+			unlock, err := lockCluster(clusterName)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			metadata, err := meta.ClusterMetadata(clusterName)
 			if err != nil {
 				return err
 			}
 
-			if !skipConfirm {
+			if !skipConfirm && !gOpt.DryRun {
 				if err := cliutil.PromptForConfirmOrAbortError(
 					"This operation will destroy TiDB %s cluster %s and its data.\nDo you want to continue? [y/N]:",
 					color.HiYellowString(metadata.Version),
@@ -68,7 +74,9 @@ func newDestroyCmd() *cobra.Command {
 				ClusterOperate(metadata.Topology, operator.DestroyOperation, operator.Options{}).
 				Build()
 
-			if err := t.Execute(task.NewContext()); err != nil {
+			ctx := newContext()
+			defer ctx.Close()
+			if err := t.Execute(ctx); err != nil {
 				if errorx.Cast(err) != nil {
 					// FIXME: Map possible task errors and give suggestions.
 					return err
@@ -76,6 +84,11 @@ func newDestroyCmd() *cobra.Command {
 				return errors.Trace(err)
 			}
 
+			if gOpt.DryRun {
+				printDryRunPlan(ctx)
+				return nil
+			}
+
 			if err := os.RemoveAll(meta.ClusterPath(clusterName)); err != nil {
 				return errors.Trace(err)
 			}
@@ -84,5 +97,7 @@ func newDestroyCmd() *cobra.Command {
 		},
 	}
 
+	addDryRunFlag(cmd)
+
 	return cmd
 }
@@ -14,11 +14,15 @@
 package command
 
 import (
+	"crypto/x509"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/certutil"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
@@ -31,6 +35,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// certExpiryWarning is how long before the expiry of certificates to warn
+// about it in display
+const certExpiryWarning = 30 * 24 * time.Hour
+
 func newDisplayCmd() *cobra.Command {
 	var (
 		clusterName string
@@ -51,7 +59,7 @@ func newDisplayCmd() *cobra.Command {
 				return err
 			}
 
-			metadata, err := meta.ClusterMetadata(clusterName)
+			metadata, err := clusterMetadata(clusterName)
 			if err != nil {
 				return errors.AddStack(err)
 			}
@@ -79,10 +87,69 @@ func displayClusterMeta(clusterName string, opt *operator.Options) error {
 
 	fmt.Printf("TiDB Cluster: %s\n", cyan.Sprint(clusterName))
 	fmt.Printf("TiDB Version: %s\n", cyan.Sprint(clsMeta.Version))
+	if clsMeta.Topology.GlobalOptions.TLSEnabled {
+		displayCertExpiry(clusterName, clsMeta.Topology)
+	}
 
 	return nil
 }
 
+// displayCertExpiry shows when the CA, the client certificate and the earliest
+// certificate of the instances expire, the ones failed to be loaded are warned
+// about without stopping displaying the cluster.
+func displayCertExpiry(clusterName string, topo *meta.TopologySpecification) {
+	for _, cert := range []struct {
+		name string
+		file string
+	}{
+		{"TLS CA Expiry", meta.TLSCACert},
+		{"TLS Client Cert Expiry", meta.TLSClientCert},
+	} {
+		path := meta.ClusterPath(clusterName, meta.TLSCertKeyDir, cert.file)
+		c, err := certutil.LoadCertificate(path)
+		if err != nil {
+			log.Warnf("Failed to load the certificate %s: %s", path, err)
+			continue
+		}
+		fmt.Printf("%s: %s\n", cert.name, formatCertExpiry(c.NotAfter))
+	}
+
+	var (
+		earliest *x509.Certificate
+		id       string
+	)
+	topo.IterInstance(func(inst meta.Instance) {
+		if !topo.NeedTLSCert(inst.ComponentName()) {
+			return
+		}
+		certFile, _ := meta.TLSSignedCertKeyPaths(clusterName, inst)
+		cert, err := certutil.LoadCertificate(certFile)
+		if err != nil {
+			log.Warnf("Failed to load the certificate of %s: %s", inst.ID(), err)
+			return
+		}
+		if earliest == nil || cert.NotAfter.Before(earliest.NotAfter) {
+			earliest, id = cert, inst.ID()
+		}
+	})
+	if earliest != nil {
+		fmt.Printf("TLS Cert Expiry: %s (%s)\n", formatCertExpiry(earliest.NotAfter), id)
+	}
+}
+
+// formatCertExpiry highlights the expiry time if it's within certExpiryWarning
+func formatCertExpiry(t time.Time) string {
+	expiry := t.Local().Format("2006-01-02 15:04:05")
+	switch left := time.Until(t); {
+	case left <= 0:
+		return color.RedString("%s (expired)", expiry)
+	case left < certExpiryWarning:
+		return color.YellowString("%s (expires in %d days)", expiry, int(left.Hours()/24))
+	default:
+		return color.CyanString(expiry)
+	}
+}
+
 func destroyTombstoneIfNeed(clusterName string, metadata *meta.ClusterMeta, opt operator.Options) error {
 	topo := metadata.Topology
 
@@ -90,8 +157,21 @@ func destroyTombstoneIfNeed(clusterName string, metadata *meta.ClusterMeta, opt
 		return nil
 	}
 
-	ctx := task.NewContext()
-	err := ctx.SetSSHKeySet(meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+	// display doesn't wait for the other operations, the tombstone nodes are
+	// left to be destroyed next time if the cluster is locked
+	unlock, err := lockCluster(clusterName)
+	if errorx.IsOfType(err, meta.ErrClusterLocked) {
+		log.Warnf("%s, skip destroying the tombstone nodes", errorx.Cast(err).Message())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ctx := newContext()
+	defer ctx.Close()
+	err = ctx.SetSSHKeySet(meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 		meta.ClusterPath(clusterName, "ssh", "id_rsa.pub"))
 	if err != nil {
 		return errors.AddStack(err)
@@ -113,18 +193,29 @@ func destroyTombstoneIfNeed(clusterName string, metadata *meta.ClusterMeta, opt
 
 	log.Infof("Start destroy Tombstone nodes: %v ...", nodes)
 
+	firewallRules := topo.FirewallRules()
 	_, err = operator.DestroyTombstone(ctx, topo, false /* returnNodesOnly */, opt)
 	if err != nil {
 		return errors.AddStack(err)
 	}
 
-	log.Infof("Destroy success")
+	// the nodes are destroyed whether the ports are closed or not
+	if err := meta.SaveClusterMeta(clusterName, metadata); err != nil {
+		return err
+	}
 
-	return meta.SaveClusterMeta(clusterName, metadata)
+	// close the ports of the destroyed nodes
+	firewallTasks := buildFirewallTasks(firewallRules, topo.FirewallRules())
+	if err := task.NewBuilder().Parallel(firewallTasks...).Build().Execute(ctx); err != nil {
+		log.Warnf("Failed to close the ports of the destroyed nodes: %s", err)
+	}
+
+	log.Infof("Destroy success")
+	return nil
 }
 
 func displayClusterTopology(clusterName string, opt *operator.Options) error {
-	metadata, err := meta.ClusterMetadata(clusterName)
+	metadata, err := clusterMetadata(clusterName)
 	if err != nil {
 		return err
 	}
@@ -136,7 +227,8 @@ func displayClusterTopology(clusterName string, opt *operator.Options) error {
 		{"ID", "Role", "Host", "Ports", "OS/Arch", "Status", "Data Dir", "Deploy Dir"},
 	}
 
-	ctx := task.NewContext()
+	ctx := newContext()
+	defer ctx.Close()
 	err = ctx.SetSSHKeySet(meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 		meta.ClusterPath(clusterName, "ssh", "id_rsa.pub"))
 	if err != nil {
@@ -169,7 +261,7 @@ func displayClusterTopology(clusterName string, opt *operator.Options) error {
 				dataDir = insDirs[1]
 			}
 
-			status := ins.Status(pdList...)
+			status := ins.Status(opt.TLSConfig, pdList...)
 			// Query the service status
 			if status == "-" {
 				e, found := ctx.GetExecutor(ins.GetHost())
@@ -0,0 +1,49 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	"github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// addDryRunFlag adds the --dry-run flag to cmd
+func addDryRunFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&gOpt.DryRun, "dry-run", false, "Print the operations to be performed on each host without performing them")
+}
+
+// printDryRunPlan prints the operations recorded in dry run grouped by host
+func printDryRunPlan(ctx *task.Context) {
+	bold := color.New(color.Bold)
+	fmt.Println()
+	fmt.Println("The operations to be performed (nothing is changed in dry run):")
+	for _, host := range ctx.Recorder.Hosts() {
+		if host == "" {
+			bold.Println("\nControl machine:")
+		} else {
+			bold.Printf("\nHost %s:\n", host)
+		}
+		for _, a := range ctx.Recorder.Actions(host) {
+			fmt.Printf("  %s\n", a)
+		}
+	}
+
+	if utils.IsExist(ctx.DryRunDir()) {
+		fmt.Printf("\nThe generated files are kept in %s for review.\n", color.CyanString(ctx.DryRunDir()))
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/fatih/color"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
@@ -46,6 +47,12 @@ func newEditConfigCmd() *cobra.Command {
 			}
 
 			logger.EnableAuditLog()
+			unlock, err := lockCluster(clusterName)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			metadata, err := meta.ClusterMetadata(clusterName)
 			if err != nil {
 				return err
@@ -96,8 +103,13 @@ func editTopo(clusterName string, metadata *meta.ClusterMeta) error {
 		return errors.AddStack(err)
 	}
 
+	// the relative paths of the included files are relative to the working
+	// directory instead of the temporary file
 	newTopo := new(meta.TopologySpecification)
-	err = yaml.UnmarshalStrict(newData, newTopo)
+	files, err := meta.NewTopologyFiles(filepath.Base(name), newData)
+	if err == nil {
+		err = files.Unmarshal(newTopo)
+	}
 	if err != nil {
 		log.Infof("Failed to parse topology file: %v", err)
 		return errors.AddStack(err)
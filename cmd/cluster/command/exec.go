@@ -47,6 +47,13 @@ func newExecCmd() *cobra.Command {
 			}
 
 			logger.EnableAuditLog()
+			// the command may change the hosts of the cluster
+			unlock, err := lockCluster(clusterName)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			metadata, err := meta.ClusterMetadata(clusterName)
 			if err != nil {
 				return err
@@ -86,7 +93,8 @@ func newExecCmd() *cobra.Command {
 				Parallel(shellTasks...).
 				Build()
 
-			execCtx := task.NewContext()
+			execCtx := newContext()
+			defer execCtx.Close()
 			if err := t.Execute(execCtx); err != nil {
 				if errorx.Cast(err) != nil {
 					// FIXME: Map possible task errors and give suggestions.
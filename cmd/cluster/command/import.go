@@ -70,6 +70,12 @@ func newImportCmd() *cobra.Command {
 						fmt.Sprintf("Please use --rename `NAME` to specify another name (You can use `%s list` to see all clusters)", cliutil.OsArgs0())))
 			}
 
+			unlock, err := lockCluster(clsName)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			// prompt for backups
 			backupDir := meta.ClusterPath(clsName, "ansible-backup")
 			backupFile := filepath.Join(ansibleDir, fmt.Sprintf("tiup-%s.bak", inventoryFileName))
@@ -59,6 +59,7 @@ func newPatchCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Specify the nodes")
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Specify the role")
 	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
+	addDryRunFlag(cmd)
 	return cmd
 }
 
@@ -67,11 +68,17 @@ func patch(clusterName, packagePath string, options operator.Options, overwrite
 		return errors.Errorf("cannot patch non-exists cluster %s", clusterName)
 	}
 
+	unlock, err := lockCluster(clusterName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if exist := tiuputils.IsExist(packagePath); !exist {
 		return errors.New("specified package not exists")
 	}
 
-	metadata, err := meta.ClusterMetadata(clusterName)
+	metadata, err := clusterMetadata(clusterName)
 	if err != nil {
 		return err
 	}
@@ -102,7 +109,9 @@ func patch(clusterName, packagePath string, options operator.Options, overwrite
 		ClusterOperate(metadata.Topology, operator.UpgradeOperation, options).
 		Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	ctx := newContext()
+	defer ctx.Close()
+	if err := t.Execute(ctx); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -110,6 +119,11 @@ func patch(clusterName, packagePath string, options operator.Options, overwrite
 		return errors.Trace(err)
 	}
 
+	if gOpt.DryRun {
+		printDryRunPlan(ctx)
+		return nil
+	}
+
 	if overwrite {
 		if err := overwritePatch(clusterName, insts[0].ComponentName(), packagePath); err != nil {
 			return err
@@ -146,7 +160,7 @@ func instancesToPatch(metadata *meta.ClusterMeta, options operator.Options) ([]m
 }
 
 func checkPackage(clusterName, comp, nodeOS, arch, packagePath string) error {
-	metadata, err := meta.ClusterMetadata(clusterName)
+	metadata, err := clusterMetadata(clusterName)
 	if err != nil {
 		return err
 	}
@@ -45,7 +45,13 @@ func newReloadCmd() *cobra.Command {
 			}
 
 			logger.EnableAuditLog()
-			metadata, err := meta.ClusterMetadata(clusterName)
+			unlock, err := lockCluster(clusterName)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
+			metadata, err := clusterMetadata(clusterName)
 			if err != nil {
 				return err
 			}
@@ -55,7 +61,9 @@ func newReloadCmd() *cobra.Command {
 				return err
 			}
 
-			if err := t.Execute(task.NewContext()); err != nil {
+			ctx := newContext()
+			defer ctx.Close()
+			if err := t.Execute(ctx); err != nil {
 				if errorx.Cast(err) != nil {
 					// FIXME: Map possible task errors and give suggestions.
 					return err
@@ -63,6 +71,11 @@ func newReloadCmd() *cobra.Command {
 				return errors.Trace(err)
 			}
 
+			if gOpt.DryRun {
+				printDryRunPlan(ctx)
+				return nil
+			}
+
 			log.Infof("Reloaded cluster `%s` successfully", clusterName)
 
 			return nil
@@ -72,6 +85,7 @@ func newReloadCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only start specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only start specified nodes")
 	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
+	addDryRunFlag(cmd)
 
 	return cmd
 }
@@ -95,7 +109,7 @@ func buildReloadTask(
 		logDir := clusterutil.Abs(metadata.User, inst.LogDir())
 
 		// Download and copy the latest component to remote if the cluster is imported from Ansible
-		tb := task.NewBuilder().UserSSH(inst.GetHost(), inst.GetSSHPort(), metadata.User, gOpt.SSHTimeout)
+		tb := task.NewBuilder().UserSSH(inst.GetHost(), inst.GetSSHPort(), metadata.User, gOpt.SSHTimeout, metadata.Topology.GlobalOptions.SSHType, inst.GetSSHProxy())
 		if inst.IsImported() {
 			switch compName := inst.ComponentName(); compName {
 			case meta.ComponentGrafana, meta.ComponentPrometheus, meta.ComponentAlertManager:
@@ -0,0 +1,195 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/edit"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+func newMetaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "meta",
+		Short: "Manage the revisions of the meta of a TiDB cluster",
+		Long: fmt.Sprintf(`Manage the revisions of the meta of a TiDB cluster. A revision is kept each time
+the meta is saved, e.g: by deploy, scale-in, scale-out and edit-config, the last
+%d revisions are kept.`, meta.MaxMetaRevisions),
+	}
+
+	cmd.AddCommand(
+		newMetaListCmd(),
+		newMetaDiffCmd(),
+		newMetaRestoreCmd(),
+	)
+	return cmd
+}
+
+func newMetaListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list <cluster-name>",
+		Short: "List the revisions of the meta of a TiDB cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			revisions, err := metaRevisions(args[0])
+			if err != nil {
+				return err
+			}
+
+			revisionTable := [][]string{{"Revision", "Time", "Audit ID", "Command"}}
+			for i := len(revisions) - 1; i >= 0; i-- {
+				r := revisions[i]
+				id := strconv.Itoa(r.ID)
+				if i == len(revisions)-1 {
+					id += " (current)"
+				}
+				revisionTable = append(revisionTable, []string{
+					id,
+					r.Time.Format(time.RFC3339),
+					r.AuditID,
+					r.Command,
+				})
+			}
+			cliutil.PrintTable(revisionTable, true)
+			return nil
+		},
+	}
+	return cmd
+}
+
+func newMetaDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <cluster-name> <revision> [revision]",
+		Short: "Show the changes between two revisions of the meta of a TiDB cluster",
+		Long: `Show the changes from the first revision to the second one of the meta of a TiDB
+cluster, the second one is the current meta by default.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 && len(args) != 3 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if _, err := metaRevisions(clusterName); err != nil {
+				return err
+			}
+			from, err := metaRevisionData(clusterName, args[1])
+			if err != nil {
+				return err
+			}
+			var to []byte
+			if len(args) == 3 {
+				to, err = metaRevisionData(clusterName, args[2])
+			} else {
+				to, err = ioutil.ReadFile(meta.ClusterPath(clusterName, meta.MetaFileName))
+			}
+			if err != nil {
+				return errors.AddStack(err)
+			}
+
+			edit.ShowDiff(string(from), string(to), os.Stdout)
+			return nil
+		},
+	}
+	return cmd
+}
+
+func newMetaRestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore <cluster-name> <revision>",
+		Short: "Restore the meta of a TiDB cluster to a revision",
+		Long: `Restore the meta of a TiDB cluster to a revision, which is kept as a new revision
+so that the restore can be undone as well. Only the meta is restored, the cluster
+is left untouched, use reload to apply the configs restored if needed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if _, err := metaRevisions(clusterName); err != nil {
+				return err
+			}
+
+			logger.EnableAuditLog()
+			unlock, err := lockCluster(clusterName)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
+			data, err := metaRevisionData(clusterName, args[1])
+			if err != nil {
+				return err
+			}
+			// the meta of the revision is validated before restoring
+			var metadata meta.ClusterMeta
+			if err := yaml.Unmarshal(data, &metadata); err != nil {
+				return errors.Annotatef(err, "failed to parse revision %s of the meta", args[1])
+			}
+
+			current, err := ioutil.ReadFile(meta.ClusterPath(clusterName, meta.MetaFileName))
+			if err != nil {
+				return errors.AddStack(err)
+			}
+			edit.ShowDiff(string(current), string(data), os.Stdout)
+
+			if !skipConfirm {
+				if err := cliutil.PromptForConfirmOrAbortError(
+					color.HiYellowString("Please check the changes highlighted above, do you want to restore the meta to revision %s? [y/N]:", args[1]),
+				); err != nil {
+					return err
+				}
+			}
+
+			if err := meta.SaveClusterMeta(clusterName, &metadata); err != nil {
+				return errors.Annotate(err, "failed to save")
+			}
+			log.Infof("Restored the meta of cluster `%s` to revision %s successfully", clusterName, args[1])
+			return nil
+		},
+	}
+	return cmd
+}
+
+// metaRevisions returns the revisions of the meta of the cluster, which must exist
+func metaRevisions(clusterName string) ([]meta.MetaRevision, error) {
+	if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+		return nil, errors.Errorf("cluster %s does not exist", clusterName)
+	}
+	return meta.MetaRevisions(clusterName)
+}
+
+func metaRevisionData(clusterName, revision string) ([]byte, error) {
+	id, err := strconv.Atoi(revision)
+	if err != nil {
+		return nil, errors.Errorf("invalid revision '%s', it should be a number", revision)
+	}
+	return meta.MetaRevisionData(clusterName, id)
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+)
+
+// rollbackOnFailure handles the steps done by the failed operation t. They
+// are kept to be resumed if some of them are saved to the checkpoint, unless
+// rollback is true, otherwise they're undone and the results are reported.
+func rollbackOnFailure(ctx *task.Context, t task.Task, rollback bool) {
+	if ctx.DryRun() {
+		return
+	}
+	if !rollback && ctx.Checkpoint != nil && ctx.Checkpoint.Len() > 0 {
+		log.Warnf("The %d steps done are kept, you can fix the error above and run the command again with %s to resume it, or with %s to start over and roll back the steps done if it fails again",
+			ctx.Checkpoint.Len(), color.YellowString("--resume"), color.YellowString("--rollback"))
+		return
+	}
+
+	log.Warnf("Rolling back the steps done...")
+	// the errors are reported along with the results
+	_ = t.Rollback(ctx)
+	printRollbackReport(ctx.RollbackResults())
+
+	// the operation has to start over since the steps done are undone
+	if ctx.Checkpoint == nil {
+		return
+	}
+	if err := ctx.Checkpoint.Remove(); err != nil {
+		log.Warnf("Failed to remove the checkpoint: %s", err)
+	}
+}
+
+// printRollbackReport prints the result of rolling back each step
+func printRollbackReport(results []task.RollbackResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	var undone, notUndone, failed int
+	rows := [][]string{{"Result", "Step"}}
+	for _, r := range results {
+		var result string
+		switch r.Err {
+		case nil:
+			undone++
+			result = color.GreenString("Undone")
+		case task.ErrUnsupportedRollback:
+			notUndone++
+			result = color.YellowString("Not undone")
+		default:
+			failed++
+			result = color.RedString("Failed: %s", r.Err)
+		}
+		rows = append(rows, []string{result, r.Task})
+	}
+
+	fmt.Println()
+	cliutil.PrintTable(rows, true)
+	fmt.Println()
+	log.Infof("Rollback finished: %d steps undone, %d steps not undone, %d steps failed", undone, notUndone, failed)
+	if notUndone+failed > 0 {
+		log.Warnf("Please check the steps not undone or failed above and clean them up manually if needed")
+	}
+}
@@ -41,7 +41,7 @@ func newScaleInCmd() *cobra.Command {
 			}
 
 			clusterName := args[0]
-			if !skipConfirm {
+			if !skipConfirm && !gOpt.DryRun {
 				if err := cliutil.PromptForConfirmOrAbortError(
 					"This operation will delete the %s nodes in `%s` and all their data.\nDo you want to continue? [y/N]:",
 					strings.Join(gOpt.Nodes, ","),
@@ -59,6 +59,7 @@ func newScaleInCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Specify the nodes")
 	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
 	cmd.Flags().BoolVar(&gOpt.Force, "force", false, "Force just try stop and destroy instance before removing the instance from topo")
+	addDryRunFlag(cmd)
 
 	_ = cmd.MarkFlagRequired("node")
 
@@ -70,7 +71,13 @@ func scaleIn(clusterName string, options operator.Options) error {
 		return errors.Errorf("cannot scale-in non-exists cluster %s", clusterName)
 	}
 
-	metadata, err := meta.ClusterMetadata(clusterName)
+	unlock, err := lockCluster(clusterName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	metadata, err := clusterMetadata(clusterName)
 	if err != nil {
 		return err
 	}
@@ -130,19 +137,24 @@ func scaleIn(clusterName string, options operator.Options) error {
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout)
 
+	// the nodes removed from the topology now, the others are removed once they
+	// become tombstone
+	removedNodes := options.Nodes
 	if !options.Force {
-		b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options).
-			UpdateMeta(clusterName, metadata, operator.AsyncNodes(metadata.Topology, options.Nodes, false)).
-			UpdateTopology(clusterName, metadata, operator.AsyncNodes(metadata.Topology, options.Nodes, false))
-	} else {
-		b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options).
-			UpdateMeta(clusterName, metadata, options.Nodes).
-			UpdateTopology(clusterName, metadata, options.Nodes)
+		removedNodes = operator.AsyncNodes(metadata.Topology, options.Nodes, false)
 	}
+	b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options).
+		UpdateMeta(clusterName, metadata, removedNodes).
+		UpdateTopology(clusterName, metadata, removedNodes)
 
-	t := b.Parallel(regenConfigTasks...).Build()
+	// close the ports of the removed nodes
+	firewallTasks := buildFirewallTasks(metadata.Topology.FirewallRules(), metadata.Topology.FirewallRules(removedNodes...))
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	t := b.Parallel(regenConfigTasks...).Parallel(firewallTasks...).Build()
+
+	ctx := newContext()
+	defer ctx.Close()
+	if err := t.Execute(ctx); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -150,6 +162,11 @@ func scaleIn(clusterName string, options operator.Options) error {
 		return errors.Trace(err)
 	}
 
+	if gOpt.DryRun {
+		printDryRunPlan(ctx)
+		return nil
+	}
+
 	log.Infof("Scaled cluster `%s` in successfully", clusterName)
 
 	return nil
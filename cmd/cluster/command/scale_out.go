@@ -23,29 +23,36 @@ import (
 	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil/prepare"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
 	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/report"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
-	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 	"github.com/pingcap-incubator/tiup/pkg/set"
 	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
 	"github.com/pingcap/errors"
 	"github.com/spf13/cobra"
 )
 
+var (
+	errNSScaleOut           = errNS.NewSubNamespace("scale_out")
+	errScaleOutNotResumable = errNSScaleOut.NewType("not_resumable", errutil.ErrTraitPreCheck)
+	errScaleOutStartFailed  = errNSScaleOut.NewType("start_failed")
+)
+
 type scaleOutOptions struct {
 	user         string // username to login to the SSH server
 	identityFile string // path to the private key file
 	usePassword  bool   // use password instead of identity file for ssh connection
+	resume       bool   // skip the tasks done by the last failed scale-out
+	rollback     bool   // roll back the tasks done if it fails
 }
 
 func newScaleOutCmd() *cobra.Command {
-	opt := scaleOutOptions{
-		identityFile: filepath.Join(utils.UserHome(), ".ssh", "id_rsa"),
-	}
+	opt := scaleOutOptions{}
 	cmd := &cobra.Command{
 		Use:          "scale-out <cluster-name> <topology.yaml>",
 		Short:        "Scale out a TiDB cluster",
@@ -60,9 +67,12 @@ func newScaleOutCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&opt.user, "user", utils.CurrentUser(), "The user name to login via SSH. The user must has root (or sudo) privilege.")
-	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", opt.identityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().StringVar(&opt.user, "user", "", "The user name to login via SSH, the current user by default, or the one in the OpenSSH client config if ssh_type is openssh. The user must has root (or sudo) privilege.")
+	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", "", "The path of the SSH identity file, ~/.ssh/id_rsa by default, or the ones in the OpenSSH client config if ssh_type is openssh. If specified, public key authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.usePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+	cmd.Flags().BoolVar(&opt.resume, "resume", false, "Resume the last failed scale-out, the steps done by it are skipped.")
+	cmd.Flags().BoolVar(&opt.rollback, "rollback", false, "Roll back the steps done if the scale-out fails instead of keeping them to be resumed with --resume.")
+	addDryRunFlag(cmd)
 
 	return cmd
 }
@@ -72,19 +82,19 @@ func scaleOut(clusterName, topoFile string, opt scaleOutOptions) error {
 		return errors.Errorf("cannot scale-out non-exists cluster %s", clusterName)
 	}
 
-	metadata, err := meta.ClusterMetadata(clusterName)
+	unlock, err := lockCluster(clusterName)
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	// Inherit existing global configuration. We must assign the inherited values before unmarshalling
-	// because some default value rely on the global options and monitored options.
-	var newPart = meta.TopologySpecification{
-		GlobalOptions:    metadata.Topology.GlobalOptions,
-		MonitoredOptions: metadata.Topology.MonitoredOptions,
-		ServerConfigs:    metadata.Topology.ServerConfigs,
+	metadata, err := clusterMetadata(clusterName)
+	if err != nil {
+		return err
 	}
-	if err := utils.ParseTopologyYaml(topoFile, &newPart); err != nil {
+
+	newPart, err := parseScaleOutTopology(metadata.Topology, topoFile)
+	if err != nil {
 		return err
 	}
 
@@ -92,11 +102,20 @@ func scaleOut(clusterName, topoFile string, opt scaleOutOptions) error {
 		teleTopology = string(data)
 	}
 
+	if opt.resume {
+		if err := checkScaleOutResumable(clusterName, metadata.Topology, newPart); err != nil {
+			return err
+		}
+	}
+
 	// Abort scale out operation if the merged topology is invalid
-	mergedTopo := metadata.Topology.Merge(&newPart)
+	mergedTopo := metadata.Topology.Merge(newPart)
 	if err := mergedTopo.Validate(); err != nil {
 		return err
 	}
+	if err := confirmLabelProblems(mergedTopo, metadata.Topology); err != nil {
+		return err
+	}
 
 	if err := prepare.CheckClusterPortConflict(clusterName, mergedTopo); err != nil {
 		return err
@@ -111,25 +130,34 @@ func scaleOut(clusterName, topoFile string, opt scaleOutOptions) error {
 			patchedComponents.Insert(instance.ComponentName())
 		}
 	})
-	if !skipConfirm {
+	if !skipConfirm && !gOpt.DryRun {
 		// patchedComponents are components that have been patched and overwrited
-		if err := confirmTopology(clusterName, metadata.Version, &newPart, patchedComponents); err != nil {
+		if err := confirmTopology(clusterName, metadata.Version, newPart, patchedComponents); err != nil {
 			return err
 		}
 	}
 
+	opt.user, opt.identityFile = executor.DefaultLogin(metadata.Topology.GlobalOptions.SSHType, opt.user, opt.identityFile)
 	sshConnProps, err := cliutil.ReadIdentityFileOrPassword(opt.identityFile, opt.usePassword)
 	if err != nil {
 		return err
 	}
 
 	// Build the scale out tasks
-	t, err := buildScaleOutTask(clusterName, metadata, mergedTopo, opt, sshConnProps, &newPart, patchedComponents, gOpt.OptTimeout)
+	t, startTask, err := buildScaleOutTask(clusterName, metadata, mergedTopo, opt, sshConnProps, newPart, patchedComponents, gOpt.OptTimeout)
 	if err != nil {
 		return err
 	}
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	ctx := newContext()
+	defer ctx.Close()
+	if !gOpt.DryRun {
+		if ctx.Checkpoint, err = newCheckpoint(clusterName, "scale-out", metadata.Version, newPart, opt.resume); err != nil {
+			return err
+		}
+	}
+	if err := t.Execute(ctx); err != nil {
+		rollbackOnFailure(ctx, t, opt.rollback)
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -137,11 +165,110 @@ func scaleOut(clusterName, topoFile string, opt scaleOutOptions) error {
 		return errors.Trace(err)
 	}
 
+	// the new instances are saved to the meta and may be running once they
+	// are started, they're left to be started again or scaled in instead of
+	// being rolled back if it fails
+	if err := startTask.Execute(ctx); err != nil {
+		if !gOpt.DryRun {
+			if err := ctx.Checkpoint.Remove(); err != nil {
+				log.Warnf("Failed to remove the checkpoint: %s", err)
+			}
+		}
+		return scaleOutStartFailed(clusterName, newPart, err)
+	}
+
+	if gOpt.DryRun {
+		printDryRunPlan(ctx)
+		return nil
+	}
+
+	if err := ctx.Checkpoint.Remove(); err != nil {
+		return err
+	}
 	log.Infof("Scaled cluster `%s` out successfully", clusterName)
 
 	return nil
 }
 
+// parseScaleOutTopology parses the topology file of the instances to scale out
+// to the cluster of topo, whose settings are inherited.
+func parseScaleOutTopology(topo *meta.TopologySpecification, topoFile string) (*meta.TopologySpecification, error) {
+	// Inherit existing global configuration. We must assign the inherited values before unmarshalling
+	// because some default value rely on the global options and monitored options.
+	// The host groups are copied as the instances may be in the groups of the
+	// cluster, and they are resolved in unmarshalling.
+	newPart := &meta.TopologySpecification{
+		GlobalOptions:    topo.GlobalOptions,
+		MonitoredOptions: topo.MonitoredOptions,
+		ServerConfigs:    topo.ServerConfigs,
+		HostGroups:       topo.HostGroups.Clone(),
+	}
+	if err := meta.ParseTopologyYaml(topoFile, newPart); err != nil {
+		return nil, err
+	}
+	return newPart, nil
+}
+
+// checkScaleOutResumable checks whether the last scale-out is interrupted after
+// the new instances are saved to the meta, in which case the rest of it can't
+// be resumed since the topology can't be merged again.
+func checkScaleOutResumable(clusterName string, topo, newPart *meta.TopologySpecification) error {
+	saved := set.NewStringSet()
+	topo.IterInstance(func(inst meta.Instance) {
+		saved.Insert(inst.ID())
+	})
+
+	var ids []string
+	newPart.IterInstance(func(inst meta.Instance) {
+		if saved.Exist(inst.ID()) {
+			ids = append(ids, inst.ID())
+		}
+	})
+	if len(ids) == 0 {
+		return nil
+	}
+	return errScaleOutNotResumable.
+		New("The instances %s have been saved to the meta of cluster `%s` by the last scale-out", strings.Join(ids, ","), clusterName).
+		WithProperty(cliutil.SuggestionFromTemplate(`
+The rest of the last scale-out can't be resumed, please start the new instances with:
+
+    {{ColorCommand}}{{OsArgs0}} start {{.ClusterName}}{{ColorReset}}
+
+and then refresh the configs of the cluster with:
+
+    {{ColorCommand}}{{OsArgs0}} reload {{.ClusterName}}{{ColorReset}}
+`, map[string]string{
+			"ClusterName": clusterName,
+		}))
+}
+
+// scaleOutStartFailed tells how to start the new instances saved to the meta
+// after they're failed to be started, or to scale them in.
+func scaleOutStartFailed(clusterName string, newPart *meta.TopologySpecification, err error) error {
+	var ids []string
+	newPart.IterInstance(func(inst meta.Instance) {
+		ids = append(ids, inst.ID())
+	})
+	return errScaleOutStartFailed.
+		Wrap(err, "Failed to start the new instances of cluster `%s`, they have been saved to the meta and are not rolled back", clusterName).
+		WithProperty(cliutil.SuggestionFromTemplate(`
+Please fix the error above and start the new instances with:
+
+    {{ColorCommand}}{{OsArgs0}} start {{.ClusterName}} -N {{.Nodes}}{{ColorReset}}
+
+and then refresh the configs of the cluster with:
+
+    {{ColorCommand}}{{OsArgs0}} reload {{.ClusterName}}{{ColorReset}}
+
+or remove them from the cluster with:
+
+    {{ColorCommand}}{{OsArgs0}} scale-in {{.ClusterName}} -N {{.Nodes}}{{ColorReset}}
+`, map[string]string{
+			"ClusterName": clusterName,
+			"Nodes":       strings.Join(ids, ","),
+		}))
+}
+
 // Deprecated
 func convertStepDisplaysToTasks(t []*task.StepDisplay) []task.Task {
 	tasks := make([]task.Task, 0, len(t))
@@ -151,6 +278,8 @@ func convertStepDisplaysToTasks(t []*task.StepDisplay) []task.Task {
 	return tasks
 }
 
+// buildScaleOutTask builds the tasks to deploy the new instances and save them
+// to the meta, and the tasks to start them and refresh the cluster.
 func buildScaleOutTask(
 	clusterName string,
 	metadata *meta.ClusterMeta,
@@ -160,7 +289,7 @@ func buildScaleOutTask(
 	newPart *meta.TopologySpecification,
 	patchedComponents set.StringSet,
 	timeout int64,
-) (task.Task, error) {
+) (task.Task, task.Task, error) {
 	var (
 		envInitTasks       []task.Task // tasks which are used to initialize environment
 		downloadCompTasks  []task.Task // tasks which are used to download components
@@ -193,9 +322,10 @@ func buildScaleOutTask(
 			}
 
 			uninitializedHosts[host] = hostInfo{
-				ssh:  instance.GetSSHPort(),
-				os:   instance.OS(),
-				arch: instance.Arch(),
+				ssh:   instance.GetSSHPort(),
+				proxy: instance.GetSSHProxy(),
+				os:    instance.OS(),
+				arch:  instance.Arch(),
 			}
 
 			var dirs []string
@@ -217,6 +347,8 @@ func buildScaleOutTask(
 					sshConnProps.IdentityFile,
 					sshConnProps.IdentityFilePassphrase,
 					gOpt.SSHTimeout,
+					metadata.Topology.GlobalOptions.SSHType,
+					instance.GetSSHProxy(),
 				).
 				EnvInit(instance.GetHost(), metadata.User).
 				Mkdir(globalOptions.User, instance.GetHost(), dirs...).
@@ -226,7 +358,7 @@ func buildScaleOutTask(
 	})
 
 	if iterErr != nil {
-		return task.NewBuilder().Build(), iterErr
+		return nil, nil, iterErr
 	}
 
 	// Download missing component
@@ -243,7 +375,7 @@ func buildScaleOutTask(
 
 		// Deploy component
 		tb := task.NewBuilder().
-			UserSSH(inst.GetHost(), inst.GetSSHPort(), metadata.User, gOpt.SSHTimeout).
+			UserSSH(inst.GetHost(), inst.GetSSHPort(), metadata.User, gOpt.SSHTimeout, metadata.Topology.GlobalOptions.SSHType, inst.GetSSHProxy()).
 			Mkdir(metadata.User, inst.GetHost(),
 				deployDir, logDir,
 				filepath.Join(deployDir, "bin"),
@@ -255,6 +387,10 @@ func buildScaleOutTask(
 		} else {
 			tb.CopyComponent(inst.ComponentName(), inst.OS(), inst.Arch(), version, inst.GetHost(), deployDir)
 		}
+		if newPart.NeedTLSCert(inst.ComponentName()) {
+			tb.Mkdir(metadata.User, inst.GetHost(), filepath.Join(deployDir, meta.TLSCertKeyDir)).
+				TLSCert(clusterName, inst, deployDir)
+		}
 		t := tb.ScaleConfig(clusterName,
 			metadata.Version,
 			metadata.Topology,
@@ -308,7 +444,7 @@ func buildScaleOutTask(
 	// handle dir scheme changes
 	if hasImported {
 		if err := meta.HandleImportPathMigration(clusterName); err != nil {
-			return task.NewBuilder().Build(), err
+			return nil, nil, err
 		}
 	}
 
@@ -329,8 +465,15 @@ func buildScaleOutTask(
 		metadata.Version,
 	)
 	downloadCompTasks = append(downloadCompTasks, convertStepDisplaysToTasks(dlTasks)...)
-	deployCompTasks = append(deployCompTasks, convertStepDisplaysToTasks(dpTasks)...)
+	for _, ts := range dpTasks {
+		deployCompTasks = append(deployCompTasks, convertStepDisplaysToTasks(ts)...)
+	}
 
+	// open the ports of the new instances, and to the new hosts if the sources
+	// are restricted
+	firewallTasks := buildFirewallTasks(metadata.Topology.FirewallRules(), mergedTopo.FirewallRules())
+
+	originTopo := metadata.Topology
 	builder := task.NewBuilder().
 		SSHKeySet(
 			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
@@ -338,27 +481,40 @@ func buildScaleOutTask(
 		Parallel(downloadCompTasks...).
 		Parallel(envInitTasks...).
 		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
-		Parallel(deployCompTasks...)
+		Parallel(deployCompTasks...).
+		Parallel(firewallTasks...)
 
-	if report.Enable() {
+	if report.Enable() && !gOpt.DryRun {
 		builder.Parallel(convertStepDisplaysToTasks([]*task.StepDisplay{nodeInfoTask})...)
 	}
 
 	// TODO: find another way to make sure current cluster started
-	builder.ClusterOperate(metadata.Topology, operator.StartOperation, operator.Options{OptTimeout: timeout}).
+	builder.ClusterOperate(metadata.Topology, operator.StartOperation, operator.Options{OptTimeout: timeout, TLSConfig: gOpt.TLSConfig}).
 		ClusterSSH(newPart, metadata.User, gOpt.SSHTimeout).
-		Func("save meta", func(_ *task.Context) error {
+		FuncWithRollback("save meta", func(ctx *task.Context) error {
 			metadata.Topology = mergedTopo
+			if ctx.DryRun() {
+				ctx.Recorder.Note("", "save the meta of cluster %s", clusterName)
+				return nil
+			}
 			return meta.SaveClusterMeta(clusterName, metadata)
-		}).
-		ClusterOperate(newPart, operator.StartOperation, operator.Options{OptTimeout: timeout}).
+		}, func(ctx *task.Context) error {
+			// restore the meta without the new instances
+			metadata.Topology = originTopo
+			return meta.SaveClusterMeta(clusterName, metadata)
+		})
+
+	startTask := task.NewBuilder().
+		ClusterOperate(newPart, operator.StartOperation, operator.Options{OptTimeout: timeout, TLSConfig: gOpt.TLSConfig}).
 		Parallel(refreshConfigTasks...).
 		ClusterOperate(metadata.Topology, operator.RestartOperation, operator.Options{
 			Roles:      []string{meta.ComponentPrometheus},
 			OptTimeout: timeout,
+			TLSConfig:  gOpt.TLSConfig,
 		}).
-		UpdateTopology(clusterName, metadata, nil)
+		UpdateTopology(clusterName, metadata, nil).
+		Build()
 
-	return builder.Build(), nil
+	return builder.Build(), startTask, nil
 
 }
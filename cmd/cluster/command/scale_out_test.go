@@ -0,0 +1,65 @@
+package command
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+type scaleOutSuite struct{}
+
+var _ = check.Suite(&scaleOutSuite{})
+
+func (s *scaleOutSuite) TestParseScaleOutTopology(c *check.C) {
+	topo := &meta.TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  deploy_dir: /tidb-deploy
+host_groups:
+  ssd:
+    data_dir: "/{{ .Host.disk }}/data"
+    hosts:
+      172.16.5.140:
+        disk: nvme0
+      172.16.5.141:
+        disk: nvme1
+tikv_servers:
+  - host: 172.16.5.140
+    group: ssd
+pd_servers:
+  - host: 172.16.5.140
+`), topo)
+	c.Assert(err, check.IsNil)
+
+	topoFile := filepath.Join(c.MkDir(), "scale-out.yaml")
+	err = ioutil.WriteFile(topoFile, []byte(`
+tikv_servers:
+  - host: 172.16.5.141
+    group: ssd
+`), 0644)
+	c.Assert(err, check.IsNil)
+	newPart, err := parseScaleOutTopology(topo, topoFile)
+	c.Assert(err, check.IsNil)
+	c.Assert(newPart.TiKVServers, check.HasLen, 1)
+	c.Assert(newPart.TiKVServers[0].DeployDir, check.Equals, "/tidb-deploy/tikv-20160")
+	c.Assert(newPart.TiKVServers[0].DataDir, check.Equals, "/nvme1/data/tikv-20160")
+
+	// the groups defined in the file are not added to the cluster's
+	err = ioutil.WriteFile(topoFile, []byte(`
+host_groups:
+  hdd:
+    hosts:
+      172.16.5.142:
+tikv_servers:
+  - host: 172.16.5.142
+    group: hdd
+`), 0644)
+	c.Assert(err, check.IsNil)
+	newPart, err = parseScaleOutTopology(topo, topoFile)
+	c.Assert(err, check.IsNil)
+	c.Assert(newPart.HostGroups, check.HasLen, 2)
+	c.Assert(topo.HostGroups, check.HasLen, 1)
+}
@@ -55,8 +55,14 @@ func newStartCmd() *cobra.Command {
 
 func startCluster(clusterName string, options operator.Options) error {
 	logger.EnableAuditLog()
+	unlock, err := lockCluster(clusterName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	log.Infof("Starting cluster %s...", clusterName)
-	metadata, err := meta.ClusterMetadata(clusterName)
+	metadata, err := clusterMetadata(clusterName)
 	if err != nil {
 		return err
 	}
@@ -70,7 +76,9 @@ func startCluster(clusterName string, options operator.Options) error {
 		UpdateTopology(clusterName, metadata, nil).
 		Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	ctx := newContext()
+	defer ctx.Close()
+	if err := t.Execute(ctx); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
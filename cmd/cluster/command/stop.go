@@ -44,7 +44,13 @@ func newStopCmd() *cobra.Command {
 			}
 
 			logger.EnableAuditLog()
-			metadata, err := meta.ClusterMetadata(clusterName)
+			unlock, err := lockCluster(clusterName)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
+			metadata, err := clusterMetadata(clusterName)
 			if err != nil {
 				return err
 			}
@@ -57,7 +63,9 @@ func newStopCmd() *cobra.Command {
 				ClusterOperate(metadata.Topology, operator.StopOperation, gOpt).
 				Build()
 
-			if err := t.Execute(task.NewContext()); err != nil {
+			ctx := newContext()
+			defer ctx.Close()
+			if err := t.Execute(ctx); err != nil {
 				if errorx.Cast(err) != nil {
 					// FIXME: Map possible task errors and give suggestions.
 					return err
@@ -46,6 +46,7 @@ func newUpgradeCmd() *cobra.Command {
 	}
 	cmd.Flags().BoolVar(&gOpt.Force, "force", false, "Force upgrade won't transfer leader")
 	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
+	addDryRunFlag(cmd)
 
 	return cmd
 }
@@ -71,16 +72,23 @@ func upgrade(clusterName, clusterVersion string, opt operator.Options) error {
 		return errors.Errorf("cannot upgrade non-exists cluster %s", clusterName)
 	}
 
-	metadata, err := meta.ClusterMetadata(clusterName)
+	unlock, err := lockCluster(clusterName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	metadata, err := clusterMetadata(clusterName)
 	if err != nil {
 		return err
 	}
 
 	var (
-		downloadCompTasks []task.Task // tasks which are used to download components
-		copyCompTasks     []task.Task // tasks which are used to copy components to remote host
+		// the components are copied to each instance once downloaded, without
+		// waiting for the other downloads
+		copyCompTasks = task.NewGraph()
 
-		uniqueComps = map[string]struct{}{}
+		uniqueComps = map[string]task.Task{} // comp-version-os-arch -> task which is used to download it
 	)
 
 	if err := versionCompare(metadata.Version, clusterVersion); err != nil {
@@ -102,11 +110,9 @@ func upgrade(clusterName, clusterVersion string, opt operator.Options) error {
 			// Download component from repository
 			key := fmt.Sprintf("%s-%s-%s-%s", compInfo.component, compInfo.version, inst.OS(), inst.Arch())
 			if _, found := uniqueComps[key]; !found {
-				uniqueComps[key] = struct{}{}
-				t := task.NewBuilder().
+				uniqueComps[key] = copyCompTasks.Add(task.NewBuilder().
 					Download(inst.ComponentName(), inst.OS(), inst.Arch(), version).
-					Build()
-				downloadCompTasks = append(downloadCompTasks, t)
+					Build())
 			}
 
 			deployDir := clusterutil.Abs(metadata.User, inst.DeployDir())
@@ -142,7 +148,7 @@ func upgrade(clusterName, clusterVersion string, opt operator.Options) error {
 					Cache:  meta.ClusterPath(clusterName, meta.TempConfigPath),
 				},
 			)
-			copyCompTasks = append(copyCompTasks, tb.Build())
+			copyCompTasks.Add(tb.Build(), uniqueComps[key])
 		}
 	}
 
@@ -158,12 +164,13 @@ func upgrade(clusterName, clusterVersion string, opt operator.Options) error {
 			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(metadata.Topology, metadata.User, gOpt.SSHTimeout).
-		Parallel(downloadCompTasks...).
-		Parallel(copyCompTasks...).
+		Graph(copyCompTasks).
 		ClusterOperate(metadata.Topology, operator.UpgradeOperation, opt).
 		Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	ctx := newContext()
+	defer ctx.Close()
+	if err := t.Execute(ctx); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -171,6 +178,11 @@ func upgrade(clusterName, clusterVersion string, opt operator.Options) error {
 		return errors.Trace(err)
 	}
 
+	if gOpt.DryRun {
+		printDryRunPlan(ctx)
+		return nil
+	}
+
 	metadata.Version = clusterVersion
 	if err := meta.SaveClusterMeta(clusterName, metadata); err != nil {
 		return errors.Trace(err)
@@ -0,0 +1,172 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	errNSValidate     = errNS.NewSubNamespace("validate")
+	errValidateFailed = errNSValidate.NewType("failed", errutil.ErrTraitPreCheck)
+)
+
+func newValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate <topology.yaml>",
+		Short: "Validate a topology file",
+		Long: `Validate a topology file without deploying it, all the problems found are
+reported at once with their line numbers, e.g: unknown fields, port and directory
+conflicts. The files included by the topology file are validated along with it.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			return validateTopology(args[0])
+		},
+	}
+	return cmd
+}
+
+func validateTopology(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return utils.ErrTopologyReadFailed.Wrap(err, "Failed to read topology file %s", file)
+	}
+
+	problems := topologyProblems(file, data)
+	if len(problems) == 0 {
+		fmt.Printf("%s is valid\n", color.CyanString(file))
+		return nil
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	return errValidateFailed.
+		New("Found %d problem(s) in topology file %s", len(problems), file).
+		WithProperty(cliutil.SuggestionFromString("Please fix the problems above and validate it again."))
+}
+
+// topologyProblems returns the problems of the topology and the files included
+// by it in the format of "file:line: problem". Validation can't be done if the
+// topology can't be parsed, so the problems of parsing are returned only in
+// that case.
+func topologyProblems(file string, data []byte) []string {
+	var topo meta.TopologySpecification
+	files, err := meta.NewTopologyFiles(file, data)
+	if err == nil {
+		err = files.Unmarshal(&topo)
+	}
+
+	var problems []string
+	locate := func(errs []*meta.TopologyError) {
+		for _, te := range errs {
+			f, line := files.Locate(te.Path)
+			problems = append(problems, fmt.Sprintf("%s:%d: %s", f, line, te))
+		}
+	}
+	switch e := err.(type) {
+	case nil:
+		locate(topo.ValidateLabels())
+	case *meta.TopologyError:
+		// the topology is parsed before the first problem is found
+		locate(topo.ValidateAll())
+		locate(topo.ValidateLabels())
+	case *meta.TopologyFileError:
+		problems = parseProblems(e)
+	default:
+		problems = append(problems, fmt.Sprintf("%s: %s", file, err))
+	}
+	return problems
+}
+
+// warnLabelProblems warns about the problems of the labels of the stores in
+// topo, it's used by the commands only checking the topology.
+func warnLabelProblems(topo *meta.TopologySpecification) {
+	for _, e := range topo.ValidateLabels() {
+		log.Warnf("%s", e)
+	}
+}
+
+// confirmLabelProblems checks the labels of the stores in topo to be deployed,
+// the operation is aborted on the problems found unless it's confirmed to go
+// on, see newLabelProblems.
+func confirmLabelProblems(topo, existing *meta.TopologySpecification) error {
+	problems := newLabelProblems(topo, existing)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	for _, e := range problems {
+		log.Errorf("%s: %s", e.Path, e)
+	}
+	if skipConfirm || gOpt.DryRun {
+		return nil
+	}
+	return cliutil.PromptForConfirmOrAbortError(
+		"The replicas may not be isolated by the labels as expected because of the problems above.\nDo you want to continue? [y/N]: ")
+}
+
+// newLabelProblems returns the problems of the labels of the stores in topo,
+// except the ones of the existing topology, which the clusters deployed before
+// may have, they are only warned about.
+func newLabelProblems(topo, existing *meta.TopologySpecification) []*meta.TopologyError {
+	known := set.NewStringSet()
+	if existing != nil {
+		for _, e := range existing.ValidateLabels() {
+			known.Insert(e.Path)
+		}
+	}
+
+	var problems []*meta.TopologyError
+	for _, e := range topo.ValidateLabels() {
+		if known.Exist(e.Path) {
+			log.Warnf("%s", e)
+			continue
+		}
+		problems = append(problems, e)
+	}
+	return problems
+}
+
+func parseProblems(e *meta.TopologyFileError) []string {
+	var problems []string
+	switch err := e.Err.(type) {
+	case *yaml.TypeError:
+		// e.g: line 5: field foo not found in type meta.TiDBSpec
+		for _, msg := range err.Errors {
+			problems = append(problems, fmt.Sprintf("%s:%s", e.File, strings.TrimPrefix(msg, "line ")))
+		}
+	default:
+		// e.g: yaml: line 3: mapping values are not allowed in this context
+		if msg := err.Error(); strings.HasPrefix(msg, "yaml: line ") {
+			problems = append(problems, fmt.Sprintf("%s:%s", e.File, strings.TrimPrefix(msg, "yaml: line ")))
+		} else {
+			problems = append(problems, fmt.Sprintf("%s: %s", e.Location(), msg))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,101 @@
+package command
+
+import (
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+type validateSuite struct{}
+
+var _ = check.Suite(&validateSuite{})
+
+func (s *validateSuite) TestTopologyProblems(c *check.C) {
+	problems := topologyProblems("topo.yaml", []byte(`
+tidb_servers:
+  - host: 172.16.5.138
+    port: 1234
+  - host: 172.16.5.138
+    port: 1234
+    deploy_dir: /tidb
+pd_servers:
+  - host: 172.16.5.138
+    deploy_dir: /tidb
+    client_port: 1234
+`))
+	c.Assert(problems, check.DeepEquals, []string{
+		"topo.yaml:6: port '1234' conflicts between 'tidb_servers:172.16.5.138.port' and 'tidb_servers:172.16.5.138.port'",
+		// the default status_port is not in the file, the line of the instance is reported
+		"topo.yaml:5: port '10080' conflicts between 'tidb_servers:172.16.5.138.status_port' and 'tidb_servers:172.16.5.138.status_port'",
+		"topo.yaml:11: port '1234' conflicts between 'tidb_servers:172.16.5.138.port' and 'pd_servers:172.16.5.138.client_port'",
+		"topo.yaml:10: directory '/tidb' conflicts between 'tidb_servers:172.16.5.138.deploy_dir' and 'pd_servers:172.16.5.138.deploy_dir'",
+	})
+
+	problems = topologyProblems("topo.yaml", []byte(`
+tidb_servers:
+  - host: 172.16.5.138
+    prot: 4000
+pd_servers:
+  - host: 172.16.5.138
+    client_prot: 2379
+`))
+	c.Assert(problems, check.HasLen, 2)
+	c.Assert(problems[0], check.Matches, "topo.yaml:4: field prot not found .*")
+
+	problems = topologyProblems("topo.yaml", []byte("tidb_servers:\n  - host: a\n   port: 1\n"))
+	c.Assert(problems, check.HasLen, 1)
+	c.Assert(problems[0], check.Matches, "topo.yaml:2: did not find expected .*")
+	c.Assert(topologyProblems("topo.yaml", []byte("pd_servers:\n  - host: 172.16.5.138\n")), check.HasLen, 0)
+
+	// the problems of labels are reported along with the others
+	problems = topologyProblems("topo.yaml", []byte(`
+server_configs:
+  pd:
+    replication.location-labels: ["host"]
+    replication.max-replicas: 1
+pd_servers:
+  - host: 172.16.5.138
+tikv_servers:
+  - host: 172.16.5.139
+`))
+	c.Assert(problems, check.DeepEquals, []string{
+		"topo.yaml:9: label 'host' in replication.location-labels of PD is missing in server.labels of 'tikv_servers:172.16.5.139:20160'",
+	})
+}
+
+func (s *validateSuite) TestNewLabelProblems(c *check.C) {
+	parse := func(content string) *meta.TopologySpecification {
+		topo := &meta.TopologySpecification{}
+		c.Assert(yaml.Unmarshal([]byte(content), topo), check.IsNil)
+		return topo
+	}
+	paths := func(errs []*meta.TopologyError) []string {
+		var paths []string
+		for _, e := range errs {
+			paths = append(paths, e.Path)
+		}
+		return paths
+	}
+
+	existing := parse(`
+server_configs:
+  pd:
+    replication.location-labels: ["host"]
+    replication.max-replicas: 1
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.141
+`)
+	c.Assert(paths(newLabelProblems(existing, nil)), check.DeepEquals, []string{"tikv_servers.0.config"})
+
+	// only the problems of the new stores are reported on scale-out
+	merged := existing.Merge(parse(`
+tikv_servers:
+  - host: 172.16.5.142
+    config:
+      server.labels: { host: h2 }
+  - host: 172.16.5.143
+`))
+	c.Assert(paths(newLabelProblems(merged, existing)), check.DeepEquals, []string{"tikv_servers.2.config"})
+}
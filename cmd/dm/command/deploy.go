@@ -16,7 +16,6 @@ package command
 import (
 	"fmt"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
 
@@ -26,6 +25,7 @@ import (
 	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil/prepare"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
@@ -49,9 +49,7 @@ type deployOptions struct {
 }
 
 func newDeploy() *cobra.Command {
-	opt := deployOptions{
-		identityFile: path.Join(utils.UserHome(), ".ssh", "id_rsa"),
-	}
+	opt := deployOptions{}
 	cmd := &cobra.Command{
 		Use:          "deploy <cluster-name> <version> <topology.yaml>",
 		Short:        "Deploy a DM cluster for production",
@@ -71,8 +69,8 @@ func newDeploy() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&opt.user, "user", utils.CurrentUser(), "The user name to login via SSH. The user must has root (or sudo) privilege.")
-	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", opt.identityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().StringVar(&opt.user, "user", "", "The user name to login via SSH, the current user by default, or the one in the OpenSSH client config if ssh_type is openssh. The user must has root (or sudo) privilege.")
+	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", "", "The path of the SSH identity file, ~/.ssh/id_rsa by default, or the ones in the OpenSSH client config if ssh_type is openssh. If specified, public key authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.usePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
 
 	return cmd
@@ -119,6 +117,14 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 	if err := utils.ValidateClusterNameOrError(clusterName); err != nil {
 		return err
 	}
+	// the lock is taken before checking the name, so that the cluster is not
+	// deployed twice at the same time
+	unlock, err := lockCluster(clusterName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if tiuputils.IsExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
 		// FIXME: When change to use args, the suggestion text need to be updated.
 		return errDeployNameDuplicate.
@@ -127,7 +133,7 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 	}
 
 	var topo meta.DMTopologySpecification
-	if err := utils.ParseTopologyYaml(topoFile, &topo); err != nil {
+	if err := meta.ParseTopologyYaml(topoFile, &topo); err != nil {
 		return err
 	}
 
@@ -144,6 +150,7 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 		}
 	}
 
+	opt.user, opt.identityFile = executor.DefaultLogin(topo.GlobalOptions.SSHType, opt.user, opt.identityFile)
 	sshConnProps, err := cliutil.ReadIdentityFileOrPassword(opt.identityFile, opt.usePassword)
 	if err != nil {
 		return err
@@ -187,6 +194,8 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 					sshConnProps.IdentityFile,
 					sshConnProps.IdentityFilePassphrase,
 					gOpt.SSHTimeout,
+					globalOptions.SSHType,
+					inst.GetSSHProxy(),
 				).
 				EnvInit(inst.GetHost(), globalOptions.User).
 				Mkdir(globalOptions.User, inst.GetHost(), dirs...).
@@ -208,7 +217,7 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 		logDir := clusterutil.Abs(globalOptions.User, inst.LogDir())
 		// Deploy component
 		t := task.NewBuilder().
-			UserSSH(inst.GetHost(), inst.GetSSHPort(), globalOptions.User, gOpt.SSHTimeout).
+			UserSSH(inst.GetHost(), inst.GetSSHPort(), globalOptions.User, gOpt.SSHTimeout, globalOptions.SSHType, inst.GetSSHProxy()).
 			Mkdir(globalOptions.User, inst.GetHost(),
 				deployDir, dataDir, logDir,
 				filepath.Join(deployDir, "bin"),
@@ -239,7 +248,9 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 		ParallelStep("+ Copy files", deployCompTasks...).
 		Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	ctx := newContext()
+	defer ctx.Close()
+	if err := t.Execute(ctx); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -44,6 +44,12 @@ func newDestroyCmd() *cobra.Command {
 			}
 
 			logger.EnableAuditLog()
+			unlock, err := lockCluster(clusterName)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			metadata, err := meta.DMMetadata(clusterName)
 			if err != nil {
 				return err
@@ -68,7 +74,9 @@ func newDestroyCmd() *cobra.Command {
 				ClusterOperate(metadata.Topology, operator.DestroyOperation, operator.Options{}).
 				Build()
 
-			if err := t.Execute(task.NewContext()); err != nil {
+			ctx := newContext()
+			defer ctx.Close()
+			if err := t.Execute(ctx); err != nil {
 				if errorx.Cast(err) != nil {
 					// FIXME: Map possible task errors and give suggestions.
 					return err
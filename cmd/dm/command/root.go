@@ -15,8 +15,12 @@ package command
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
@@ -27,6 +31,7 @@ import (
 	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
 	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/version"
 	"github.com/pingcap-incubator/tiup/pkg/localdata"
 	tiupmeta "github.com/pingcap-incubator/tiup/pkg/meta"
@@ -41,6 +46,28 @@ var (
 	errNS       = errorx.NewNamespace("cmd")
 	gOpt        operator.Options
 	skipConfirm bool
+
+	// the password to run sudo on remote hosts
+	sudoPassword     string
+	sudoPasswordFile string
+	askSudoPassword  bool
+
+	// the password of the deploy user to run sudo on remote hosts
+	deploySudoPassword     string
+	deploySudoPasswordFile string
+	askDeploySudoPassword  bool
+
+	// the events of tasks are written to the file as JSON lines if it's set
+	eventsFile   string
+	eventsOutput io.WriteCloser
+	eventWriter  *task.EventWriter
+
+	// the time taken by the tasks, it's printed if showTiming is true
+	timing     = task.NewTiming()
+	showTiming bool
+
+	// remove the operation lock of the cluster held by another operation
+	forceUnlock bool
 )
 
 func init() {
@@ -72,6 +99,22 @@ func init() {
 			}
 
 			meta.SetTiupEnv(env)
+
+			sudoPassword, err = cliutil.ReadSudoPassword(sudoPasswordFile, askSudoPassword)
+			if err != nil {
+				return err
+			}
+			deploySudoPassword, err = cliutil.ReadDeploySudoPassword(deploySudoPasswordFile, askDeploySudoPassword)
+			if err != nil {
+				return err
+			}
+
+			if eventsFile != "" {
+				if eventsOutput, err = cliutil.OpenEventsFile(eventsFile); err != nil {
+					return err
+				}
+				eventWriter = task.NewEventWriter(eventsOutput)
+			}
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -86,16 +129,47 @@ func init() {
 
 	rootCmd.PersistentFlags().Int64Var(&gOpt.SSHTimeout, "ssh-timeout", 5, "Timeout in seconds to connect host via SSH, ignored for operations that don't need an SSH connection.")
 	rootCmd.PersistentFlags().Int64Var(&gOpt.OptTimeout, "wait-timeout", 60, "Timeout in seconds to wait for an operation to complete, ignored for operations that don't fit.")
+	rootCmd.PersistentFlags().IntVarP(&gOpt.Concurrency, "concurrency", "c", 0, "Max number of steps executed in parallel, e.g: copying files to different hosts, 0 means unlimited.")
+	rootCmd.PersistentFlags().IntVar(&gOpt.HostConcurrency, "host-concurrency", 8, "Max number of concurrent SSH sessions to each host, 0 means unlimited.")
 	rootCmd.PersistentFlags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip all confirmations and assumes 'yes'")
+	rootCmd.PersistentFlags().BoolVar(&askSudoPassword, "sudo-password", false, "Prompt for the password to run sudo on remote hosts, it's only needed if the SSH users are not allowed to sudo without password.")
+	rootCmd.PersistentFlags().StringVar(&sudoPasswordFile, "sudo-password-file", "", "Read the password to run sudo on remote hosts from the file, it can also be set in the environment variable "+cliutil.EnvNameSudoPassword+".")
+	rootCmd.PersistentFlags().BoolVar(&askDeploySudoPassword, "deploy-sudo-password", false, "Prompt for the password of the deploy user to run sudo on remote hosts, it's needed if the sudo password is set, as the deploy user is not allowed to sudo without password then.")
+	rootCmd.PersistentFlags().StringVar(&deploySudoPasswordFile, "deploy-sudo-password-file", "", "Read the password of the deploy user to run sudo on remote hosts from the file, it can also be set in the environment variable "+cliutil.EnvNameDeploySudoPassword+".")
+	rootCmd.PersistentFlags().StringVar(&eventsFile, "events-file", "", "Write the events of the steps executed, e.g: the begin, progress and result of each step, to the file as JSON lines, '-' for stdout, in which case the other output is written to stderr.")
+	rootCmd.PersistentFlags().BoolVar(&showTiming, "timing", false, "Print how long the operation takes by step, host and kind of tasks when it's done.")
+	rootCmd.PersistentFlags().BoolVar(&forceUnlock, "force-unlock", false, "Remove the lock of the cluster held by another operation, only use it if the operation is known to be gone, e.g: it was run on another machine which is down.")
 
 	rootCmd.AddCommand(
 		newDeploy(),
 		newStartCmd(),
 		newStopCmd(),
 		newDestroyCmd(),
+		newTrustHostCmd(),
 	)
 }
 
+// lockCluster takes the operation lock of the cluster for the commands changing
+// it, the lock is released by calling the returned function.
+func lockCluster(clusterName string) (func(), error) {
+	return meta.LockCluster(clusterName, forceUnlock)
+}
+
+// newContext creates the context to execute tasks with the global options
+func newContext() *task.Context {
+	ctx := task.NewContext()
+	ctx.SudoPassword = sudoPassword
+	ctx.DeploySudoPassword = deploySudoPassword
+	ctx.Concurrency = gOpt.Concurrency
+	ctx.SetMaxHostSessions(gOpt.HostConcurrency)
+	ctx.CancelOnInterrupt()
+	if eventWriter != nil {
+		ctx.SetEventWriter(eventWriter)
+	}
+	ctx.SetTiming(timing)
+	return ctx
+}
+
 func printErrorMessageForNormalError(err error) {
 	_, _ = colorutil.ColorErrorMsg.Fprintf(os.Stderr, "\nError: %s\n", err.Error())
 }
@@ -151,6 +225,81 @@ func extractSuggestionFromErrorX(err *errorx.Error) string {
 	return ""
 }
 
+// printFailedTasks prints the errors of the tasks failed in parallel in a table
+// sorted by host, followed by the suggestions of them.
+func printFailedTasks(failed []*task.TaskError) {
+	_, _ = colorutil.ColorErrorMsg.Fprintf(os.Stderr, "\nError: %d tasks failed\n\n", len(failed))
+
+	failed = append([]*task.TaskError(nil), failed...)
+	sort.SliceStable(failed, func(i, j int) bool {
+		return failed[i].Host < failed[j].Host
+	})
+
+	rows := [][]string{{"Host", "Task", "Error"}}
+	var suggestions []string
+	seen := make(map[string]struct{})
+	for _, e := range failed {
+		host := e.Host
+		if host == "" {
+			host = "-"
+		}
+		msg := e.Err.Error()
+		if errx := errorx.Cast(e.Err); errx != nil {
+			if errx.Message() != "" {
+				msg = errx.Message()
+			}
+			if s := extractSuggestionFromErrorX(errx); s != "" {
+				if _, ok := seen[s]; !ok {
+					seen[s] = struct{}{}
+					suggestions = append(suggestions, s)
+				}
+			}
+		}
+		rows = append(rows, []string{host, e.Name(), strings.SplitN(msg, "\n", 2)[0]})
+	}
+	cliutil.PrintTable(rows, true)
+
+	for _, s := range suggestions {
+		_, _ = fmt.Fprintf(os.Stderr, "\n%s\n", s)
+	}
+}
+
+// printTimingReport logs the time taken by the steps, hosts and kinds of tasks,
+// so that it's kept in the audit log, and prints it if --timing is set.
+func printTimingReport() {
+	sections := []struct {
+		title     string
+		summaries []task.TimingSummary
+	}{
+		{"Step", timing.Steps()},
+		{"Host", timing.Hosts()},
+		{"Kind", timing.Kinds()},
+	}
+	for _, sec := range sections {
+		if len(sec.summaries) == 0 {
+			continue
+		}
+		rows := [][]string{{sec.title, "Count", "Total", "Max"}}
+		for _, s := range sec.summaries {
+			zap.L().Info("Timing",
+				zap.String(strings.ToLower(sec.title), s.Name),
+				zap.Int("count", s.Count),
+				zap.Duration("total", s.Total),
+				zap.Duration("max", s.Max))
+			rows = append(rows, []string{
+				s.Name,
+				strconv.Itoa(s.Count),
+				s.Total.Round(time.Millisecond).String(),
+				s.Max.Round(time.Millisecond).String(),
+			})
+		}
+		if showTiming {
+			fmt.Println()
+			cliutil.PrintTable(rows, true)
+		}
+	}
+}
+
 // Execute executes the root command
 func Execute() {
 	zap.L().Info("Execute command", zap.String("command", cliutil.OsArgs()))
@@ -165,6 +314,10 @@ func Execute() {
 
 	code := 0
 	err := rootCmd.Execute()
+	if eventsOutput != nil {
+		_ = eventsOutput.Close()
+	}
+	printTimingReport()
 	if err != nil {
 		code = 1
 	}
@@ -172,7 +325,15 @@ func Execute() {
 	zap.L().Info("Execute command finished", zap.Int("code", code), zap.Error(err))
 
 	if err != nil {
-		if errx := errorx.Cast(err); errx != nil {
+		failed := task.TaskErrors(err)
+		if len(failed) == 1 {
+			// the error of the only failed task is shown as usual
+			err = failed[0].Err
+		}
+
+		if len(failed) > 1 {
+			printFailedTasks(failed)
+		} else if errx := errorx.Cast(err); errx != nil {
 			printErrorMessageForErrorX(errx)
 		} else {
 			printErrorMessageForNormalError(err)
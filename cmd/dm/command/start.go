@@ -39,6 +39,13 @@ func newStartCmd() *cobra.Command {
 				return errors.Errorf("cannot start non-exists cluster %s", clusterName)
 			}
 
+			logger.EnableAuditLog()
+			unlock, err := lockCluster(clusterName)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			return startCluster(clusterName, gOpt)
 		},
 	}
@@ -65,7 +72,9 @@ func startCluster(clusterName string, options operator.Options) error {
 		ClusterOperate(metadata.Topology, operator.StartOperation, options).
 		Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	ctx := newContext()
+	defer ctx.Close()
+	if err := t.Execute(ctx); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
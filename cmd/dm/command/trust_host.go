@@ -0,0 +1,125 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newTrustHostCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trust-host <cluster-name> <host>...",
+		Short: "Accept the new SSH host keys of hosts",
+		Long: `Accept the new SSH host keys of hosts in the DM cluster, e.g: after the hosts are
+rebuilt. The saved keys of the hosts are replaced with the ones they present now.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot trust hosts of non-exists cluster %s", clusterName)
+			}
+
+			logger.EnableAuditLog()
+			unlock, err := lockCluster(clusterName)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
+			metadata, err := meta.DMMetadata(clusterName)
+			if err != nil {
+				return err
+			}
+
+			return trustHosts(clusterName, metadata.Topology, metadata.User, args[1:])
+		},
+	}
+
+	return cmd
+}
+
+// trustHosts replaces the saved SSH host keys of the hosts in the cluster
+func trustHosts(clusterName string, spec meta.Specification, deployUser string, hosts []string) error {
+	// the first instance of each host, to get the SSH port and jump host
+	instances := make(map[string]meta.Instance)
+	for _, com := range spec.ComponentsByStartOrder() {
+		for _, inst := range com.Instances() {
+			if _, found := instances[inst.GetHost()]; !found {
+				instances[inst.GetHost()] = inst
+			}
+		}
+	}
+
+	var tasks []*task.StepDisplay
+	for _, host := range hosts {
+		inst, found := instances[host]
+		if !found {
+			return errors.Errorf("host %s not found in cluster %s", host, clusterName)
+		}
+		tasks = append(tasks, task.NewBuilder().
+			TrustHostKey(host, inst.GetSSHPort(), deployUser, gOpt.SSHTimeout, spec.GetGlobalOptions().SSHType, inst.GetSSHProxy()).
+			BuildAsStep(fmt.Sprintf("  - Scan %s:%d", host, inst.GetSSHPort())))
+	}
+
+	if !skipConfirm {
+		if err := cliutil.PromptForConfirmOrAbortError(
+			"The saved SSH host keys of %s will be replaced with the ones they present now.\nPlease make sure the hosts are rebuilt legitimately.\nDo you want to continue? [y/N]:",
+			color.HiYellowString(strings.Join(hosts, ", "))); err != nil {
+			return err
+		}
+	}
+
+	t := task.NewBuilder().
+		SSHKeySet(
+			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+		ParallelStep("+ Accept SSH host keys", tasks...).
+		Build()
+
+	ctx := newContext()
+	defer ctx.Close()
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return errors.Trace(err)
+	}
+
+	for _, host := range hosts {
+		stdout, _, ok := ctx.GetOutputs(host)
+		if !ok {
+			log.Infof("Host %s is not connected via SSH, skipped", color.CyanString(host))
+			continue
+		}
+		log.Infof("Accepted the host key of %s: %s", color.CyanString(host), stdout)
+	}
+
+	return nil
+}
@@ -42,7 +42,7 @@ func ImportConfig(name string, clsMeta *meta.ClusterMeta, sshTimeout int64) erro
 					SSHKeySet(
 						meta.ClusterPath(name, "ssh", "id_rsa"),
 						meta.ClusterPath(name, "ssh", "id_rsa.pub")).
-					UserSSH(inst.GetHost(), inst.GetSSHPort(), clsMeta.User, sshTimeout).
+					UserSSH(inst.GetHost(), inst.GetSSHPort(), clsMeta.User, sshTimeout, clsMeta.Topology.GlobalOptions.SSHType, inst.GetSSHProxy()).
 					CopyFile(filepath.Join(inst.DeployDir(), "conf", inst.ComponentName()+".toml"),
 						meta.ClusterPath(name,
 							meta.AnsibleImportedConfigPath,
@@ -59,7 +59,7 @@ func ImportConfig(name string, clsMeta *meta.ClusterMeta, sshTimeout int64) erro
 					SSHKeySet(
 						meta.ClusterPath(name, "ssh", "id_rsa"),
 						meta.ClusterPath(name, "ssh", "id_rsa.pub")).
-					UserSSH(inst.GetHost(), inst.GetSSHPort(), clsMeta.User, sshTimeout).
+					UserSSH(inst.GetHost(), inst.GetSSHPort(), clsMeta.User, sshTimeout, clsMeta.Topology.GlobalOptions.SSHType, inst.GetSSHProxy()).
 					CopyFile(filepath.Join(inst.DeployDir(), "conf", inst.ComponentName()+".toml"),
 						meta.ClusterPath(name,
 							meta.AnsibleImportedConfigPath,
@@ -89,7 +89,9 @@ func ImportConfig(name string, clsMeta *meta.ClusterMeta, sshTimeout int64) erro
 		Parallel(copyFileTasks...).
 		Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	ctx := task.NewContext()
+	defer ctx.Close()
+	if err := t.Execute(ctx); err != nil {
 		return errors.Trace(err)
 	}
 	log.Infof("Finished copying configs.")
@@ -39,6 +39,7 @@ func parseDirs(user string, ins meta.InstanceSpec, sshTimeout int64) (meta.Insta
 		KeyFile: SSHKeyPath(), // ansible generated keyfile
 		Timeout: time.Second * time.Duration(sshTimeout),
 	}, false) // not using global sudo
+	defer e.Pool.Close()
 	log.Debugf("Detecting deploy paths on %s...", hostName)
 
 	stdout, err := readStartScript(e, ins.Role(), hostName, ins.GetMainPort())
@@ -0,0 +1,193 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certutil
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pingcap/errors"
+)
+
+const (
+	keyBits = 2048
+	// the certificates are rotated by the users, they are valid long enough
+	// so that the cluster is not broken by an expired one unexpectedly
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 10 * 365 * 24 * time.Hour
+)
+
+// CertificateAuthority signs the certificates of the instances of a cluster
+type CertificateAuthority struct {
+	Cert    *x509.Certificate
+	CertPEM []byte
+	key     *rsa.PrivateKey
+}
+
+// NewCA creates a self-signed certificate authority with the common name
+func NewCA(commonName string) (*CertificateAuthority, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &CertificateAuthority{
+		Cert:    cert,
+		CertPEM: encodeCert(der),
+		key:     key,
+	}, nil
+}
+
+// LoadCA loads the certificate authority saved by Save, the certificates of
+// the trusted ones following its own are ignored.
+func LoadCA(certFile, keyFile string) (*CertificateAuthority, error) {
+	cert, err := LoadCertificate(certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil || block.Type != "RSA PRIVATE KEY" {
+		return nil, errors.Errorf("no private key found in %s", keyFile)
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Annotatef(err, "parse private key %s", keyFile)
+	}
+	return &CertificateAuthority{
+		Cert:    cert,
+		CertPEM: encodeCert(cert.Raw),
+		key:     key,
+	}, nil
+}
+
+// LoadCertificate loads the first certificate in the PEM file
+func LoadCertificate(certFile string) (*x509.Certificate, error) {
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.Errorf("no certificate found in %s", certFile)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Annotatef(err, "parse certificate %s", certFile)
+	}
+	return cert, nil
+}
+
+// Save writes the certificate and the private key of the certificate authority
+// to the files, the private key is only readable by the current user. The
+// certificates of the trusted CAs are appended to the certificate file, so that
+// the certificates signed by them are still trusted by the users of the file.
+func (ca *CertificateAuthority) Save(certFile, keyFile string, trusted ...*CertificateAuthority) error {
+	if err := os.MkdirAll(filepath.Dir(certFile), 0755); err != nil {
+		return errors.Trace(err)
+	}
+	certPEM := append([]byte{}, ca.CertPEM...)
+	for _, t := range trusted {
+		certPEM = append(certPEM, t.CertPEM...)
+	}
+	if err := ioutil.WriteFile(certFile, certPEM, 0644); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ioutil.WriteFile(keyFile, encodeKey(ca.key), 0600))
+}
+
+// Sign issues a certificate for both server and client authentication, the
+// hosts are the IP addresses or DNS names it's valid for. The certificate and
+// the private key are returned in PEM.
+func (ca *CertificateAuthority) Sign(commonName string, hosts []string) (certPEM, keyPEM []byte, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return encodeCert(der), encodeKey(key), nil
+}
+
+func serialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	return serial, errors.Trace(err)
+}
+
+func encodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func encodeKey(key *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/pingcap/check"
+)
+
+type caSuite struct{}
+
+var _ = check.Suite(&caSuite{})
+
+func TestCertUtil(t *testing.T) {
+	check.TestingT(t)
+}
+
+func (s *caSuite) TestSign(c *check.C) {
+	ca, err := NewCA("test-cluster")
+	c.Assert(err, check.IsNil)
+
+	dir := c.MkDir()
+	certFile, keyFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.pem")
+	c.Assert(ca.Save(certFile, keyFile), check.IsNil)
+	ca, err = LoadCA(certFile, keyFile)
+	c.Assert(err, check.IsNil)
+	c.Assert(ca.Cert.Subject.CommonName, check.Equals, "test-cluster")
+
+	certPEM, keyPEM, err := ca.Sign("tikv", []string{"172.16.5.140", "localhost"})
+	c.Assert(err, check.IsNil)
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	c.Assert(err, check.IsNil)
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	c.Assert(err, check.IsNil)
+
+	roots := x509.NewCertPool()
+	c.Assert(roots.AppendCertsFromPEM(ca.CertPEM), check.IsTrue)
+	for _, host := range []string{"172.16.5.140", "localhost"} {
+		_, err = cert.Verify(x509.VerifyOptions{
+			DNSName:   host,
+			Roots:     roots,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		c.Assert(err, check.IsNil)
+	}
+	_, err = cert.Verify(x509.VerifyOptions{DNSName: "172.16.5.141", Roots: roots})
+	c.Assert(err, check.NotNil)
+}
+
+func (s *caSuite) TestTrusted(c *check.C) {
+	old, err := NewCA("test-cluster")
+	c.Assert(err, check.IsNil)
+	certPEM, _, err := old.Sign("tikv", []string{"172.16.5.140"})
+	c.Assert(err, check.IsNil)
+	ca, err := NewCA("test-cluster")
+	c.Assert(err, check.IsNil)
+
+	dir := c.MkDir()
+	certFile, keyFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.pem")
+	c.Assert(ca.Save(certFile, keyFile, old), check.IsNil)
+	loaded, err := LoadCA(certFile, keyFile)
+	c.Assert(err, check.IsNil)
+	c.Assert(loaded.Cert.Equal(ca.Cert), check.IsTrue)
+	c.Assert(loaded.CertPEM, check.DeepEquals, ca.CertPEM)
+
+	// the certificates signed by the old CA are still trusted
+	data, err := ioutil.ReadFile(certFile)
+	c.Assert(err, check.IsNil)
+	roots := x509.NewCertPool()
+	c.Assert(roots.AppendCertsFromPEM(data), check.IsTrue)
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	c.Assert(err, check.IsNil)
+	_, err = cert.Verify(x509.VerifyOptions{DNSName: "172.16.5.140", Roots: roots})
+	c.Assert(err, check.IsNil)
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cliutil
+
+import (
+	"io"
+	"os"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// ErrEventsFileOpenFailed is ErrEventsFileOpenFailed
+	ErrEventsFileOpenFailed = errNS.NewType("events_file_open_failed", errutil.ErrTraitPreCheck)
+)
+
+// OpenEventsFile opens the file to write the events of tasks to, the events are
+// appended if the file exists. It's the stdout if the path is "-", and the
+// other output is moved to stderr then.
+func OpenEventsFile(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return stdoutEvents()
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, ErrEventsFileOpenFailed.
+			Wrap(err, "Failed to open events file '%s'", path)
+	}
+	return f, nil
+}
+
+// stdoutEvents returns the stdout to write the events to, the human output,
+// e.g: the logs and progress bars, is moved to stderr by pointing the file
+// descriptor of stdout to stderr, so that the events can be parsed.
+func stdoutEvents() (io.WriteCloser, error) {
+	fd, err := unix.Dup(int(os.Stdout.Fd()))
+	if err != nil {
+		return nil, ErrEventsFileOpenFailed.Wrap(err, "Failed to duplicate stdout")
+	}
+	if err := unix.Dup2(int(os.Stderr.Fd()), int(os.Stdout.Fd())); err != nil {
+		_ = unix.Close(fd)
+		return nil, ErrEventsFileOpenFailed.Wrap(err, "Failed to redirect stdout to stderr")
+	}
+	return os.NewFile(uintptr(fd), "stdout"), nil
+}
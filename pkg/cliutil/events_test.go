@@ -0,0 +1,94 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cliutil
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/check"
+)
+
+// the test binary runs as the helper process writing events to stdout if the
+// variable is set
+const eventsHelperEnv = "TIUP_CLUSTER_EVENTS_HELPER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(eventsHelperEnv) != "" {
+		os.Exit(eventsHelper())
+	}
+	os.Exit(m.Run())
+}
+
+func TestCliutil(t *testing.T) {
+	check.TestingT(t)
+}
+
+func eventsHelper() int {
+	w, err := OpenEventsFile("-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintln(w, `{"event":"task_begin"}`)
+	fmt.Println("human output")
+	if err := w.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+type eventsSuite struct{}
+
+var _ = check.Suite(&eventsSuite{})
+
+func (s *eventsSuite) TestStdout(c *check.C) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), eventsHelperEnv+"=1")
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	c.Assert(cmd.Run(), check.IsNil, check.Commentf("stderr: %s", stderr))
+
+	// only the events are left in stdout
+	c.Assert(stdout.String(), check.Equals, "{\"event\":\"task_begin\"}\n")
+	c.Assert(stderr.String(), check.Equals, "human output\n")
+}
+
+func (s *eventsSuite) TestFile(c *check.C) {
+	path := filepath.Join(c.MkDir(), "events.json")
+	for _, line := range []string{"a", "b"} {
+		w, err := OpenEventsFile(path)
+		c.Assert(err, check.IsNil)
+		_, err = fmt.Fprintln(w, line)
+		c.Assert(err, check.IsNil)
+		c.Assert(w.Close(), check.IsNil)
+	}
+
+	// the events are appended
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "a\nb\n")
+
+	_, err = OpenEventsFile(c.MkDir())
+	c.Assert(errorx.IsOfType(errorx.Cast(err), ErrEventsFileOpenFailed), check.IsTrue)
+}
@@ -281,21 +281,32 @@ Please change to use another port or another host.
 
 // BuildDownloadCompTasks build download component tasks
 func BuildDownloadCompTasks(version string, topo meta.Specification) []*task.StepDisplay {
+	tasks, _ := BuildDownloadCompTaskMap(version, topo)
+	return tasks
+}
+
+// DownloadCompKey returns the key of the task downloading the component of inst
+func DownloadCompKey(inst meta.Instance) string {
+	return fmt.Sprintf("%s-%s-%s", inst.ComponentName(), inst.OS(), inst.Arch())
+}
+
+// BuildDownloadCompTaskMap builds the download component tasks as BuildDownloadCompTasks,
+// they are also returned in a map keyed by DownloadCompKey of the instances
+func BuildDownloadCompTaskMap(version string, topo meta.Specification) ([]*task.StepDisplay, map[string]*task.StepDisplay) {
 	var tasks []*task.StepDisplay
-	uniqueTaskList := make(map[string]struct{}) // map["comp-os-arch"]{}
+	taskMap := make(map[string]*task.StepDisplay) // map["comp-os-arch"]task
 	topo.IterInstance(func(inst meta.Instance) {
-		key := fmt.Sprintf("%s-%s-%s", inst.ComponentName(), inst.OS(), inst.Arch())
-		if _, found := uniqueTaskList[key]; !found {
-			uniqueTaskList[key] = struct{}{}
-
+		key := DownloadCompKey(inst)
+		if _, found := taskMap[key]; !found {
 			version := meta.ComponentVersion(inst.ComponentName(), version)
 			t := task.
 				NewBuilder().
 				Download(inst.ComponentName(), inst.OS(), inst.Arch(), version).
 				BuildAsStep(fmt.Sprintf("  - Download %s:%s (%s/%s)",
 					inst.ComponentName(), version, inst.OS(), inst.Arch()))
+			taskMap[key] = t
 			tasks = append(tasks, t)
 		}
 	})
-	return tasks
+	return tasks, taskMap
 }
@@ -14,16 +14,23 @@
 package cliutil
 
 import (
+	"bytes"
 	"io/ioutil"
+	"net"
+	"os"
+	"strings"
 
 	"github.com/ScaleFT/sshkeys"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
 var (
 	// ErrIdentityFileReadFiled is ErrIdentityFileReadFiled
 	ErrIdentityFileReadFiled = errNS.NewType("id_read_failed", errutil.ErrTraitPreCheck)
+	// ErrSudoPasswordReadFailed is ErrSudoPasswordReadFailed
+	ErrSudoPasswordReadFailed = errNS.NewType("sudo_password_read_failed", errutil.ErrTraitPreCheck)
 )
 
 // SSHConnectionProps is SSHConnectionProps
@@ -43,8 +50,17 @@ func ReadIdentityFileOrPassword(identityFilePath string, usePass bool) (*SSHConn
 		}, nil
 	}
 
+	// the identity file is left to the OpenSSH client config
+	if identityFilePath == "" {
+		return &SSHConnectionProps{}, nil
+	}
+
 	// Identity file is specified, check identity file
 	buf, err := ioutil.ReadFile(identityFilePath)
+	if os.IsNotExist(err) && len(agentKeys()) > 0 {
+		// authenticate with the keys in ssh-agent only
+		return &SSHConnectionProps{}, nil
+	}
 	if err != nil {
 		return nil, ErrIdentityFileReadFiled.
 			Wrap(err, "Failed to read SSH identity file '%s'", identityFilePath).
@@ -74,7 +90,20 @@ Looks like your SSH private key {{ColorKeyword}}{{.File}}{{ColorReset}} is inval
 			}))
 	}
 
-	// SSH key is passphrase protected
+	// SSH key is passphrase protected, no need to decrypt it if it's already
+	// loaded in ssh-agent
+	if pubKey, err := ioutil.ReadFile(identityFilePath + ".pub"); err == nil {
+		if pk, _, _, _, err := ssh.ParseAuthorizedKey(pubKey); err == nil {
+			for _, key := range agentKeys() {
+				if bytes.Equal(key.Marshal(), pk.Marshal()) {
+					return &SSHConnectionProps{
+						IdentityFile: identityFilePath,
+					}, nil
+				}
+			}
+		}
+	}
+
 	passphrase := PromptForPassword("The SSH identity key is encrypted. Input its passphrase: ")
 	if _, err := sshkeys.ParseEncryptedPrivateKey(buf, []byte(passphrase)); err != nil {
 		return nil, ErrIdentityFileReadFiled.
@@ -86,3 +115,59 @@ Looks like your SSH private key {{ColorKeyword}}{{.File}}{{ColorReset}} is inval
 		IdentityFilePassphrase: passphrase,
 	}, nil
 }
+
+// EnvNameSudoPassword is the environment variable to read the sudo password from
+const EnvNameSudoPassword = "TIUP_CLUSTER_SUDO_PASSWORD"
+
+// EnvNameDeploySudoPassword is the environment variable to read the sudo
+// password of the deploy user from
+const EnvNameDeploySudoPassword = "TIUP_CLUSTER_DEPLOY_SUDO_PASSWORD"
+
+// ReadSudoPassword returns the password to run sudo on remote hosts, it's read
+// from the file if it's specified, or prompted for if prompt is true, otherwise
+// it's taken from the environment variable. An empty password is returned if
+// none of them is set.
+func ReadSudoPassword(file string, prompt bool) (string, error) {
+	return readPassword(file, prompt, "Input sudo password: ", EnvNameSudoPassword)
+}
+
+// ReadDeploySudoPassword returns the password of the deploy user to run sudo
+// with, it's read the same way as ReadSudoPassword.
+func ReadDeploySudoPassword(file string, prompt bool) (string, error) {
+	return readPassword(file, prompt, "Input sudo password of the deploy user: ", EnvNameDeploySudoPassword)
+}
+
+func readPassword(file string, prompt bool, promptText, env string) (string, error) {
+	switch {
+	case file != "":
+		buf, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", ErrSudoPasswordReadFailed.
+				Wrap(err, "Failed to read sudo password file '%s'", file)
+		}
+		return strings.TrimRight(string(buf), "\r\n"), nil
+	case prompt:
+		return PromptForPassword(promptText), nil
+	default:
+		return os.Getenv(env), nil
+	}
+}
+
+// agentKeys returns the keys in the ssh-agent of SSH_AUTH_SOCK, if any
+func agentKeys() []*agent.Key {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	keys, err := agent.NewClient(conn).List()
+	if err != nil {
+		return nil
+	}
+	return keys
+}
@@ -14,6 +14,9 @@
 package executor
 
 import (
+	"io"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/joomcode/errorx"
@@ -21,18 +24,85 @@ import (
 
 var (
 	errNS = errorx.NewNamespace("executor")
+
+	// ErrUnsupportedSSHType is ErrUnsupportedSSHType
+	ErrUnsupportedSSHType = errNS.NewType("unsupported_ssh_type")
+)
+
+// SSHType represents the way used to connect to a host
+type SSHType string
+
+const (
+	// SSHTypeBuiltin uses the builtin SSH client, hosts on the loopback
+	// interface are connected with the local executor
+	SSHTypeBuiltin SSHType = "builtin"
+	// SSHTypeNone runs everything on the control machine without SSH, all
+	// hosts must be addresses of the control machine
+	SSHTypeNone SSHType = "none"
+	// SSHTypeOpenSSH uses the builtin SSH client, but the Host aliases, User,
+	// Port, IdentityFile and ProxyJump are resolved from the OpenSSH client
+	// config of the current user
+	SSHTypeOpenSSH SSHType = "openssh"
 )
 
 // TiOpsExecutor is the executor interface for TiOps, all tasks will in the end
 // be passed to a executor and then be actually performed.
 type TiOpsExecutor interface {
 	// Execute run the command, then return it's stdout and stderr
-	// NOTE: stdin is not supported as it seems we don't need it (for now). If
-	// at some point in the future we need to pass stdin to a command, we'll
-	// need to refactor this function and its implementations.
 	// If the cmd can't quit in timeout, it will return error, the default timeout is 60 seconds.
 	Execute(cmd string, sudo bool, timeout ...time.Duration) (stdout []byte, stderr []byte, err error)
 
+	// ExecuteWithStdin is the same as Execute, but the data read from stdin is
+	// streamed to the stdin of the command, it should be used to pass secrets
+	// or file contents instead of embedding them in the command line.
+	ExecuteWithStdin(cmd string, stdin io.Reader, sudo bool, timeout ...time.Duration) (stdout []byte, stderr []byte, err error)
+
 	// Transfer copies files from or to a target
 	Transfer(src string, dst string, download bool) error
+
+	// UploadWithMode copies the file to the target like Transfer, but the
+	// file is replaced and never accessible with other modes than mode, it
+	// should be used to upload private keys.
+	UploadWithMode(src string, dst string, mode os.FileMode) error
+}
+
+// New creates a TiOpsExecutor of the given type for the host in the SSHConfig,
+// SSH connections are taken from the pool if it's not nil.
+func New(etype SSHType, sudo bool, c SSHConfig, pool *SSHConnPool) (TiOpsExecutor, error) {
+	switch etype {
+	case "", SSHTypeBuiltin:
+		if IsLoopbackHost(c.Host) {
+			return NewLocalExecutor(c, sudo), nil
+		}
+		e := NewSSHExecutor(c, sudo)
+		if pool != nil {
+			e.Pool = pool
+		}
+		return e, nil
+	case SSHTypeNone:
+		if err := checkLocalHost(c.Host); err != nil {
+			return nil, err
+		}
+		return NewLocalExecutor(c, sudo), nil
+	case SSHTypeOpenSSH:
+		conf, err := loadSSHConfig()
+		if err != nil {
+			return nil, err
+		}
+		if c, err = conf.resolve(c); err != nil {
+			return nil, err
+		}
+		e := NewSSHExecutor(c, sudo)
+		if pool != nil {
+			e.Pool = pool
+		}
+		return e, nil
+	default:
+		return nil, ErrUnsupportedSSHType.New("SSH type '%s' is not supported", etype)
+	}
+}
+
+// shellQuote quotes s as a single word for POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
 }
@@ -0,0 +1,255 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var (
+	// ErrSSHHostKeyMismatch is ErrSSHHostKeyMismatch
+	ErrSSHHostKeyMismatch = errNSSSH.NewType("host_key_mismatch")
+	// ErrSSHHostKeyUnknown is ErrSSHHostKeyUnknown
+	ErrSSHHostKeyUnknown = errNSSSH.NewType("host_key_unknown")
+)
+
+// KnownHosts verifies the host keys of SSH servers with a known_hosts file in
+// the format of OpenSSH. It's safe for concurrent use.
+type KnownHosts struct {
+	sync.Mutex
+	path      string
+	acceptNew bool
+}
+
+// NewKnownHosts creates a KnownHosts backed by the file at path, if acceptNew
+// is true, the keys of hosts not in the file are trusted on first use and added
+// to the file, otherwise connecting to them fails.
+func NewKnownHosts(path string, acceptNew bool) *KnownHosts {
+	return &KnownHosts{
+		path:      path,
+		acceptNew: acceptNew,
+	}
+}
+
+// Path returns the path of the known_hosts file
+func (k *KnownHosts) Path() string {
+	return k.path
+}
+
+// callback returns the ssh.HostKeyCallback to verify host keys, unknown hosts
+// are trusted on first use if either acceptNew or k.acceptNew is true.
+func (k *KnownHosts) callback(acceptNew bool) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		k.Lock()
+		defer k.Unlock()
+
+		err := k.check(hostname, remote, key)
+		keyErr, ok := err.(*knownhosts.KeyError)
+		if !ok {
+			return err
+		}
+
+		host, _, _ := net.SplitHostPort(hostname)
+		if len(keyErr.Want) > 0 {
+			return ErrSSHHostKeyMismatch.
+				New("The %s host key of '%s' is %s, which doesn't match the one in '%s'", key.Type(), hostname, ssh.FingerprintSHA256(key), k.path).
+				WithProperty(cliutil.SuggestionFromTemplate(`
+Someone could be doing something nasty, or the host is rebuilt, please make sure
+it is the host you expected. If the host key is changed legitimately, accept the
+new key with:
+
+    {{ColorCommand}}{{OsArgs0}} trust-host <cluster-name> {{.Host}}{{ColorReset}}
+`, map[string]string{
+					"Host": host,
+				}))
+		}
+
+		if !acceptNew && !k.acceptNew {
+			return ErrSSHHostKeyUnknown.
+				New("The host key of '%s' is not found in '%s'", hostname, k.path).
+				WithProperty(cliutil.SuggestionFromTemplate(`
+Please make sure it is the host you expected, and accept its key with:
+
+    {{ColorCommand}}{{OsArgs0}} trust-host <cluster-name> {{.Host}}{{ColorReset}}
+`, map[string]string{
+					"Host": host,
+				}))
+		}
+
+		return k.add(hostname, key)
+	}
+}
+
+func (k *KnownHosts) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	if _, err := os.Stat(k.path); os.IsNotExist(err) {
+		// no host is known yet
+		return &knownhosts.KeyError{}
+	}
+	check, err := knownhosts.New(k.path)
+	if err != nil {
+		return err
+	}
+	return check(hostname, remote, key)
+}
+
+// add appends the key of hostname to the file, the caller must hold the lock
+func (k *KnownHosts) add(hostname string, key ssh.PublicKey) error {
+	if err := utils.CreateDir(filepath.Dir(k.path)); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(k.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)); err != nil {
+		return err
+	}
+	zap.L().Info("Host key added",
+		zap.String("host", hostname),
+		zap.String("type", key.Type()),
+		zap.String("fingerprint", ssh.FingerprintSHA256(key)),
+		zap.String("file", k.path))
+	return nil
+}
+
+// Trust replaces the saved keys of the address, in the form of host:port, with
+// key, it's used when the host key is changed legitimately.
+func (k *KnownHosts) Trust(addr string, key ssh.PublicKey) error {
+	k.Lock()
+	defer k.Unlock()
+
+	data, err := ioutil.ReadFile(k.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	name := knownhosts.Normalize(addr)
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" && !knownHostsLineMatches(line, name) {
+			lines = append(lines, line+"\n")
+		}
+	}
+	if err := ioutil.WriteFile(k.path, []byte(strings.Join(lines, "")), 0600); err != nil {
+		return err
+	}
+
+	return k.add(addr, key)
+}
+
+// knownHostsLineMatches reports whether the line of known_hosts is for the name,
+// only plain host names are compared, hashed ones are kept as is.
+func knownHostsLineMatches(line, name string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return false
+	}
+	names := fields[0]
+	if strings.HasPrefix(names, "@") {
+		if len(fields) < 2 {
+			return false
+		}
+		names = fields[1]
+	}
+
+	for _, n := range strings.Split(names, ",") {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// errHostKeyScanned aborts the handshake once the host key is received
+var errHostKeyScanned = errors.New("host key scanned")
+
+// ScanHostKey connects to the SSH server of c to get its host key without login,
+// the jump host, if any, is verified with c.KnownHosts. The address of the
+// server is returned along with the key. If the host is not connected via SSH
+// with the SSH type, a nil key is returned.
+func ScanHostKey(etype SSHType, c SSHConfig) (addr string, key ssh.PublicKey, err error) {
+	switch etype {
+	case "", SSHTypeBuiltin:
+		if IsLoopbackHost(c.Host) {
+			return "", nil, nil
+		}
+	case SSHTypeNone:
+		return "", nil, nil
+	case SSHTypeOpenSSH:
+		conf, err := loadSSHConfig()
+		if err != nil {
+			return "", nil, err
+		}
+		if c, err = conf.resolve(c); err != nil {
+			return "", nil, err
+		}
+	default:
+		return "", nil, ErrUnsupportedSSHType.New("SSH type '%s' is not supported", etype)
+	}
+
+	e := &SSHExecutor{}
+	e.Initialize(c)
+	addr = net.JoinHostPort(e.Config.Server, e.Config.Port)
+	config := &ssh.ClientConfig{
+		User:    e.Config.User,
+		Timeout: e.Config.Timeout,
+		HostKeyCallback: func(_ string, _ net.Addr, k ssh.PublicKey) error {
+			key = k
+			return errHostKeyScanned
+		},
+	}
+
+	var conn net.Conn
+	if e.Config.Proxy.Server == "" {
+		conn, err = net.DialTimeout("tcp", addr, e.Config.Timeout)
+	} else {
+		var hostKeyErr error
+		var proxy *ssh.Client
+		proxy, err = dialProxy(e.Config, keepError(e.HostKeyCallback, &hostKeyErr))
+		if err != nil {
+			if hostKeyErr != nil {
+				return "", nil, hostKeyErr
+			}
+			return "", nil, err
+		}
+		defer proxy.Close()
+		conn, err = proxy.Dial("tcp", addr)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(e.Config.Timeout))
+
+	if _, _, _, err = ssh.NewClientConn(conn, addr, config); key == nil {
+		return "", nil, err
+	}
+	return addr, key, nil
+}
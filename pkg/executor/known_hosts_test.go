@@ -0,0 +1,63 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"path/filepath"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/check"
+	"golang.org/x/crypto/ssh"
+)
+
+type knownHostsSuite struct{}
+
+var _ = check.Suite(&knownHostsSuite{})
+
+func newHostKey(c *check.C) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	c.Assert(err, check.IsNil)
+	key, err := ssh.NewPublicKey(pub)
+	c.Assert(err, check.IsNil)
+	return key
+}
+
+func (s *knownHostsSuite) TestVerify(c *check.C) {
+	path := filepath.Join(c.MkDir(), "ssh", "known_hosts")
+	remote := &net.TCPAddr{IP: net.ParseIP("172.16.5.140"), Port: 22}
+	key1, key2 := newHostKey(c), newHostKey(c)
+
+	// unknown hosts are rejected if not trusted on first use
+	strict := NewKnownHosts(path, false)
+	err := strict.callback(false)("172.16.5.140:22", remote, key1)
+	c.Assert(errorx.IsOfType(err, ErrSSHHostKeyUnknown), check.IsTrue)
+
+	c.Assert(strict.callback(true)("172.16.5.140:22", remote, key1), check.IsNil)
+	c.Assert(strict.callback(false)("172.16.5.140:22", remote, key1), check.IsNil)
+	c.Assert(NewKnownHosts(path, true).callback(false)("172.16.5.141:2222", remote, key1), check.IsNil)
+
+	// a changed key is always rejected
+	err = NewKnownHosts(path, true).callback(true)("172.16.5.140:22", remote, key2)
+	c.Assert(errorx.IsOfType(err, ErrSSHHostKeyMismatch), check.IsTrue)
+
+	c.Assert(strict.Trust("172.16.5.140:22", key2), check.IsNil)
+	c.Assert(strict.callback(false)("172.16.5.140:22", remote, key2), check.IsNil)
+	err = strict.callback(false)("172.16.5.140:22", remote, key1)
+	c.Assert(errorx.IsOfType(err, ErrSSHHostKeyMismatch), check.IsTrue)
+	// keys of other hosts are kept
+	c.Assert(strict.callback(false)("172.16.5.141:2222", remote, key1), check.IsNil)
+}
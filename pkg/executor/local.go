@@ -0,0 +1,247 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"go.uber.org/zap"
+)
+
+var (
+	errNSLocal = errNS.NewSubNamespace("local")
+
+	// ErrPropLocalCommand is ErrPropLocalCommand
+	ErrPropLocalCommand = errorx.RegisterPrintableProperty("local_command")
+	// ErrPropLocalStdout is ErrPropLocalStdout
+	ErrPropLocalStdout = errorx.RegisterPrintableProperty("local_stdout")
+	// ErrPropLocalStderr is ErrPropLocalStderr
+	ErrPropLocalStderr = errorx.RegisterPrintableProperty("local_stderr")
+
+	// ErrLocalExecuteFailed is ErrLocalExecuteFailed
+	ErrLocalExecuteFailed = errNSLocal.NewType("execute_failed")
+	// ErrLocalExecuteTimedout is ErrLocalExecuteTimedout
+	ErrLocalExecuteTimedout = errNSLocal.NewType("execute_timedout")
+	// ErrLocalHostRequired is ErrLocalHostRequired
+	ErrLocalHostRequired = errNSLocal.NewType("local_host_required")
+)
+
+// LocalExecutor implements TiOpsExecutor by running commands on the control
+// machine directly, it's used for hosts which can be reached without SSH.
+type LocalExecutor struct {
+	Host string // the host in topology, only used for logging
+	User string // the user to run commands as, default to the current user
+	Sudo bool   // all commands run with this executor will be using sudo
+
+	sudoer *sudoer
+}
+
+var _ TiOpsExecutor = &LocalExecutor{}
+
+// NewLocalExecutor create a local executor, only Host, User and SudoPassword
+// of the SSHConfig are used.
+func NewLocalExecutor(c SSHConfig, sudo bool) *LocalExecutor {
+	return &LocalExecutor{
+		Host:   c.Host,
+		User:   c.User,
+		Sudo:   sudo,
+		sudoer: newSudoer(c.SudoPassword),
+	}
+}
+
+// switchUser reports whether the commands need to be run as another user
+func (e *LocalExecutor) switchUser() bool {
+	return e.User != "" && e.User != utils.CurrentUser()
+}
+
+// Execute run the command on the control machine as the user of executor, it
+// behaves the same as SSHExecutor: commands start in the home directory of the user.
+func (e *LocalExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	return e.ExecuteWithStdin(cmd, nil, sudo, timeout...)
+}
+
+// ExecuteWithStdin run the command on the control machine with the data of
+// stdin sent to it
+func (e *LocalExecutor) ExecuteWithStdin(cmd string, stdin io.Reader, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	cmd = fmt.Sprintf("cd; %s", cmd)
+
+	if len(timeout) == 0 {
+		timeout = append(timeout, executeDefaultTimeout)
+	}
+
+	// try to acquire root permission, or switch to the login user like SSH does,
+	// sudo may prompt on the terminal if no password is given
+	useSudo := e.Sudo || sudo || e.switchUser()
+	if useSudo {
+		if err := e.sudoer.check(utils.CurrentUser(), e.Host, e.runWithoutSudo); err != nil {
+			return nil, nil, err
+		}
+		user := "root"
+		if !e.Sudo && !sudo {
+			user = e.User
+		}
+		cmd = e.sudoer.command(cmd, user, false)
+		stdin = e.sudoer.stdin(stdin)
+	}
+
+	// set a basic PATH in case it's empty on login
+	cmd = fmt.Sprintf("PATH=$PATH:/usr/bin:/usr/sbin %s", cmd)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout[0])
+	defer cancel()
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	err := e.run(ctx, cmd, stdin, stdout, stderr)
+
+	zap.L().Info("LocalCommand",
+		zap.String("host", e.Host),
+		zap.String("cmd", cmd),
+		zap.Error(err),
+		zap.String("stdout", stdout.String()),
+		zap.String("stderr", stderr.String()))
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return stdout.Bytes(), stderr.Bytes(), ErrLocalExecuteTimedout.
+			Wrap(ctx.Err(), "Execute command locally timedout for host '%s'", e.Host).
+			WithProperty(ErrPropLocalCommand, cmd).
+			WithProperty(ErrPropLocalStdout, stdout.String()).
+			WithProperty(ErrPropLocalStderr, stderr.String())
+	}
+
+	if err != nil {
+		if useSudo {
+			if sudoErr := sudoError(stderr.String(), utils.CurrentUser(), e.Host, err); sudoErr != nil {
+				return stdout.Bytes(), stderr.Bytes(), sudoErr
+			}
+		}
+		baseErr := ErrLocalExecuteFailed.
+			Wrap(err, "Failed to execute command locally for host '%s'", e.Host).
+			WithProperty(ErrPropLocalCommand, cmd).
+			WithProperty(ErrPropLocalStdout, stdout.String()).
+			WithProperty(ErrPropLocalStderr, stderr.String())
+		if stdout.Len() > 0 || stderr.Len() > 0 {
+			output := strings.TrimSpace(strings.Join([]string{stdout.String(), stderr.String()}, "\n"))
+			baseErr = baseErr.
+				WithProperty(cliutil.SuggestionFromFormat("Command output on local host:\n%s\n",
+					color.YellowString(output)))
+		}
+		return stdout.Bytes(), stderr.Bytes(), baseErr
+	}
+
+	return stdout.Bytes(), stderr.Bytes(), nil
+}
+
+func (e *LocalExecutor) run(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	command := exec.CommandContext(ctx, "/bin/bash", "-c", cmd)
+	// the command is not killed by Ctrl-C in the terminal as it's in its own
+	// process group, it's left to finish like the ones on remote hosts
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	command.Stdin = stdin
+	command.Stdout = stdout
+	command.Stderr = stderr
+	return command.Run()
+}
+
+// runWithoutSudo runs the command to check sudo, it returns the stderr
+func (e *LocalExecutor) runWithoutSudo(cmd string, stdin io.Reader) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), executeDefaultTimeout)
+	defer cancel()
+
+	stderr := new(bytes.Buffer)
+	err := e.run(ctx, fmt.Sprintf("PATH=$PATH:/usr/bin:/usr/sbin %s", cmd), stdin, nil, stderr)
+	return stderr.String(), err
+}
+
+// Transfer copies files on the control machine, the copied file is owned by
+// the user of executor when uploading, and by the current user when downloading.
+func (e *LocalExecutor) Transfer(src string, dst string, download bool) error {
+	cmd := fmt.Sprintf("cp %s %s", src, dst)
+	owner := e.User
+	if download {
+		if err := utils.CreateDir(filepath.Dir(dst)); err != nil {
+			return err
+		}
+		owner = utils.CurrentUser()
+	}
+
+	// the file may not be accessible by both the users, so copy it with root
+	// permission and then fix the ownership
+	if e.switchUser() {
+		cmd = fmt.Sprintf("%s && chown %s: %s", cmd, owner, dst)
+		_, _, err := e.Execute(cmd, true)
+		return err
+	}
+
+	_, _, err := e.Execute(cmd, false)
+	return err
+}
+
+// UploadWithMode implements TiOpsExecutor interface, the file is copied with
+// umask 077 so that it's only accessible by the owner before the mode is set.
+func (e *LocalExecutor) UploadWithMode(src string, dst string, mode os.FileMode) error {
+	cmd := fmt.Sprintf("rm -f %[2]s && (umask 077 && cp %[1]s %[2]s)", src, dst)
+	sudo := false
+	if e.switchUser() {
+		cmd = fmt.Sprintf("%s && chown %s: %s", cmd, e.User, dst)
+		sudo = true
+	}
+	cmd = fmt.Sprintf("%s && chmod %04o %s", cmd, mode.Perm(), dst)
+	_, _, err := e.Execute(cmd, sudo)
+	return err
+}
+
+// IsLoopbackHost reports whether the host is an address of the loopback interface
+func IsLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// checkLocalHost returns error if the host is not an address of the control machine
+func checkLocalHost(host string) error {
+	if IsLoopbackHost(host) {
+		return nil
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.String() == host {
+			return nil
+		}
+	}
+
+	return ErrLocalHostRequired.
+		New("Host '%s' is not an address of the current machine, it can't be connected without SSH", host).
+		WithProperty(cliutil.SuggestionFromString("Please set 'ssh_type' to 'builtin' in the global section of topology, or use addresses of the current machine only."))
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/check"
+)
+
+type localSuite struct{}
+
+var _ = check.Suite(&localSuite{})
+
+func TestExecutor(t *testing.T) {
+	check.TestingT(t)
+}
+
+func (s *localSuite) TestNew(c *check.C) {
+	e, err := New(SSHTypeBuiltin, false, SSHConfig{Host: "127.0.0.1"}, nil)
+	c.Assert(err, check.IsNil)
+	_, ok := e.(*LocalExecutor)
+	c.Assert(ok, check.IsTrue)
+
+	e, err = New("", false, SSHConfig{Host: "172.16.5.140"}, nil)
+	c.Assert(err, check.IsNil)
+	_, ok = e.(*SSHExecutor)
+	c.Assert(ok, check.IsTrue)
+
+	_, err = New(SSHTypeNone, false, SSHConfig{Host: "localhost"}, nil)
+	c.Assert(err, check.IsNil)
+	_, err = New(SSHTypeNone, false, SSHConfig{Host: "203.0.113.1"}, nil)
+	c.Assert(err, check.NotNil)
+	_, err = New("unknown", false, SSHConfig{Host: "127.0.0.1"}, nil)
+	c.Assert(err, check.NotNil)
+}
+
+func (s *localSuite) TestExecuteAndTransfer(c *check.C) {
+	e := NewLocalExecutor(SSHConfig{Host: "127.0.0.1", User: utils.CurrentUser()}, false)
+
+	stdout, _, err := e.Execute("echo foo", false)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(stdout), check.Equals, "foo\n")
+
+	_, _, err = e.Execute("exit 1", false)
+	c.Assert(err, check.NotNil)
+	_, _, err = e.Execute("sleep 1", false, time.Millisecond*100)
+	c.Assert(err, check.NotNil)
+
+	stdout, _, err = e.ExecuteWithStdin(`read v; echo "$v-$(cat)"`, strings.NewReader("foo\nbar"), false)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(stdout), check.Equals, "foo-bar\n")
+
+	dir := c.MkDir()
+	src := filepath.Join(dir, "src")
+	c.Assert(ioutil.WriteFile(src, []byte("bar"), 0644), check.IsNil)
+	dst := filepath.Join(dir, "sub", "dst")
+	c.Assert(e.Transfer(src, dst, true), check.IsNil)
+	data, err := ioutil.ReadFile(dst)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "bar")
+
+	// overwrite the existing file like scp does
+	c.Assert(e.Transfer(src, dst, false), check.IsNil)
+	_, err = os.Stat(dst)
+	c.Assert(err, check.IsNil)
+
+	// the existing file is replaced with the mode
+	c.Assert(e.UploadWithMode(src, dst, 0600), check.IsNil)
+	fi, err := os.Stat(dst)
+	c.Assert(err, check.IsNil)
+	c.Assert(fi.Mode().Perm(), check.Equals, os.FileMode(0600))
+	data, err = ioutil.ReadFile(dst)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "bar")
+}
+
+func (s *localSuite) TestShellQuote(c *check.C) {
+	e := NewLocalExecutor(SSHConfig{Host: "127.0.0.1", User: utils.CurrentUser()}, false)
+	for _, arg := range []string{"", "foo bar", `"$HOME"`, "it's", `\'`} {
+		stdout, _, err := e.Execute("printf %s "+shellQuote(arg), false)
+		c.Assert(err, check.IsNil)
+		c.Assert(string(stdout), check.Equals, arg)
+	}
+}
+
+func (s *localSuite) TestProcessGroup(c *check.C) {
+	e := NewLocalExecutor(SSHConfig{Host: "127.0.0.1", User: utils.CurrentUser()}, false)
+
+	// the command doesn't get the SIGINT sent to the process group of the
+	// terminal by Ctrl-C
+	stdout, _, err := e.Execute("ps -o pgid= -p $$", false)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.TrimSpace(string(stdout)), check.Not(check.Equals), strconv.Itoa(syscall.Getpgrp()))
+}
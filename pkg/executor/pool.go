@@ -0,0 +1,373 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/ScaleFT/sshkeys"
+	"github.com/appleboy/easyssh-proxy"
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// maxSessionsPerConn is the max number of concurrent sessions over one
+// connection, it's lower than the default MaxSessions (10) of sshd
+const maxSessionsPerConn = 8
+
+// SSHConnPool keeps long-lived SSH connections, commands sent to the same
+// user@host:port share connections by running in separated sessions, new
+// connections are only established when the existing ones are busy or broken.
+// It's safe for concurrent use.
+type SSHConnPool struct {
+	sync.Mutex
+	conns  map[string]*connList
+	closed bool
+
+	// the max number of concurrent sessions to a host, no matter which user
+	// or connection they are using, unlimited if it's not positive
+	maxHostSessions int
+	hostSlots       map[string]chan struct{}
+}
+
+// connList is the list of connections to the same target
+type connList struct {
+	sync.Mutex
+	conns []*sshConn
+}
+
+type sshConn struct {
+	client   *ssh.Client
+	proxy    *ssh.Client // the connection to the jump host, if any
+	sessions int         // number of sessions in use
+	broken   bool        // no more sessions should be opened on a broken connection
+}
+
+func (c *sshConn) close() error {
+	err := c.client.Close()
+	if c.proxy != nil {
+		_ = c.proxy.Close()
+	}
+	return err
+}
+
+// NewSSHConnPool creates an empty SSHConnPool
+func NewSSHConnPool() *SSHConnPool {
+	return &SSHConnPool{
+		conns:     make(map[string]*connList),
+		hostSlots: make(map[string]chan struct{}),
+	}
+}
+
+// SetMaxHostSessions limits the number of concurrent sessions to each host,
+// the commands to a host wait for the running ones if the limit is reached.
+// It should be set before the pool is used.
+func (p *SSHConnPool) SetMaxHostSessions(n int) {
+	p.Lock()
+	defer p.Unlock()
+	p.maxHostSessions = n
+}
+
+// slots returns the semaphore of the sessions to the host, or nil if it's
+// unlimited
+func (p *SSHConnPool) slots(host string) chan struct{} {
+	p.Lock()
+	defer p.Unlock()
+	if p.maxHostSessions <= 0 {
+		return nil
+	}
+	s, ok := p.hostSlots[host]
+	if !ok {
+		s = make(chan struct{}, p.maxHostSessions)
+		p.hostSlots[host] = s
+	}
+	return s
+}
+
+func connKey(config *easyssh.MakeConfig) string {
+	key := fmt.Sprintf("%s@%s", config.User, net.JoinHostPort(config.Server, config.Port))
+	if config.Proxy.Server != "" {
+		key += fmt.Sprintf(" via %s@%s", config.Proxy.User, net.JoinHostPort(config.Proxy.Server, config.Proxy.Port))
+	}
+	return key
+}
+
+func (p *SSHConnPool) list(config *easyssh.MakeConfig) (*connList, error) {
+	key := connKey(config)
+
+	p.Lock()
+	defer p.Unlock()
+	if p.closed {
+		return nil, fmt.Errorf("connection pool closed, can't connect to %s", key)
+	}
+	l, ok := p.conns[key]
+	if !ok {
+		l = &connList{}
+		p.conns[key] = l
+	}
+	return l, nil
+}
+
+// acquire returns a connection to the target in config with a session slot
+// reserved, a new connection is established if all the existing ones are busy.
+// It waits if the sessions to the host reach the limit. The connection must be
+// released after the session is closed.
+func (p *SSHConnPool) acquire(config *easyssh.MakeConfig, hostKeyCallback ssh.HostKeyCallback) (conn *sshConn, err error) {
+	l, err := p.list(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if slots := p.slots(config.Server); slots != nil {
+		slots <- struct{}{}
+		defer func() {
+			if err != nil {
+				<-slots
+			}
+		}()
+	}
+
+	// only lock the connections of this target, so that connecting to different
+	// hosts are not blocked by each other
+	l.Lock()
+	defer l.Unlock()
+	for _, c := range l.conns {
+		if !c.broken && c.sessions < maxSessionsPerConn {
+			c.sessions++
+			return c, nil
+		}
+	}
+
+	client, proxy, err := dial(config, hostKeyCallback)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Debug("SSH connection established", zap.String("target", connKey(config)))
+
+	conn = &sshConn{client: client, proxy: proxy, sessions: 1}
+	l.conns = append(l.conns, conn)
+	return conn, nil
+}
+
+// release gives back the session slot of the connection, if the connection
+// is broken, it's closed when no session is using it anymore.
+func (p *SSHConnPool) release(config *easyssh.MakeConfig, conn *sshConn, broken bool) {
+	if slots := p.slots(config.Server); slots != nil {
+		defer func() { <-slots }()
+	}
+
+	l, err := p.list(config)
+	if err != nil {
+		// the pool is closed, so is the connection
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	conn.sessions--
+	if broken {
+		conn.broken = true
+	}
+	if !conn.broken || conn.sessions > 0 {
+		return
+	}
+
+	for i, c := range l.conns {
+		if c == conn {
+			l.conns = append(l.conns[:i], l.conns[i+1:]...)
+			break
+		}
+	}
+	_ = conn.close()
+	zap.L().Debug("SSH connection closed", zap.String("target", connKey(config)))
+}
+
+// Close closes all the connections in the pool, the pool can't be used anymore
+func (p *SSHConnPool) Close() error {
+	p.Lock()
+	defer p.Unlock()
+
+	var firstErr error
+	for key, l := range p.conns {
+		l.Lock()
+		for _, conn := range l.conns {
+			if err := conn.close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		l.conns = nil
+		l.Unlock()
+		delete(p.conns, key)
+	}
+	p.closed = true
+	return firstErr
+}
+
+// dial connects to the target in config, it's the same as easyssh.MakeConfig.Connect
+// except that no session is opened, the connection to the jump host is returned
+// as proxy and should be closed after the client if it's not nil.
+func dial(config *easyssh.MakeConfig, hostKeyCallback ssh.HostKeyCallback) (client *ssh.Client, proxy *ssh.Client, err error) {
+	var hostKeyErr error
+	hostKeyCallback = keepError(hostKeyCallback, &hostKeyErr)
+	defer func() {
+		if err != nil && hostKeyErr != nil {
+			err = hostKeyErr
+		}
+	}()
+
+	targetConfig, closer, err := clientConfig(config.User, config.KeyPath, config.Passphrase, config.Password)
+	if err != nil {
+		return nil, nil, err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+	targetConfig.Timeout = config.Timeout
+	targetConfig.HostKeyCallback = hostKeyCallback
+	target := net.JoinHostPort(config.Server, config.Port)
+
+	if config.Proxy.Server == "" {
+		client, err = ssh.Dial("tcp", target, targetConfig)
+		return client, nil, err
+	}
+
+	proxy, err = dialProxy(config, hostKeyCallback)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := proxy.Dial("tcp", target)
+	if err != nil {
+		proxy.Close()
+		return nil, nil, err
+	}
+	ncc, chans, reqs, err := ssh.NewClientConn(conn, target, targetConfig)
+	if err != nil {
+		conn.Close()
+		proxy.Close()
+		return nil, nil, err
+	}
+
+	return ssh.NewClient(ncc, chans, reqs), proxy, nil
+}
+
+// dialProxy connects to the jump host in config
+func dialProxy(config *easyssh.MakeConfig, hostKeyCallback ssh.HostKeyCallback) (*ssh.Client, error) {
+	proxyConfig, closer, err := clientConfig(config.Proxy.User, config.Proxy.KeyPath, config.Proxy.Passphrase, config.Proxy.Password)
+	if err != nil {
+		return nil, err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+	proxyConfig.Timeout = config.Proxy.Timeout
+	proxyConfig.HostKeyCallback = hostKeyCallback
+
+	proxy, err := ssh.Dial("tcp", net.JoinHostPort(config.Proxy.Server, config.Proxy.Port), proxyConfig)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to connect to the jump host %s", config.Proxy.Server)
+	}
+	return proxy, nil
+}
+
+// keepError wraps the callback to save its error to errp, as the error of host
+// key verification is flattened to string by the ssh package. All host keys are
+// accepted if the callback is nil.
+func keepError(cb ssh.HostKeyCallback, errp *error) ssh.HostKeyCallback {
+	if cb == nil {
+		cb = ssh.InsecureIgnoreHostKey()
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if err := cb(hostname, remote, key); err != nil {
+			*errp = err
+			return err
+		}
+		return nil
+	}
+}
+
+// clientConfig builds the ssh.ClientConfig with all available auth methods, the
+// HostKeyCallback is left to the caller. If the returned connection to ssh-agent
+// is not nil, it should be closed after the handshake is done.
+func clientConfig(user, keyPath, passphrase, password string) (*ssh.ClientConfig, *net.UnixConn, error) {
+	var auths []ssh.AuthMethod
+
+	if password != "" {
+		auths = append(auths, ssh.Password(password))
+	}
+
+	if keyPath != "" {
+		buf, err := ioutil.ReadFile(keyPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		var signer ssh.Signer
+		if passphrase != "" {
+			signer, err = sshkeys.ParseEncryptedPrivateKey(buf, []byte(passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(buf)
+		}
+		_, missingPassphrase := err.(*ssh.PassphraseMissingError)
+		switch {
+		case err == nil:
+			auths = append(auths, ssh.PublicKeys(signer))
+		case missingPassphrase && os.Getenv("SSH_AUTH_SOCK") != "":
+			// the key is encrypted but no passphrase is given, it's expected
+			// to be loaded in ssh-agent
+		default:
+			return nil, nil, err
+		}
+	} else if password == "" {
+		// like OpenSSH, the default keys are tried if no key is specified,
+		// the encrypted ones are left to ssh-agent
+		if signers := defaultKeySigners(); len(signers) > 0 {
+			auths = append(auths, ssh.PublicKeys(signers...))
+		}
+	}
+
+	var agentConn *net.UnixConn
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: sock, Net: "unix"}); err == nil {
+			agentConn = conn
+			auths = append(auths, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+
+	return &ssh.ClientConfig{
+		User: user,
+		Auth: auths,
+	}, agentConn, nil
+}
+
+// defaultKeySigners returns the signers of the default identity files which
+// exist and are not encrypted
+func defaultKeySigners() []ssh.Signer {
+	var signers []ssh.Signer
+	for _, file := range defaultIdentityFiles {
+		buf, err := ioutil.ReadFile(expandHome(file))
+		if err != nil {
+			continue
+		}
+		if signer, err := ssh.ParsePrivateKey(buf); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	return signers
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/appleboy/easyssh-proxy"
+	"github.com/pingcap/check"
+)
+
+type poolSuite struct{}
+
+var _ = check.Suite(&poolSuite{})
+
+// closedPortConfig returns the config of a target refusing connections
+func closedPortConfig(c *check.C) *easyssh.MakeConfig {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	port := l.Addr().(*net.TCPAddr).Port
+	c.Assert(l.Close(), check.IsNil)
+	return &easyssh.MakeConfig{
+		User:     "tidb",
+		Server:   "127.0.0.1",
+		Port:     strconv.Itoa(port),
+		Password: "secret",
+		Timeout:  time.Second,
+	}
+}
+
+func (s *poolSuite) TestHostSlots(c *check.C) {
+	p := NewSSHConnPool()
+	c.Assert(p.slots("127.0.0.1"), check.IsNil)
+
+	p.SetMaxHostSessions(2)
+	slots := p.slots("127.0.0.1")
+	c.Assert(cap(slots), check.Equals, 2)
+	c.Assert(p.slots("127.0.0.1"), check.Equals, slots)
+	c.Assert(p.slots("127.0.0.2"), check.Not(check.Equals), slots)
+}
+
+func (s *poolSuite) TestAcquireWaitsForSlot(c *check.C) {
+	p := NewSSHConnPool()
+	p.SetMaxHostSessions(2)
+	config := closedPortConfig(c)
+
+	// the sessions to the host reach the limit
+	slots := p.slots(config.Server)
+	slots <- struct{}{}
+	slots <- struct{}{}
+
+	done := make(chan error)
+	go func() {
+		_, err := p.acquire(config, nil)
+		done <- err
+	}()
+	select {
+	case <-done:
+		c.Fatal("acquired a connection beyond the limit of sessions")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	<-slots
+	select {
+	case err := <-done:
+		c.Assert(err, check.NotNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("the released slot is not taken")
+	}
+	// the slot is given back as the connection is failed
+	c.Assert(len(slots), check.Equals, 1)
+}
+
+func (s *poolSuite) TestAcquireErrorReleasesSlot(c *check.C) {
+	p := NewSSHConnPool()
+	p.SetMaxHostSessions(1)
+	config := closedPortConfig(c)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			_, err := p.acquire(config, nil)
+			c.Check(err, check.NotNil)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		c.Fatal("the slot is not released when the connection is failed")
+	}
+	c.Assert(len(p.slots(config.Server)), check.Equals, 0)
+}
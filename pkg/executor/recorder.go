@@ -0,0 +1,199 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// ActionType is the type of operations recorded in dry run
+type ActionType string
+
+// types of the recorded operations
+const (
+	// ActionExecute is a command executed on the host
+	ActionExecute ActionType = "execute"
+	// ActionUpload is a file copied from the control machine to the host
+	ActionUpload ActionType = "upload"
+	// ActionDownload is a file copied from the host to the control machine
+	ActionDownload ActionType = "download"
+	// ActionNote is an operation not performed by executors, e.g: calling
+	// the API of PD or saving the meta of cluster
+	ActionNote ActionType = "note"
+)
+
+// Action is an operation recorded in dry run
+type Action struct {
+	Type   ActionType
+	Host   string // the host to operate on, empty for the control machine
+	User   string // the user to run the command as
+	Sudo   bool   // the command is run with sudo
+	Detail string // the command, source and destination of transfer, or the note
+}
+
+// String implements the fmt.Stringer interface
+func (a Action) String() string {
+	switch a.Type {
+	case ActionExecute:
+		prompt := "$"
+		if a.Sudo {
+			prompt = "#"
+		}
+		return fmt.Sprintf("[%s]%s %s", a.User, prompt, a.Detail)
+	case ActionUpload, ActionDownload:
+		return fmt.Sprintf("[%s] %s %s", a.User, a.Type, a.Detail)
+	default:
+		return fmt.Sprintf("- %s", a.Detail)
+	}
+}
+
+// Recorder keeps the operations of all the RecordingExecutors in order, it's
+// safe for concurrent use.
+type Recorder struct {
+	sync.Mutex
+	actions []Action
+}
+
+// NewRecorder creates an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Record appends the action to the recorder
+func (r *Recorder) Record(a Action) {
+	r.Lock()
+	defer r.Unlock()
+	r.actions = append(r.actions, a)
+}
+
+// Note records an operation which is not performed by executors, the host is
+// empty if it's on the control machine.
+func (r *Recorder) Note(host, format string, a ...interface{}) {
+	r.Record(Action{
+		Type:   ActionNote,
+		Host:   host,
+		Detail: fmt.Sprintf(format, a...),
+	})
+}
+
+// Hosts returns the hosts with recorded actions in the order of their first
+// actions, the control machine is represented by an empty string.
+func (r *Recorder) Hosts() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	var hosts []string
+	seen := make(map[string]struct{})
+	for _, a := range r.actions {
+		if _, ok := seen[a.Host]; !ok {
+			seen[a.Host] = struct{}{}
+			hosts = append(hosts, a.Host)
+		}
+	}
+	return hosts
+}
+
+// Actions returns the recorded actions of the host in order
+func (r *Recorder) Actions(host string) []Action {
+	r.Lock()
+	defer r.Unlock()
+
+	var actions []Action
+	for _, a := range r.actions {
+		if a.Host == host {
+			actions = append(actions, a)
+		}
+	}
+	return actions
+}
+
+// RecordingExecutor implements TiOpsExecutor by recording the operations instead
+// of performing them, it's used to show what would be done in dry run. Commands
+// always succeed with empty output.
+type RecordingExecutor struct {
+	Host     string
+	User     string
+	Sudo     bool // all commands run with this executor will be using sudo
+	Recorder *Recorder
+}
+
+var _ TiOpsExecutor = &RecordingExecutor{}
+
+// NewRecordingExecutor creates a RecordingExecutor for the host of c, only Host
+// and User of the SSHConfig are used.
+func NewRecordingExecutor(c SSHConfig, sudo bool, r *Recorder) *RecordingExecutor {
+	return &RecordingExecutor{
+		Host:     c.Host,
+		User:     c.User,
+		Sudo:     sudo,
+		Recorder: r,
+	}
+}
+
+// Execute implements TiOpsExecutor interface
+func (e *RecordingExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	return e.ExecuteWithStdin(cmd, nil, sudo, timeout...)
+}
+
+// ExecuteWithStdin implements TiOpsExecutor interface, the data of stdin is
+// recorded along with the command.
+func (e *RecordingExecutor) ExecuteWithStdin(cmd string, stdin io.Reader, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	if stdin != nil {
+		data, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, nil, err
+		}
+		cmd = fmt.Sprintf("%s <<< %s", cmd, shellQuote(string(data)))
+	}
+
+	e.Recorder.Record(Action{
+		Type:   ActionExecute,
+		Host:   e.Host,
+		User:   e.User,
+		Sudo:   e.Sudo || sudo,
+		Detail: cmd,
+	})
+	return nil, nil, nil
+}
+
+// Transfer implements TiOpsExecutor interface
+func (e *RecordingExecutor) Transfer(src string, dst string, download bool) error {
+	a := Action{
+		Type:   ActionUpload,
+		Host:   e.Host,
+		User:   e.User,
+		Detail: fmt.Sprintf("%s -> %s", src, dst),
+	}
+	if download {
+		a.Type = ActionDownload
+	}
+	e.Recorder.Record(a)
+	return nil
+}
+
+// UploadWithMode implements TiOpsExecutor interface
+func (e *RecordingExecutor) UploadWithMode(src string, dst string, mode os.FileMode) error {
+	e.Recorder.Record(Action{
+		Type:   ActionUpload,
+		Host:   e.Host,
+		User:   e.User,
+		Detail: fmt.Sprintf("%s -> %s (mode %04o)", src, dst, mode.Perm()),
+	})
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"strings"
+
+	"github.com/pingcap/check"
+)
+
+type recorderSuite struct{}
+
+var _ = check.Suite(&recorderSuite{})
+
+func (s *recorderSuite) TestRecordingExecutor(c *check.C) {
+	r := NewRecorder()
+	e1 := NewRecordingExecutor(SSHConfig{Host: "h1", User: "root"}, false, r)
+	e2 := NewRecordingExecutor(SSHConfig{Host: "h2", User: "tidb"}, true, r)
+
+	stdout, stderr, err := e2.Execute("ls", false)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout, check.HasLen, 0)
+	c.Assert(stderr, check.HasLen, 0)
+	r.Note("", "download tidb")
+	_, _, err = e1.ExecuteWithStdin("cat > a", strings.NewReader("it's"), true)
+	c.Assert(err, check.IsNil)
+	c.Assert(e1.Transfer("src", "dst", false), check.IsNil)
+	c.Assert(e2.Transfer("src", "dst", true), check.IsNil)
+	c.Assert(e1.UploadWithMode("key", "dst.pem", 0600), check.IsNil)
+
+	c.Assert(r.Hosts(), check.DeepEquals, []string{"h2", "", "h1"})
+	var actions []string
+	for _, host := range r.Hosts() {
+		for _, a := range r.Actions(host) {
+			actions = append(actions, a.String())
+		}
+	}
+	c.Assert(actions, check.DeepEquals, []string{
+		"[tidb]# ls",
+		"[tidb] download src -> dst",
+		"- download tidb",
+		`[root]# cat > a <<< 'it'\''s'`,
+		"[root] upload src -> dst",
+		"[root] upload key -> dst.pem (mode 0600)",
+	})
+}
@@ -14,7 +14,9 @@
 package executor
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -27,6 +29,7 @@ import (
 	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
 )
 
 var (
@@ -64,7 +67,12 @@ type (
 	// SSHExecutor implements TiOpsExecutor with SSH as transportation layer.
 	SSHExecutor struct {
 		Config *easyssh.MakeConfig
-		Sudo   bool // all commands run with this executor will be using sudo
+		Sudo   bool         // all commands run with this executor will be using sudo
+		Pool   *SSHConnPool // the pool to get connection from, shared by executors
+		// HostKeyCallback verifies the host keys of the target and the jump host
+		HostKeyCallback ssh.HostKeyCallback
+
+		sudoer *sudoer
 	}
 
 	// SSHConfig is the configuration needed to establish SSH connection.
@@ -77,16 +85,28 @@ type (
 		Passphrase string // passphrase of the private key file
 		// Timeout is the maximum amount of time for the TCP connection to establish.
 		Timeout time.Duration
+		// Proxy is the jump host to connect through, the connection to the
+		// target is not proxied if it's nil.
+		Proxy *SSHConfig
+		// KnownHosts verifies the host keys, all host keys are accepted if it's nil.
+		KnownHosts *KnownHosts
+		// AcceptNewHostKey trusts the hosts not in KnownHosts on first use.
+		AcceptNewHostKey bool
+		// SudoPassword is the password of User to run sudo, it's only needed
+		// if the user is not allowed to run sudo without password.
+		SudoPassword string
 	}
 )
 
 var _ TiOpsExecutor = &SSHExecutor{}
 
-// NewSSHExecutor create a ssh executor.
+// NewSSHExecutor create a ssh executor, the connection is kept in a pool
+// owned by the executor, call e.Pool.Close() when it's no longer used.
 func NewSSHExecutor(c SSHConfig, sudo bool) *SSHExecutor {
 	e := new(SSHExecutor)
 	e.Initialize(c)
 	e.Sudo = sudo
+	e.Pool = NewSSHConnPool()
 	return e
 }
 
@@ -116,25 +136,59 @@ func (e *SSHExecutor) Initialize(config SSHConfig) {
 	} else if len(config.Password) > 0 {
 		e.Config.Password = config.Password
 	}
+
+	e.sudoer = newSudoer(config.SudoPassword)
+
+	e.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+	if config.KnownHosts != nil {
+		e.HostKeyCallback = config.KnownHosts.callback(config.AcceptNewHostKey)
+	}
+
+	if proxy := config.Proxy; proxy != nil {
+		if proxy.Port <= 0 {
+			proxy.Port = 22
+		}
+		if proxy.Timeout == 0 {
+			proxy.Timeout = config.Timeout
+		}
+		e.Config.Proxy = easyssh.DefaultConfig{
+			Server:     proxy.Host,
+			Port:       strconv.Itoa(proxy.Port),
+			User:       proxy.User,
+			KeyPath:    proxy.KeyFile,
+			Passphrase: proxy.Passphrase,
+			Password:   proxy.Password,
+			Timeout:    proxy.Timeout,
+		}
+	}
 }
 
 // Execute run the command via SSH, it's not invoking any specific shell by default.
 func (e *SSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	return e.ExecuteWithStdin(cmd, nil, sudo, timeout...)
+}
+
+// ExecuteWithStdin run the command via SSH with the data of stdin sent to it
+func (e *SSHExecutor) ExecuteWithStdin(cmd string, stdin io.Reader, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	if len(timeout) == 0 {
+		timeout = append(timeout, executeDefaultTimeout)
+	}
+
 	// try to acquire root permission
-	if e.Sudo || sudo {
-		cmd = fmt.Sprintf("sudo -H -u root bash -c \"%s\"", cmd)
+	useSudo := e.Sudo || sudo
+	if useSudo {
+		if err := e.sudoer.check(e.Config.User, e.Config.Server, e.runWithoutSudo); err != nil {
+			return nil, nil, err
+		}
+		cmd = e.sudoer.command(cmd, "root", true)
+		stdin = e.sudoer.stdin(stdin)
 	}
 
 	// set a basic PATH in case it's empty on login
 	cmd = fmt.Sprintf("PATH=$PATH:/usr/bin:/usr/sbin %s", cmd)
 
 	// run command on remote host
-	// default timeout is 60s in easyssh-proxy
-	if len(timeout) == 0 {
-		timeout = append(timeout, executeDefaultTimeout)
-	}
-
-	stdout, stderr, done, err := e.Config.Run(cmd, timeout...)
+	stdout, stderr, done, err := e.run(cmd, stdin, timeout[0])
 
 	zap.L().Info("SSHCommand",
 		zap.String("host", e.Config.Server),
@@ -144,7 +198,20 @@ func (e *SSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) (
 		zap.String("stdout", stdout),
 		zap.String("stderr", stderr))
 
+	if !done { // timeout case,
+		return []byte(stdout), []byte(stderr), ErrSSHExecuteTimedout.
+			Wrap(err, "Execute command over SSH timedout for '%s@%s:%s'", e.Config.User, e.Config.Server, e.Config.Port).
+			WithProperty(ErrPropSSHCommand, cmd).
+			WithProperty(ErrPropSSHStdout, stdout).
+			WithProperty(ErrPropSSHStderr, stderr)
+	}
+
 	if err != nil {
+		if useSudo {
+			if sudoErr := sudoError(stderr, e.Config.User, e.Config.Server, err); sudoErr != nil {
+				return []byte(stdout), []byte(stderr), sudoErr
+			}
+		}
 		baseErr := ErrSSHExecuteFailed.
 			Wrap(err, "Failed to execute command over SSH for '%s@%s:%s'", e.Config.User, e.Config.Server, e.Config.Port).
 			WithProperty(ErrPropSSHCommand, cmd).
@@ -160,15 +227,86 @@ func (e *SSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) (
 		return []byte(stdout), []byte(stderr), baseErr
 	}
 
-	if !done { // timeout case,
-		return []byte(stdout), []byte(stderr), ErrSSHExecuteTimedout.
-			Wrap(err, "Execute command over SSH timedout for '%s@%s:%s'", e.Config.User, e.Config.Server, e.Config.Port).
-			WithProperty(ErrPropSSHCommand, cmd).
-			WithProperty(ErrPropSSHStdout, stdout).
-			WithProperty(ErrPropSSHStderr, stderr)
+	return []byte(stdout), []byte(stderr), nil
+}
+
+// run executes the command in a new session over the pooled connection, done
+// is false if the command didn't finish in time.
+func (e *SSHExecutor) run(cmd string, stdin io.Reader, timeout time.Duration) (stdout string, stderr string, done bool, err error) {
+	session, conn, err := e.session()
+	if err != nil {
+		return "", "", true, err
 	}
 
-	return []byte(stdout), []byte(stderr), nil
+	var outBuf, errBuf bytes.Buffer
+	session.Stdin = stdin
+	session.Stdout = &outBuf
+	session.Stderr = &errBuf
+
+	errC := make(chan error, 1)
+	go func() {
+		errC <- session.Run(cmd)
+	}()
+
+	select {
+	case err = <-errC:
+		done = true
+		e.closeSession(session, conn, err)
+	case <-time.After(timeout):
+		// closing the session should make the remote side terminate the command,
+		// if it doesn't respond, the connection is considered broken
+		_ = session.Close()
+		select {
+		case <-errC:
+			e.Pool.release(e.Config, conn, false)
+		case <-time.After(time.Second):
+			_ = conn.close()
+			<-errC
+			e.Pool.release(e.Config, conn, true)
+		}
+	}
+
+	return outBuf.String(), errBuf.String(), done, err
+}
+
+// runWithoutSudo runs the command to check sudo, it returns the stderr
+func (e *SSHExecutor) runWithoutSudo(cmd string, stdin io.Reader) (string, error) {
+	cmd = fmt.Sprintf("PATH=$PATH:/usr/bin:/usr/sbin %s", cmd)
+	_, stderr, done, err := e.run(cmd, stdin, executeDefaultTimeout)
+	if !done {
+		err = ErrSSHExecuteTimedout.New("Execute command over SSH timedout for '%s@%s:%s'", e.Config.User, e.Config.Server, e.Config.Port)
+	}
+	return stderr, err
+}
+
+// session opens a new session over the pooled connection, it retries once with
+// another connection if the pooled one is no longer usable.
+// The session must be closed by closeSession.
+func (e *SSHExecutor) session() (session *ssh.Session, conn *sshConn, err error) {
+	for i := 0; i < 2; i++ {
+		if conn, err = e.Pool.acquire(e.Config, e.HostKeyCallback); err != nil {
+			return nil, nil, err
+		}
+		if session, err = conn.client.NewSession(); err == nil {
+			return session, conn, nil
+		}
+		e.Pool.release(e.Config, conn, true)
+	}
+	return nil, nil, err
+}
+
+// closeSession closes the session and gives the connection back to the pool, the
+// connection is dropped if the session error is not caused by the command itself.
+func (e *SSHExecutor) closeSession(session *ssh.Session, conn *sshConn, err error) {
+	_ = session.Close()
+
+	broken := false
+	switch err.(type) {
+	case nil, *ssh.ExitError, *ssh.ExitMissingError:
+	default:
+		broken = true
+	}
+	e.Pool.release(e.Config, conn, broken)
 }
 
 // Transfer copies files via SCP
@@ -177,27 +315,86 @@ func (e *SSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) (
 // file from remote to local.
 func (e *SSHExecutor) Transfer(src string, dst string, download bool) error {
 	if !download {
-		return e.Config.Scp(src, dst)
+		return e.upload(src, dst, 0644, false)
 	}
 
 	// download file from remote
-	session, client, err := e.Config.Connect()
+	targetPath := filepath.Dir(dst)
+	if err := utils.CreateDir(targetPath); err != nil {
+		return err
+	}
+	targetFile, err := os.Create(dst)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
-	defer session.Close()
+	defer targetFile.Close()
 
-	targetPath := filepath.Dir(dst)
-	if err = utils.CreateDir(targetPath); err != nil {
+	session, conn, err := e.session()
+	if err != nil {
 		return err
 	}
-	targetFile, err := os.Create(dst)
+
+	session.Stdout = targetFile
+
+	err = session.Run(fmt.Sprintf("cat %s", src))
+	e.closeSession(session, conn, err)
+	return err
+}
+
+// UploadWithMode implements TiOpsExecutor interface
+func (e *SSHExecutor) UploadWithMode(src string, dst string, mode os.FileMode) error {
+	return e.upload(src, dst, mode, true)
+}
+
+// upload copies the local file to remote with the scp protocol, the file is
+// created with mode. The existing file is removed first if replace is true,
+// as scp keeps its mode.
+func (e *SSHExecutor) upload(src string, dst string, mode os.FileMode, replace bool) error {
+	srcFile, err := os.Open(src)
 	if err != nil {
 		return err
 	}
+	defer srcFile.Close()
 
-	session.Stdout = targetFile
+	stat, err := srcFile.Stat()
+	if err != nil {
+		return err
+	}
 
-	return session.Run(fmt.Sprintf("cat %s", src))
+	session, conn, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	w, err := session.StdinPipe()
+	if err != nil {
+		e.closeSession(session, conn, nil)
+		return err
+	}
+
+	copyErrC := make(chan error, 1)
+	go func() {
+		defer w.Close()
+		if _, err := fmt.Fprintf(w, "C%04o %d %s\n", mode.Perm(), stat.Size(), filepath.Base(dst)); err != nil {
+			copyErrC <- err
+			return
+		}
+		if _, err := io.Copy(w, srcFile); err != nil {
+			copyErrC <- err
+			return
+		}
+		_, err := fmt.Fprint(w, "\x00")
+		copyErrC <- err
+	}()
+
+	cmd := fmt.Sprintf("scp -tr %s", dst)
+	if replace {
+		cmd = fmt.Sprintf("rm -f %s && %s", dst, cmd)
+	}
+	err = session.Run(cmd)
+	e.closeSession(session, conn, err)
+	if err != nil {
+		return err
+	}
+	return <-copyErrC
 }
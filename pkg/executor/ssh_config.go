@@ -0,0 +1,366 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrSSHConfigParseFailed is ErrSSHConfigParseFailed
+	ErrSSHConfigParseFailed = errNSSSH.NewType("config_parse_failed")
+)
+
+// the OpenSSH client config files, the values in user config take precedence
+var sshConfigFiles = []string{
+	filepath.Join(utils.UserHome(), ".ssh", "config"),
+	"/etc/ssh/ssh_config",
+}
+
+// maxIncludeDepth is the same as the limit of OpenSSH
+const maxIncludeDepth = 16
+
+// sshConfig is the parsed OpenSSH client config, only the options needed to
+// connect are kept, see ssh_config(5) for the format.
+type sshConfig struct {
+	blocks []*sshConfigBlock
+}
+
+// sshConfigBlock is a `Host` section of the config
+type sshConfigBlock struct {
+	patterns []string
+	match    bool        // the section is a `Match` section, which is not supported
+	options  [][2]string // keyword (in lower case) and value
+}
+
+var (
+	loadSSHConfigOnce sync.Once
+	loadedSSHConfig   *sshConfig
+	loadSSHConfigErr  error
+)
+
+// loadSSHConfig reads the OpenSSH client config files of current user, they are
+// only read once.
+func loadSSHConfig() (*sshConfig, error) {
+	loadSSHConfigOnce.Do(func() {
+		loadedSSHConfig = &sshConfig{}
+		for _, file := range sshConfigFiles {
+			if loadSSHConfigErr = loadedSSHConfig.parseFile(file, 0); loadSSHConfigErr != nil {
+				return
+			}
+		}
+	})
+	return loadedSSHConfig, loadSSHConfigErr
+}
+
+func (c *sshConfig) parseFile(file string, depth int) error {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return ErrSSHConfigParseFailed.Wrap(err, "Failed to read SSH config '%s'", file)
+	}
+	defer f.Close()
+
+	return c.parse(f, file, depth)
+}
+
+// parse reads the config from r, the options before any `Host` line are
+// applied to all hosts.
+func (c *sshConfig) parse(r io.Reader, file string, depth int) error {
+	if depth > maxIncludeDepth {
+		return ErrSSHConfigParseFailed.New("Too many nested includes in SSH config '%s'", file)
+	}
+
+	if len(c.blocks) == 0 {
+		c.blocks = append(c.blocks, &sshConfigBlock{patterns: []string{"*"}})
+	}
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		key, value := splitSSHConfigLine(scanner.Text())
+		if key == "" {
+			continue
+		}
+		if value == "" {
+			return ErrSSHConfigParseFailed.New("Missing value of '%s' at %s:%d", key, file, lineNo)
+		}
+
+		switch key {
+		case "host":
+			c.blocks = append(c.blocks, &sshConfigBlock{patterns: strings.Fields(value)})
+		case "match":
+			zap.L().Debug("Match section in SSH config is not supported, ignored", zap.String("file", file), zap.Int("line", lineNo))
+			c.blocks = append(c.blocks, &sshConfigBlock{match: true})
+		case "include":
+			for _, pattern := range strings.Fields(value) {
+				pattern = expandHome(pattern)
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(utils.UserHome(), ".ssh", pattern)
+				}
+				files, err := filepath.Glob(pattern)
+				if err != nil {
+					return ErrSSHConfigParseFailed.Wrap(err, "Invalid include '%s' at %s:%d", pattern, file, lineNo)
+				}
+				for _, f := range files {
+					if err := c.parseFile(f, depth+1); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			block := c.blocks[len(c.blocks)-1]
+			block.options = append(block.options, [2]string{key, value})
+		}
+	}
+
+	return scanner.Err()
+}
+
+// splitSSHConfigLine returns the lower case keyword and the value of the line,
+// the keyword and value can be separated by whitespaces or an optional '='.
+func splitSSHConfigLine(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", ""
+	}
+
+	idx := strings.IndexAny(line, " \t=")
+	if idx < 0 {
+		return strings.ToLower(line), ""
+	}
+	key := strings.ToLower(line[:idx])
+	value := strings.TrimSpace(line[idx:])
+	value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+	return key, strings.Trim(value, "\"")
+}
+
+// matches reports whether the host matches the patterns of the block
+func (b *sshConfigBlock) matches(host string) bool {
+	if b.match {
+		return false
+	}
+
+	matched := false
+	for _, pattern := range b.patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		if ok, _ := path.Match(pattern, host); !ok {
+			continue
+		}
+		if negated {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// get returns the first value of the keyword for host, as in OpenSSH, the
+// first obtained value is used.
+func (c *sshConfig) get(host, key string) string {
+	if values := c.getAll(host, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// getAll returns all the values of the keyword for host in order
+func (c *sshConfig) getAll(host, key string) []string {
+	var values []string
+	for _, block := range c.blocks {
+		if !block.matches(host) {
+			continue
+		}
+		for _, opt := range block.options {
+			if opt[0] == key {
+				values = append(values, opt[1])
+			}
+		}
+	}
+	return values
+}
+
+// resolve fills the SSHConfig with the HostName, User, Port, IdentityFile and
+// ProxyJump of c.Host from the config. Values already set in c are kept, the
+// port 22 is treated as not set as it's the default value of topology.
+func (c *sshConfig) resolve(conf SSHConfig) (SSHConfig, error) {
+	alias := conf.Host
+	conf, err := c.resolveHost(conf)
+	if err != nil {
+		return conf, err
+	}
+
+	if conf.Proxy != nil {
+		return conf, nil
+	}
+	if cmd := c.get(alias, "proxycommand"); cmd != "" && cmd != "none" {
+		zap.L().Warn("ProxyCommand in SSH config is not supported, ignored", zap.String("host", alias))
+	}
+
+	jump := c.get(alias, "proxyjump")
+	if jump == "" || jump == "none" {
+		return conf, nil
+	}
+	if strings.Contains(jump, ",") {
+		return conf, ErrSSHConfigParseFailed.New("ProxyJump '%s' of host '%s' has more than one hop, which is not supported", jump, alias)
+	}
+
+	proxy := SSHConfig{Host: jump, Timeout: conf.Timeout}
+	if idx := strings.LastIndex(jump, "@"); idx >= 0 {
+		proxy.User = jump[:idx]
+		proxy.Host = jump[idx+1:]
+	}
+	if host, port, err := splitHostPort(proxy.Host); err == nil {
+		proxy.Host = host
+		proxy.Port = port
+	}
+
+	// the jump host itself may also be an alias in config
+	if proxy, err = c.resolveHost(proxy); err != nil {
+		return conf, errors.Annotatef(err, "resolve jump host of '%s'", alias)
+	}
+	conf.Proxy = &proxy
+
+	return conf, nil
+}
+
+// DefaultLogin returns the user and the identity file to login the hosts with,
+// the current user and ~/.ssh/id_rsa are used if they're not specified, e.g:
+// by flags. They're left empty for SSHTypeOpenSSH, so that the ones in the
+// OpenSSH client config are used.
+func DefaultLogin(etype SSHType, user, identityFile string) (string, string) {
+	if etype == SSHTypeOpenSSH {
+		return user, identityFile
+	}
+	if user == "" {
+		user = utils.CurrentUser()
+	}
+	if identityFile == "" {
+		identityFile = filepath.Join(utils.UserHome(), ".ssh", "id_rsa")
+	}
+	return user, identityFile
+}
+
+// defaultIdentityFiles are tried if no IdentityFile is set for the host
+var defaultIdentityFiles = []string{"~/.ssh/id_rsa", "~/.ssh/id_ecdsa", "~/.ssh/id_ed25519"}
+
+// resolveHost is the same as resolve but ProxyJump is not resolved
+func (c *sshConfig) resolveHost(conf SSHConfig) (SSHConfig, error) {
+	alias := conf.Host
+
+	if conf.User == "" {
+		conf.User = c.get(alias, "user")
+	}
+	if conf.User == "" {
+		conf.User = utils.CurrentUser()
+	}
+	if hostname := c.get(alias, "hostname"); hostname != "" {
+		conf.Host = expandSSHTokens(hostname, alias, conf.User)
+	}
+	if conf.Port == 0 || conf.Port == 22 {
+		if port := c.get(alias, "port"); port != "" {
+			p, err := strconv.Atoi(port)
+			if err != nil {
+				return conf, ErrSSHConfigParseFailed.Wrap(err, "Invalid port '%s' for host '%s' in SSH config", port, alias)
+			}
+			conf.Port = p
+		}
+	}
+
+	if conf.KeyFile != "" || conf.Password != "" {
+		return conf, nil
+	}
+	files := c.getAll(alias, "identityfile")
+	if len(files) == 0 {
+		files = defaultIdentityFiles
+	}
+	for _, file := range files {
+		file = expandSSHTokens(file, conf.Host, conf.User)
+		if _, err := os.Stat(file); err == nil {
+			conf.KeyFile = file
+			break
+		}
+	}
+
+	return conf, nil
+}
+
+// splitHostPort splits "host:port", the port is required
+func splitHostPort(hostport string) (string, int, error) {
+	idx := strings.LastIndex(hostport, ":")
+	if idx < 0 || strings.HasSuffix(hostport, "]") {
+		return "", 0, errors.Errorf("no port in '%s'", hostport)
+	}
+	port, err := strconv.Atoi(hostport[idx+1:])
+	if err != nil {
+		return "", 0, err
+	}
+	return strings.Trim(hostport[:idx], "[]"), port, nil
+}
+
+func expandHome(p string) string {
+	if p == "~" {
+		return utils.UserHome()
+	}
+	if strings.HasPrefix(p, "~/") {
+		return filepath.Join(utils.UserHome(), p[2:])
+	}
+	return p
+}
+
+// expandSSHTokens expands the '~' and the tokens of ssh_config(5) which are
+// known before connecting: %d, %h, %r, %u and %%
+func expandSSHTokens(s, host, remoteUser string) string {
+	s = expandHome(s)
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'd':
+			b.WriteString(utils.UserHome())
+		case 'h':
+			b.WriteString(host)
+		case 'r':
+			b.WriteString(remoteUser)
+		case 'u':
+			b.WriteString(utils.CurrentUser())
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
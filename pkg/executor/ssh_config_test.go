@@ -0,0 +1,98 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/check"
+)
+
+type sshConfigSuite struct{}
+
+var _ = check.Suite(&sshConfigSuite{})
+
+func (s *sshConfigSuite) TestResolve(c *check.C) {
+	config := `
+# global options
+IdentityFile /nonexistent/global
+
+Host db-*  !db-skip
+    HostName 10.0.1.%h
+    User admin
+    Port=2222
+    ProxyJump jump@bastion:2200
+
+Host bastion
+    HostName 192.168.0.1
+    User ignored
+
+Host *
+    User fallback
+    Port 23
+`
+	conf := &sshConfig{}
+	c.Assert(conf.parse(strings.NewReader(config), "config", 0), check.IsNil)
+
+	// values in config are used if not set
+	resolved, err := conf.resolve(SSHConfig{Host: "db-1", Port: 22})
+	c.Assert(err, check.IsNil)
+	c.Assert(resolved.Host, check.Equals, "10.0.1.db-1")
+	c.Assert(resolved.User, check.Equals, "admin")
+	c.Assert(resolved.Port, check.Equals, 2222)
+	c.Assert(resolved.Proxy, check.NotNil)
+	c.Assert(resolved.Proxy.Host, check.Equals, "192.168.0.1")
+	c.Assert(resolved.Proxy.User, check.Equals, "jump")
+	c.Assert(resolved.Proxy.Port, check.Equals, 2200)
+
+	// values already set are kept, and negated patterns are skipped
+	resolved, err = conf.resolve(SSHConfig{Host: "db-skip", User: "tidb", Port: 9022, KeyFile: "/key"})
+	c.Assert(err, check.IsNil)
+	c.Assert(resolved.Host, check.Equals, "db-skip")
+	c.Assert(resolved.User, check.Equals, "tidb")
+	c.Assert(resolved.Port, check.Equals, 9022)
+	c.Assert(resolved.KeyFile, check.Equals, "/key")
+	c.Assert(resolved.Proxy, check.IsNil)
+
+	// multiple hops are not supported
+	conf = &sshConfig{}
+	c.Assert(conf.parse(strings.NewReader("ProxyJump a,b"), "config", 0), check.IsNil)
+	_, err = conf.resolve(SSHConfig{Host: "db-1"})
+	c.Assert(err, check.NotNil)
+
+	conf = &sshConfig{}
+	c.Assert(conf.parse(strings.NewReader("Host\n"), "config", 0), check.NotNil)
+}
+
+func (s *sshConfigSuite) TestResolveDefaultLogin(c *check.C) {
+	key := filepath.Join(c.MkDir(), "id_ed25519")
+	c.Assert(ioutil.WriteFile(key, []byte("key"), 0600), check.IsNil)
+	conf := &sshConfig{}
+	c.Assert(conf.parse(strings.NewReader("Host db-*\n    User admin\n    IdentityFile "+key+"\n"), "config", 0), check.IsNil)
+
+	// the login user and identity file not given by flags are from the config
+	user, identityFile := DefaultLogin(SSHTypeOpenSSH, "", "")
+	resolved, err := conf.resolve(SSHConfig{Host: "db-1", Port: 22, User: user, KeyFile: identityFile})
+	c.Assert(err, check.IsNil)
+	c.Assert(resolved.User, check.Equals, "admin")
+	c.Assert(resolved.KeyFile, check.Equals, key)
+
+	user, identityFile = DefaultLogin(SSHTypeOpenSSH, "tidb", "/key")
+	resolved, err = conf.resolve(SSHConfig{Host: "db-1", Port: 22, User: user, KeyFile: identityFile})
+	c.Assert(err, check.IsNil)
+	c.Assert(resolved.User, check.Equals, "tidb")
+	c.Assert(resolved.KeyFile, check.Equals, "/key")
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+)
+
+var (
+	// ErrSudoPasswordRequired is ErrSudoPasswordRequired
+	ErrSudoPasswordRequired = errNS.NewType("sudo_password_required")
+	// ErrSudoPasswordIncorrect is ErrSudoPasswordIncorrect
+	ErrSudoPasswordIncorrect = errNS.NewType("sudo_password_incorrect")
+)
+
+// sudoer builds the sudo commands of an executor. If the password is set, sudo
+// is checked once before the first command to see whether the password is
+// needed and correct, then it's sent to sudo as the first line of stdin.
+type sudoer struct {
+	password string
+
+	once         sync.Once
+	needPassword bool  // sudo asks for the password
+	err          error // the password is incorrect
+}
+
+func newSudoer(password string) *sudoer {
+	return &sudoer{password: password}
+}
+
+// check finds out how sudo of user on host behaves with the password, run
+// executes the command without sudo and returns its stderr. It's a no-op if
+// no password is set.
+func (s *sudoer) check(user, host string, run func(cmd string, stdin io.Reader) (string, error)) error {
+	if s == nil || s.password == "" {
+		return nil
+	}
+
+	s.once.Do(func() {
+		// -k makes sudo ignore the cached credentials, so that the password is
+		// always read if it's needed
+		stderr, err := run("LC_ALL=C sudo -n -k true", nil)
+		if err == nil || !strings.Contains(stderr, "a password is required") {
+			return
+		}
+		s.needPassword = true
+		stderr, err = run("LC_ALL=C sudo -S -p '' -k true", strings.NewReader(s.password+"\n"))
+		if err != nil && isSudoPasswordIncorrect(stderr) {
+			s.err = sudoError(stderr, user, host, err)
+		}
+	})
+	return s.err
+}
+
+// command wraps cmd to be run as user, if nonInteractive is true, sudo fails
+// immediately instead of prompting when a password is needed but not given.
+func (s *sudoer) command(cmd, user string, nonInteractive bool) string {
+	switch {
+	case s != nil && s.needPassword:
+		return fmt.Sprintf("sudo -S -p '' -k -H -u %s bash -c %s", user, shellQuote(cmd))
+	case nonInteractive:
+		return fmt.Sprintf("sudo -n -H -u %s bash -c %s", user, shellQuote(cmd))
+	default:
+		return fmt.Sprintf("sudo -H -u %s bash -c %s", user, shellQuote(cmd))
+	}
+}
+
+// stdin returns the stdin of the command built by s.command
+func (s *sudoer) stdin(stdin io.Reader) io.Reader {
+	if s == nil || !s.needPassword {
+		return stdin
+	}
+	password := strings.NewReader(s.password + "\n")
+	if stdin == nil {
+		return password
+	}
+	return io.MultiReader(password, stdin)
+}
+
+// sudoError returns the error of sudo itself found in stderr, or nil if
+// the command is failed for other reasons
+func sudoError(stderr, user, host string, cause error) error {
+	switch {
+	case strings.Contains(stderr, "sudo: a password is required"):
+		return ErrSudoPasswordRequired.
+			Wrap(cause, "User '%s' needs a password to run sudo on '%s'", user, host).
+			WithProperty(cliutil.SuggestionFromTemplate(`
+Please provide the sudo password with {{ColorKeyword}}--sudo-password{{ColorReset}} or
+{{ColorKeyword}}--sudo-password-file{{ColorReset}}, or set it in the environment variable
+{{ColorKeyword}}{{.Env}}{{ColorReset}}. The sudo password of the deploy user is provided with
+{{ColorKeyword}}--deploy-sudo-password{{ColorReset}} or {{ColorKeyword}}--deploy-sudo-password-file{{ColorReset}},
+or the environment variable {{ColorKeyword}}{{.DeployEnv}}{{ColorReset}}.
+`, map[string]string{
+				"Env":       cliutil.EnvNameSudoPassword,
+				"DeployEnv": cliutil.EnvNameDeploySudoPassword,
+			}))
+	case isSudoPasswordIncorrect(stderr):
+		return ErrSudoPasswordIncorrect.
+			Wrap(cause, "Incorrect sudo password of user '%s' on '%s'", user, host).
+			WithProperty(cliutil.SuggestionFromString("Please check the sudo password and try again."))
+	default:
+		return nil
+	}
+}
+
+func isSudoPasswordIncorrect(stderr string) bool {
+	return strings.Contains(stderr, "incorrect password attempt") ||
+		strings.Contains(stderr, "Sorry, try again")
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/check"
+)
+
+type sudoSuite struct{}
+
+var _ = check.Suite(&sudoSuite{})
+
+// fakeSudo behaves like sudo of a user who needs the password to run sudo
+func fakeSudo(password string) func(cmd string, stdin io.Reader) (string, error) {
+	return func(cmd string, stdin io.Reader) (string, error) {
+		if strings.Contains(cmd, "sudo -n") {
+			return "sudo: a password is required\n", errors.New("exit status 1")
+		}
+		input, _ := ioutil.ReadAll(stdin)
+		if string(input) != password+"\n" {
+			return "Sorry, try again.\nsudo: 1 incorrect password attempt\n", errors.New("exit status 1")
+		}
+		return "", nil
+	}
+}
+
+func (s *sudoSuite) TestSudoer(c *check.C) {
+	var sudo *sudoer
+	c.Assert(sudo.check("tidb", "h1", fakeSudo("secret")), check.IsNil)
+	c.Assert(sudo.command("ls", "root", true), check.Equals, "sudo -n -H -u root bash -c 'ls'")
+	c.Assert(sudo.stdin(nil), check.IsNil)
+
+	sudo = newSudoer("secret")
+	c.Assert(sudo.check("tidb", "h1", fakeSudo("secret")), check.IsNil)
+	c.Assert(sudo.command("ls", "root", true), check.Equals, "sudo -S -p '' -k -H -u root bash -c 'ls'")
+	input, err := ioutil.ReadAll(sudo.stdin(strings.NewReader("data")))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(input), check.Equals, "secret\ndata")
+
+	sudo = newSudoer("wrong")
+	err = sudo.check("tidb", "h1", fakeSudo("secret"))
+	c.Assert(errorx.IsOfType(err, ErrSudoPasswordIncorrect), check.IsTrue)
+	// the password is only checked once
+	c.Assert(sudo.check("tidb", "h1", nil), check.Equals, err)
+
+	err = sudoError("sudo: a password is required\n", "tidb", "h1", errors.New("exit status 1"))
+	c.Assert(errorx.IsOfType(err, ErrSudoPasswordRequired), check.IsTrue)
+	c.Assert(sudoError("ls: cannot access", "tidb", "h1", errors.New("exit status 2")), check.IsNil)
+}
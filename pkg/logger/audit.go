@@ -30,10 +30,16 @@ import (
 
 var auditEnabled atomic.Bool
 var auditBuffer *bytes.Buffer
+var auditID string
 
-// EnableAuditLog enables audit log.
+// EnableAuditLog enables audit log. The ID of the audit log is generated from
+// the current time, and recorded in the revisions of meta saved.
 func EnableAuditLog() {
 	auditEnabled.Store(true)
+	if auditID == "" {
+		auditID = base52.Encode(time.Now().Unix())
+		meta.SetAuditID(auditID)
+	}
 }
 
 // DisableAuditLog disables audit log.
@@ -56,7 +62,7 @@ func OutputAuditLogIfEnabled() {
 	if err := utils.CreateDir(auditDir); err != nil {
 		zap.L().Warn("Create audit directory failed", zap.Error(err))
 	} else {
-		auditFilePath := meta.ProfilePath(meta.TiOpsAuditDir, base52.Encode(time.Now().Unix()))
+		auditFilePath := meta.ProfilePath(meta.TiOpsAuditDir, auditID)
 		err := ioutil.WriteFile(auditFilePath, auditBuffer.Bytes(), os.ModePerm)
 		if err != nil {
 			zap.L().Warn("Write audit log file failed", zap.Error(err))
@@ -14,6 +14,7 @@
 package meta
 
 import (
+	"crypto/tls"
 	"fmt"
 	"path/filepath"
 
@@ -48,9 +49,9 @@ func (c *CDCComponent) Instances() []Instance {
 			usedDirs: []string{
 				s.DeployDir,
 			},
-			statusFn: func(_ ...string) string {
-				url := fmt.Sprintf("http://%s:%d/status", s.Host, s.Port)
-				return statusByURL(url)
+			statusFn: func(tlsCfg *tls.Config, _ ...string) string {
+				url := fmt.Sprintf("%s://%s:%d/status", scheme(tlsCfg), s.Host, s.Port)
+				return statusByURL(url, tlsCfg)
 			},
 		}})
 	}
@@ -84,7 +85,7 @@ func (i *CDCInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clusterV
 		i.GetHost(),
 		paths.Deploy,
 		paths.Log,
-	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).AppendEndpoints(i.instance.topo.Endpoints(deployUser)...)
+	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).WithTLS(i.topo.GlobalOptions.TLSEnabled).AppendEndpoints(i.instance.topo.Endpoints(deployUser)...)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_cdc_%s_%d.sh", i.GetHost(), i.GetPort()))
 
@@ -15,11 +15,9 @@ package meta
 
 import (
 	"io/ioutil"
-	"os"
 
 	"github.com/joomcode/errorx"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
-	"github.com/pingcap-incubator/tiup-cluster/pkg/file"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/version"
 	"github.com/pingcap/errors"
@@ -31,8 +29,8 @@ const (
 	MetaFileName = "meta.yaml"
 	// PatchDirName is the directory to store patch file eg. {PatchDirName}/tidb-hotfix.tar.gz
 	PatchDirName = "patch"
-	// BackupDirName is the directory to save backup files.
-	BackupDirName = "backup"
+	// CheckpointDirName is the directory to save the tasks done by operations eg. {CheckpointDirName}/deploy
+	CheckpointDirName = "checkpoint"
 )
 
 var (
@@ -45,11 +43,9 @@ var (
 
 // ClusterMeta is the specification of generic cluster metadata
 type ClusterMeta struct {
-	User    string `yaml:"user"`         // the user to run and manage cluster on remote
-	Version string `yaml:"tidb_version"` // the version of TiDB cluster
-	//EnableTLS      bool   `yaml:"enable_tls"`
-	//EnableFirewall bool   `yaml:"firewall"`
-	OpsVer string `yaml:"last_ops_ver,omitempty"` // the version of ourself that updated the meta last time
+	User    string `yaml:"user"`                   // the user to run and manage cluster on remote
+	Version string `yaml:"tidb_version"`           // the version of TiDB cluster
+	OpsVer  string `yaml:"last_ops_ver,omitempty"` // the version of ourself that updated the meta last time
 
 	Topology *TopologySpecification `yaml:"topology"`
 }
@@ -71,7 +67,6 @@ func SaveClusterMeta(clusterName string, meta *ClusterMeta) error {
 	}
 
 	metaFile := ClusterPath(clusterName, MetaFileName)
-	backupDir := ClusterPath(clusterName, BackupDirName)
 
 	// set the cmd version
 	meta.OpsVer = version.NewTiOpsVersion().FullInfo()
@@ -80,17 +75,17 @@ func SaveClusterMeta(clusterName string, meta *ClusterMeta) error {
 		return wrapError(err)
 	}
 
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
-		return wrapError(err)
-	}
-
 	data, err := yaml.Marshal(meta)
 	if err != nil {
 		return wrapError(err)
 	}
 
-	err = file.SaveFileWithBackup(metaFile, data, backupDir)
-	if err != nil {
+	// the meta is kept as a revision before overwriting the current one, so
+	// that it can be restored
+	if err := saveMetaRevision(clusterName, data); err != nil {
+		return wrapError(err)
+	}
+	if err := ioutil.WriteFile(metaFile, data, 0644); err != nil {
 		return wrapError(err)
 	}
 
@@ -26,7 +26,6 @@ import (
 type DMMeta struct {
 	User    string `yaml:"user"`       // the user to run and manage cluster on remote
 	Version string `yaml:"dm_version"` // the version of TiDB cluster
-	//EnableTLS      bool   `yaml:"enable_tls"`
 	//EnableFirewall bool   `yaml:"firewall"`
 
 	Topology *DMTopologySpecification `yaml:"topology"`
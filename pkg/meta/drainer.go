@@ -14,6 +14,7 @@
 package meta
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
@@ -51,9 +52,9 @@ func (c *DrainerComponent) Instances() []Instance {
 				s.DeployDir,
 				s.DataDir,
 			},
-			statusFn: func(_ ...string) string {
-				url := fmt.Sprintf("http://%s:%d/status", s.Host, s.Port)
-				return statusByURL(url)
+			statusFn: func(tlsCfg *tls.Config, _ ...string) string {
+				url := fmt.Sprintf("%s://%s:%d/status", scheme(tlsCfg), s.Host, s.Port)
+				return statusByURL(url, tlsCfg)
 			},
 		}})
 	}
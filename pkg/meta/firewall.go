@@ -0,0 +1,135 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/module"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+)
+
+// FirewallOptions represents the options of managing the firewalls of hosts
+type FirewallOptions struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// only accept the connections from the hosts of cluster and ExtraSources
+	RestrictSources bool     `yaml:"restrict_sources,omitempty"`
+	ExtraSources    []string `yaml:"extra_sources,omitempty"`
+}
+
+// FirewallRules returns the rules of the host firewalls needed by the cluster
+// without the excluded instances, it's empty if the firewalls are not managed.
+// The ports used by the instances and the monitoring agents of each host are
+// opened, to the hosts of cluster and the extra sources if restricted.
+func (topo *TopologySpecification) FirewallRules(excluded ...string) map[string][]module.FirewallRule {
+	opt := topo.GlobalOptions.Firewall
+	if !opt.Enabled {
+		return nil
+	}
+
+	excludedIDs := set.NewStringSet(excluded...)
+	var hosts []string
+	ports := make(map[string]map[int]struct{}) // host -> ports
+	topo.IterInstance(func(inst Instance) {
+		if excludedIDs.Exist(inst.ID()) {
+			return
+		}
+		host := inst.GetHost()
+		if _, ok := ports[host]; !ok {
+			hosts = append(hosts, host)
+			ports[host] = map[int]struct{}{
+				topo.MonitoredOptions.NodeExporterPort:     {},
+				topo.MonitoredOptions.BlackboxExporterPort: {},
+			}
+		}
+		for _, port := range inst.UsedPorts() {
+			ports[host][port] = struct{}{}
+		}
+	})
+
+	sources := []string{""}
+	if opt.RestrictSources {
+		sources = append(append([]string{}, hosts...), opt.ExtraSources...)
+	}
+
+	rules := make(map[string][]module.FirewallRule)
+	for host, hostPorts := range ports {
+		for port := range hostPorts {
+			for _, source := range sources {
+				rules[host] = append(rules[host], module.FirewallRule{Port: port, Source: source})
+			}
+		}
+		sortFirewallRules(rules[host])
+	}
+	return rules
+}
+
+// DiffFirewallRules returns the rules to be added to and removed from each host
+// to change the firewalls from the old rules to the new ones
+func DiffFirewallRules(old, new map[string][]module.FirewallRule) (allow, remove map[string][]module.FirewallRule) {
+	diff := func(a, b map[string][]module.FirewallRule) map[string][]module.FirewallRule {
+		result := make(map[string][]module.FirewallRule)
+		for host, rules := range a {
+			existing := make(map[module.FirewallRule]struct{})
+			for _, r := range b[host] {
+				existing[r] = struct{}{}
+			}
+			for _, r := range rules {
+				if _, ok := existing[r]; !ok {
+					result[host] = append(result[host], r)
+				}
+			}
+		}
+		return result
+	}
+	return diff(new, old), diff(old, new)
+}
+
+func sortFirewallRules(rules []module.FirewallRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Port != rules[j].Port {
+			return rules[i].Port < rules[j].Port
+		}
+		return rules[i].Source < rules[j].Source
+	})
+}
+
+// firewallSourcesDetect checks the sources of the firewall rules are valid,
+// the hosts must be IP addresses if the sources are restricted
+func (topo *TopologySpecification) firewallSourcesDetect(errs *topologyErrors) {
+	opt := topo.GlobalOptions.Firewall
+	if !opt.Enabled || !opt.RestrictSources {
+		return
+	}
+
+	reported := set.NewStringSet()
+	topo.IterInstance(func(inst Instance) {
+		host := inst.GetHost()
+		if net.ParseIP(host) == nil && !reported.Exist(host) {
+			reported.Insert(host)
+			errs.add("global.firewall.restrict_sources",
+				"the hosts must be IP addresses to restrict the sources of firewall, but '%s' is not", host)
+		}
+	})
+	for i, source := range opt.ExtraSources {
+		if net.ParseIP(source) == nil {
+			if _, _, err := net.ParseCIDR(source); err != nil {
+				errs.add(fmt.Sprintf("global.firewall.extra_sources.%d", i),
+					"'%s' in `extra_sources` of firewall is neither an IP address nor a CIDR", source)
+			}
+		}
+	}
+}
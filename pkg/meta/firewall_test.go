@@ -0,0 +1,63 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"github.com/pingcap-incubator/tiup-cluster/pkg/module"
+	. "github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+func (s *metaSuite) TestFirewallRules(c *C) {
+	topo := TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  firewall:
+    enabled: true
+pd_servers:
+  - host: 172.16.5.140
+tidb_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.Validate(), IsNil)
+
+	rules := topo.FirewallRules()
+	c.Assert(rules["172.16.5.140"], DeepEquals, []module.FirewallRule{
+		{Port: 2379}, {Port: 2380}, {Port: 4000}, {Port: 9100}, {Port: 9115}, {Port: 10080},
+	})
+	c.Assert(rules["172.16.5.141"], DeepEquals, []module.FirewallRule{
+		{Port: 4000}, {Port: 9100}, {Port: 9115}, {Port: 10080},
+	})
+
+	// the ports of the monitoring agents are closed with the last instance
+	allow, remove := DiffFirewallRules(rules, topo.FirewallRules("172.16.5.141:4000", "172.16.5.140:2379"))
+	c.Assert(allow, HasLen, 0)
+	c.Assert(remove["172.16.5.140"], DeepEquals, []module.FirewallRule{{Port: 2379}, {Port: 2380}})
+	c.Assert(remove["172.16.5.141"], HasLen, 4)
+
+	topo.GlobalOptions.Firewall.RestrictSources = true
+	topo.GlobalOptions.Firewall.ExtraSources = []string{"10.0.1.0/24"}
+	c.Assert(topo.Validate(), IsNil)
+	allow, remove = DiffFirewallRules(rules, topo.FirewallRules("172.16.5.141:4000"))
+	c.Assert(allow["172.16.5.140"], HasLen, 12)
+	c.Assert(allow["172.16.5.140"][:2], DeepEquals, []module.FirewallRule{
+		{Port: 2379, Source: "10.0.1.0/24"}, {Port: 2379, Source: "172.16.5.140"},
+	})
+	c.Assert(remove["172.16.5.140"], HasLen, 6)
+
+	topo.GlobalOptions.Firewall.ExtraSources = []string{"control-machine"}
+	c.Assert(topo.Validate(), NotNil)
+}
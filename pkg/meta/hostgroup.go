@@ -0,0 +1,277 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+type (
+	// HostGroup represents the settings shared by the instances of the group,
+	// the settings of an instance take precedence over the ones of its group
+	HostGroup struct {
+		SSHPort         int               `yaml:"ssh_port,omitempty"`
+		SSHProxy        *SSHProxy         `yaml:"ssh_proxy,omitempty"`
+		DeployDir       string            `yaml:"deploy_dir,omitempty"`
+		DataDir         string            `yaml:"data_dir,omitempty"`
+		LogDir          string            `yaml:"log_dir,omitempty"`
+		NumaNode        string            `yaml:"numa_node,omitempty"`
+		ResourceControl ResourceControl   `yaml:"resource_control,omitempty"`
+		Labels          map[string]string `yaml:"labels,omitempty"`
+		// the hosts in the group and their variables, which can be used in the
+		// dirs and configs of instances, e.g: {{ .Host.disk }}
+		Hosts map[string]map[string]interface{} `yaml:"hosts,omitempty"`
+	}
+
+	// HostGroups represents the host groups by their names
+	HostGroups map[string]HostGroup
+)
+
+// the fields of instances in which the variables of hosts are rendered
+var (
+	hostVarDirFields    = []string{"DeployDir", "DataDir", "LogDir", "TmpDir"}
+	hostVarConfigFields = []string{"Config", "LearnerConfig"}
+)
+
+// the config of the labels of stores, by the config field of the instance
+var labelsConfigKeys = map[string]map[string]string{
+	ComponentTiKV:    {"Config": "server.labels"},
+	ComponentTiFlash: {"LearnerConfig": "server.labels"},
+}
+
+// merge returns the host groups of both, the groups of g take precedence if
+// they have the same name
+func (g HostGroups) merge(that HostGroups) HostGroups {
+	if len(g) == 0 && len(that) == 0 {
+		return nil
+	}
+	groups := make(HostGroups, len(g)+len(that))
+	for name, group := range that {
+		groups[name] = group
+	}
+	for name, group := range g {
+		groups[name] = group
+	}
+	return groups
+}
+
+// Clone returns a copy of the host groups, so that they are not changed when
+// the copy is decoded into
+func (g HostGroups) Clone() HostGroups {
+	return g.merge(nil)
+}
+
+// resolveHostGroups returns a copy of the topology whose instances have the
+// settings of their host groups and the variables of their hosts rendered,
+// the problems found are added to errs.
+func (topo *TopologySpecification) resolveHostGroups(errs *topologyErrors) *TopologySpecification {
+	return topo.HostGroups.resolve(topo, errs).(*TopologySpecification)
+}
+
+// resolveHostGroups returns a copy of the topology whose instances have the
+// settings of their host groups and the variables of their hosts rendered,
+// the problems found are added to errs.
+func (topo *DMTopologySpecification) resolveHostGroups(errs *topologyErrors) *DMTopologySpecification {
+	return topo.HostGroups.resolve(topo, errs).(*DMTopologySpecification)
+}
+
+// resolve returns a copy of topo, a pointer to a topology specification, with
+// the instances resolved by the host groups
+func (g HostGroups) resolve(topo interface{}, errs *topologyErrors) interface{} {
+	resolved := reflect.New(reflect.TypeOf(topo).Elem())
+	resolved.Elem().Set(reflect.ValueOf(topo).Elem())
+	v := resolved.Elem()
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if isSkipField(field) {
+			continue
+		}
+
+		// the instances are copied to leave the original topology untouched
+		compSpecs := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+		reflect.Copy(compSpecs, field)
+		for index := 0; index < compSpecs.Len(); index++ {
+			path := fmt.Sprintf("%s.%d", yamlKey(t.Field(i).Tag.Get("yaml")), index)
+			g.resolveInstance(errs, path, compSpecs.Index(index))
+		}
+		field.Set(compSpecs)
+	}
+	return resolved.Interface()
+}
+
+func (g HostGroups) resolveInstance(errs *topologyErrors, path string, inst reflect.Value) {
+	var vars map[string]interface{}
+	if name := inst.FieldByName("Group").String(); name != "" {
+		group, ok := g[name]
+		if !ok {
+			errs.add(path+".group", "host group '%s' of %s is not defined in host_groups", name, path)
+			return
+		}
+		host := inst.FieldByName("Host").String()
+		if len(group.Hosts) > 0 {
+			if vars, ok = group.Hosts[host]; !ok {
+				errs.add(path+".host", "host '%s' of %s is not in host group '%s'", host, path, name)
+				return
+			}
+		}
+		group.applyTo(inst)
+	}
+
+	data := map[string]interface{}{"Host": vars}
+	for _, name := range hostVarDirFields {
+		field := inst.FieldByName(name)
+		if !field.IsValid() {
+			continue
+		}
+		key := yamlKey(fieldTag(inst, name))
+		val, err := renderHostVars(key, field.String(), data)
+		if err != nil {
+			errs.add(path+"."+key, "failed to render %s of %s: %s", key, path, err)
+			continue
+		}
+		field.SetString(val)
+	}
+	for _, name := range hostVarConfigFields {
+		field := inst.FieldByName(name)
+		if !field.IsValid() || field.IsNil() {
+			continue
+		}
+		key := yamlKey(fieldTag(inst, name))
+		val, err := renderConfigHostVars(key, field.Interface(), data)
+		if err != nil {
+			errs.add(path+"."+key, "failed to render %s of %s: %s", key, path, err)
+			continue
+		}
+		field.Set(reflect.ValueOf(val))
+	}
+}
+
+// applyTo sets the settings of the group to the instance if they are not set
+// by the instance. The dirs of the group are used in the same way as the ones
+// of the global options.
+func (g HostGroup) applyTo(inst reflect.Value) {
+	spec := inst.Interface().(InstanceSpec)
+	if field := inst.FieldByName("SSHPort"); field.Int() == 0 {
+		field.SetInt(int64(g.SSHPort))
+	}
+	if field := inst.FieldByName("SSHProxy"); field.IsNil() && g.SSHProxy != nil {
+		proxy := *g.SSHProxy
+		field.Set(reflect.ValueOf(&proxy))
+	}
+	if field := inst.FieldByName("DeployDir"); field.String() == "" && g.DeployDir != "" {
+		field.SetString(filepath.Join(g.DeployDir, fmt.Sprintf("%s-%s", spec.Role(), getPort(inst))))
+	}
+	if field := inst.FieldByName("DataDir"); field.IsValid() && field.String() == "" && g.DataDir != "" {
+		if strings.HasPrefix(g.DataDir, "/") {
+			field.SetString(filepath.Join(g.DataDir, fmt.Sprintf("%s-%s", spec.Role(), getPort(inst))))
+		} else {
+			field.SetString(g.DataDir)
+		}
+	}
+	if field := inst.FieldByName("LogDir"); field.IsValid() && field.String() == "" {
+		field.SetString(g.LogDir)
+	}
+	if field := inst.FieldByName("NumaNode"); field.IsValid() && field.String() == "" {
+		field.SetString(g.NumaNode)
+	}
+	if field := inst.FieldByName("ResourceControl"); field.IsValid() {
+		// the resource control of the instance is merged over the one of the group
+		field.Set(reflect.ValueOf(MergeResourceControl(g.ResourceControl, field.Interface().(ResourceControl))))
+	}
+
+	if len(g.Labels) == 0 {
+		return
+	}
+	for name, key := range labelsConfigKeys[spec.Role()] {
+		labels := make(map[string]interface{}, len(g.Labels))
+		for k, v := range g.Labels {
+			labels[k] = v
+		}
+		field := inst.FieldByName(name)
+		config, _ := field.Interface().(map[string]interface{})
+		// the labels set by the config of the instance take precedence
+		merged, err := merge(map[string]interface{}{key: labels}, config)
+		if err != nil {
+			continue
+		}
+		field.Set(reflect.ValueOf(merged))
+	}
+}
+
+func fieldTag(v reflect.Value, name string) string {
+	f, _ := v.Type().FieldByName(name)
+	return f.Tag.Get("yaml")
+}
+
+// renderHostVars renders the variables of the host in s, e.g: {{ .Host.disk }}
+func renderHostVars(name, s string, data interface{}) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// renderConfigHostVars renders the variables of the host in all the strings
+// of the config, the config is copied instead of being modified
+func renderConfigHostVars(name string, val interface{}, data interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case string:
+		return renderHostVars(name, v, data)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			r, err := renderConfigHostVars(name, e, data)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = r
+		}
+		return m, nil
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for k, e := range v {
+			r, err := renderConfigHostVars(name, e, data)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = r
+		}
+		return m, nil
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			r, err := renderConfigHostVars(name, e, data)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = r
+		}
+		return s, nil
+	default:
+		return val, nil
+	}
+}
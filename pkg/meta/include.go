@@ -0,0 +1,350 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+// the key of the files included by a topology file
+const includeKey = "include"
+
+// TopologyFileError is an error of parsing a topology file, Line is 0 if the
+// line is unknown or already in the message of Err
+type TopologyFileError struct {
+	File string
+	Line int
+	Err  error
+}
+
+// Error implements the error interface
+func (e *TopologyFileError) Error() string {
+	return e.Err.Error()
+}
+
+// Location returns the file and the line of the error, e.g: topo.yaml:3
+func (e *TopologyFileError) Location() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d", e.File, e.Line)
+	}
+	return e.File
+}
+
+// TopologyFiles are a topology file and the files included by it recursively
+// with `include`, in the order of merging: a file comes before the files it
+// includes, which are merged in order.
+type TopologyFiles struct {
+	files []*topologyFile
+}
+
+type topologyFile struct {
+	path     string
+	data     []byte
+	sections map[string]interface{} // the top level sections of the file
+	includes []string
+
+	// the index of the first instance of the file in the merged topology, and
+	// the number of instances, by the keys of the sections
+	first map[string]int
+	count map[string]int
+}
+
+// ReadTopologyFiles reads the topology file and the files included by it
+func ReadTopologyFiles(file string) (*TopologyFiles, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, utils.ErrTopologyReadFailed.
+			Wrap(err, "Failed to read topology file %s", file).
+			WithProperty(cliutil.SuggestionFromTemplate(`
+Please check whether your topology file {{ColorKeyword}}{{.File}}{{ColorReset}} exists and try again.
+
+To generate a sample topology file:
+  {{ColorCommand}}{{OsArgs0}} template topology > topo.yaml{{ColorReset}}
+`, map[string]string{
+				"File": file,
+			}))
+	}
+	return NewTopologyFiles(file, data)
+}
+
+// NewTopologyFiles returns the topology file of the data and the files included
+// by it, the relative paths of the included files are relative to the directory
+// of the file including them. Glob patterns are supported, e.g: dc/*.yaml
+func NewTopologyFiles(file string, data []byte) (*TopologyFiles, error) {
+	fs := &TopologyFiles{}
+	if err := fs.add(file, data); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+func (fs *TopologyFiles) add(file string, data []byte) error {
+	for _, f := range fs.files {
+		if f.path == file {
+			return &TopologyFileError{File: file, Err: errors.Errorf("topology file %s is included more than once", file)}
+		}
+	}
+
+	f := &topologyFile{path: file, data: data}
+	if err := yaml.Unmarshal(data, &f.sections); err != nil {
+		return &TopologyFileError{File: file, Err: err}
+	}
+	var includes struct {
+		Include []string `yaml:"include"`
+	}
+	if err := yaml.Unmarshal(data, &includes); err != nil {
+		return &TopologyFileError{File: file, Err: err}
+	}
+	for _, pattern := range includes.Include {
+		path := pattern
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+		if !strings.ContainsAny(pattern, "*?[") {
+			f.includes = append(f.includes, path)
+			continue
+		}
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return &TopologyFileError{File: file, Line: utils.YamlLine(data, includeKey), Err: err}
+		}
+		if len(matches) == 0 {
+			return &TopologyFileError{
+				File: file,
+				Line: utils.YamlLine(data, includeKey),
+				Err:  errors.Errorf("no topology file matches %s", pattern),
+			}
+		}
+		f.includes = append(f.includes, matches...)
+	}
+	fs.files = append(fs.files, f)
+
+	for _, path := range f.includes {
+		included, err := ioutil.ReadFile(path)
+		if err != nil {
+			return &TopologyFileError{File: file, Line: utils.YamlLine(data, includeKey), Err: err}
+		}
+		if err := fs.add(path, included); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unmarshal merges the topology files into out, a pointer to a topology
+// specification, e.g: *TopologySpecification. The instances are merged with
+// the Merge method of the topology, and the other sections can only be set by
+// one file, except the host groups with different names. The errors of parsing
+// are *TopologyFileError, and the errors of validation are *TopologyError,
+// which can be located by Locate.
+func (fs *TopologyFiles) Unmarshal(out interface{}) error {
+	root := fs.files[0]
+	if len(fs.files) == 1 {
+		if _, ok := root.sections[includeKey]; !ok {
+			return fileError(root, yaml.UnmarshalStrict(root.data, out))
+		}
+	}
+
+	v := reflect.ValueOf(out).Elem()
+	t := v.Type()
+	// the files are parsed without the default values and validation, which
+	// are done after they are merged
+	fields := []reflect.StructField{{
+		Name: "Include",
+		Type: reflect.TypeOf([]string{}),
+		Tag:  reflect.StructTag(fmt.Sprintf(`yaml:"%s,omitempty"`, includeKey)),
+	}}
+	for i := 0; i < t.NumField(); i++ {
+		fields = append(fields, t.Field(i))
+	}
+	rawType := reflect.StructOf(fields)
+
+	var merged reflect.Value
+	for index, f := range fs.files {
+		raw := reflect.New(rawType).Elem()
+		if index == 0 {
+			// keep the values set before, e.g: the global options of scale-out
+			for i := 0; i < t.NumField(); i++ {
+				raw.Field(i + 1).Set(v.Field(i))
+			}
+		}
+		if err := yaml.UnmarshalStrict(f.data, raw.Addr().Interface()); err != nil {
+			return &TopologyFileError{File: f.path, Err: err}
+		}
+		topo := reflect.New(t)
+		for i := 0; i < t.NumField(); i++ {
+			topo.Elem().Field(i).Set(raw.Field(i + 1))
+		}
+
+		f.first = make(map[string]int)
+		f.count = make(map[string]int)
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).Type.Kind() != reflect.Slice {
+				continue
+			}
+			key := yamlKey(t.Field(i).Tag.Get("yaml"))
+			if merged.IsValid() {
+				f.first[key] = merged.Elem().Field(i).Len()
+			}
+			f.count[key] = topo.Elem().Field(i).Len()
+		}
+
+		if index == 0 {
+			merged = topo
+			continue
+		}
+		if err := fs.mergeSections(index, merged.Elem(), topo.Elem()); err != nil {
+			return err
+		}
+		merged = merged.MethodByName("Merge").Call([]reflect.Value{topo})[0]
+	}
+
+	data, err := yaml.Marshal(merged.Interface())
+	if err != nil {
+		return fileError(root, err)
+	}
+	zap.L().Debug("Merge topology files", zap.String("file", root.path), zap.ByteString("topology", data))
+	return fileError(root, yaml.UnmarshalStrict(data, out))
+}
+
+// mergeSections sets the sections other than the instances of the file at
+// the index to the merged topology, the conflicts with the former files are
+// returned as errors.
+func (fs *TopologyFiles) mergeSections(index int, merged, topo reflect.Value) error {
+	f := fs.files[index]
+	t := topo.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := yamlKey(t.Field(i).Tag.Get("yaml"))
+		if _, ok := f.sections[key]; !ok || t.Field(i).Type.Kind() == reflect.Slice {
+			continue
+		}
+
+		for _, former := range fs.files[:index] {
+			section, ok := former.sections[key]
+			if !ok {
+				continue
+			}
+			// the maps, e.g: host groups, are merged by the Merge method
+			if t.Field(i).Type.Kind() != reflect.Map {
+				return sectionConflict(f, former, key)
+			}
+			names, _ := section.(map[interface{}]interface{})
+			for _, name := range topo.Field(i).MapKeys() {
+				if _, ok := names[name.Interface()]; ok {
+					return sectionConflict(f, former, fmt.Sprintf("%s.%v", key, name.Interface()))
+				}
+			}
+		}
+		if t.Field(i).Type.Kind() != reflect.Map {
+			merged.Field(i).Set(topo.Field(i))
+		}
+	}
+	return nil
+}
+
+func sectionConflict(f, former *topologyFile, path string) error {
+	return &TopologyFileError{
+		File: f.path,
+		Line: utils.YamlLine(f.data, path),
+		Err: errors.Errorf("`%s` conflicts with the one in %s:%d",
+			path, former.path, utils.YamlLine(former.data, path)),
+	}
+}
+
+// fileError returns the error of the file as a *TopologyFileError, except the
+// errors of validation
+func fileError(f *topologyFile, err error) error {
+	switch err.(type) {
+	case nil, *TopologyError:
+		return err
+	default:
+		return &TopologyFileError{File: f.path, Err: err}
+	}
+}
+
+// Locate returns the file and the line of the path in the merged topology,
+// e.g: tikv_servers.3.port
+func (fs *TopologyFiles) Locate(path string) (string, int) {
+	keys := strings.Split(path, ".")
+	if len(keys) > 1 {
+		if index, err := strconv.Atoi(keys[1]); err == nil {
+			for _, f := range fs.files {
+				first, count := f.first[keys[0]], f.count[keys[0]]
+				if index >= first && index < first+count {
+					keys[1] = strconv.Itoa(index - first)
+					return f.path, utils.YamlLine(f.data, strings.Join(keys, "."))
+				}
+			}
+		}
+	}
+
+	located := fs.files[0]
+	for _, f := range fs.files {
+		section, ok := f.sections[keys[0]]
+		if !ok {
+			continue
+		}
+		if names, ok := section.(map[interface{}]interface{}); ok && len(keys) > 1 {
+			if _, ok := names[keys[1]]; !ok {
+				continue
+			}
+		}
+		located = f
+		break
+	}
+	return located.path, utils.YamlLine(located.data, path)
+}
+
+// ParseTopologyYaml reads the topology file and the files included by it, and
+// merges them into out, a pointer to a topology specification
+func ParseTopologyYaml(file string, out interface{}) error {
+	zap.L().Debug("Parse topology file", zap.String("file", file))
+
+	fs, err := ReadTopologyFiles(file)
+	if err == nil {
+		err = fs.Unmarshal(out)
+	}
+
+	location := file
+	switch e := err.(type) {
+	case nil:
+		zap.L().Debug("Parse topology file succeeded", zap.Any("topology", out))
+		return nil
+	case *TopologyFileError:
+		location = e.Location()
+	case *TopologyError:
+		f, line := fs.Locate(e.Path)
+		location = fmt.Sprintf("%s:%d", f, line)
+	default:
+		return err
+	}
+	return utils.ErrTopologyParseFailed.
+		Wrap(err, "Failed to parse topology file %s", location).
+		WithProperty(cliutil.SuggestionFromTemplate(`
+Please check the syntax of your topology file {{ColorKeyword}}{{.File}}{{ColorReset}} and try again.
+`, map[string]string{
+			"File": location,
+		}))
+}
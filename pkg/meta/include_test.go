@@ -0,0 +1,109 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+)
+
+func writeTopologyFiles(c *C, files map[string]string) string {
+	dir := c.MkDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+		c.Assert(ioutil.WriteFile(path, []byte(content), 0644), IsNil)
+	}
+	return dir
+}
+
+func (s *metaSuite) TestIncludeTopologyFiles(c *C) {
+	dir := writeTopologyFiles(c, map[string]string{
+		"topo.yaml": `
+include:
+  - configs.yaml
+  - dc/*.yaml
+global:
+  deploy_dir: /tidb-deploy
+pd_servers:
+  - host: 172.16.5.140
+`,
+		"configs.yaml": `
+server_configs:
+  tikv:
+    storage.reserve-space: 0
+host_groups:
+  ssd:
+    data_dir: /ssd
+`,
+		"dc/dc1.yaml": `
+host_groups:
+  hdd:
+    data_dir: /hdd
+tikv_servers:
+  - host: 172.16.5.141
+    group: ssd
+`,
+		"dc/dc2.yaml": `
+tikv_servers:
+  - host: 172.16.5.142
+    group: hdd
+`,
+	})
+
+	topo := TopologySpecification{}
+	c.Assert(ParseTopologyYaml(filepath.Join(dir, "topo.yaml"), &topo), IsNil)
+	c.Assert(topo.PDServers, HasLen, 1)
+	c.Assert(topo.TiKVServers, HasLen, 2)
+	c.Assert(topo.TiKVServers[0].DeployDir, Equals, "/tidb-deploy/tikv-20160")
+	c.Assert(topo.TiKVServers[0].DataDir, Equals, "/ssd/tikv-20160")
+	c.Assert(topo.TiKVServers[1].DataDir, Equals, "/hdd/tikv-20160")
+	c.Assert(topo.ServerConfigs.TiKV, DeepEquals, map[string]interface{}{"storage.reserve-space": 0})
+	c.Assert(topo.HostGroups, HasLen, 2)
+
+	// the sections other than the instances can't be set by multiple files
+	dir = writeTopologyFiles(c, map[string]string{
+		"topo.yaml": "include: [dc1.yaml]\nglobal:\n  user: tidb\n",
+		"dc1.yaml":  "tikv_servers:\n  - host: 172.16.5.141\nglobal:\n  user: tikv\n",
+	})
+	files, err := ReadTopologyFiles(filepath.Join(dir, "topo.yaml"))
+	c.Assert(err, IsNil)
+	err = files.Unmarshal(&TopologySpecification{})
+	c.Assert(err, FitsTypeOf, &TopologyFileError{})
+	c.Assert(err.(*TopologyFileError).Location(), Equals, filepath.Join(dir, "dc1.yaml")+":3")
+	c.Assert(err, ErrorMatches, "`global` conflicts with the one in .*topo.yaml:2")
+
+	// the problems of validation are located in the included files
+	dir = writeTopologyFiles(c, map[string]string{
+		"topo.yaml": "include: [dc1.yaml]\ntikv_servers:\n  - host: 172.16.5.141\n",
+		"dc1.yaml":  "tikv_servers:\n  - host: 172.16.5.142\n  - host: 172.16.5.141\n",
+	})
+	files, err = ReadTopologyFiles(filepath.Join(dir, "topo.yaml"))
+	c.Assert(err, IsNil)
+	err = files.Unmarshal(&TopologySpecification{})
+	c.Assert(err, FitsTypeOf, &TopologyError{})
+	file, line := files.Locate(err.(*TopologyError).Path)
+	c.Assert(file, Equals, filepath.Join(dir, "dc1.yaml"))
+	c.Assert(line, Equals, 3)
+
+	dir = writeTopologyFiles(c, map[string]string{
+		"topo.yaml": "include: [dc1.yaml, '*1.yaml']\n",
+		"dc1.yaml":  "tikv_servers:\n  - host: 172.16.5.141\n",
+	})
+	_, err = ReadTopologyFiles(filepath.Join(dir, "topo.yaml"))
+	c.Assert(err, ErrorMatches, "topology file .*dc1.yaml is included more than once")
+}
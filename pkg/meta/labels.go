@@ -0,0 +1,240 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup/pkg/set"
+)
+
+// the default value of replication.max-replicas of PD
+const defaultMaxReplicas = 3
+
+// ValidateLabels checks the labels of the stores against the location labels
+// of PD like labelsDetect, and returns all the problems found. It's not a part
+// of Validate, so that the clusters deployed before are still loaded, and it's
+// checked by the commands deploying and scaling out the topology.
+func (topo *TopologySpecification) ValidateLabels() []*TopologyError {
+	var errs topologyErrors
+	topo.labelsDetect(&errs)
+	return errs
+}
+
+// labelsDetect checks the labels of TiKV and TiFlash, i.e: server.labels, against
+// replication.location-labels of PD, so that the replicas can be isolated by the
+// labels as expected. The stores must have all the location labels, and the
+// replicas of TiKV must be able to be spread across the distinct values of the
+// top-level location label, e.g: dc, and distinct hosts, which is only checked if
+// the topology has PD, i.e: it's not a part of scale-out.
+// The imported instances are skipped as their configs are not in the topology.
+func (topo *TopologySpecification) labelsDetect(errs *topologyErrors) {
+	var pdConfig map[string]interface{}
+	if len(topo.PDServers) > 0 {
+		pdConfig = topo.PDServers[0].Config
+	}
+	locationLabels := locationLabelsOf(configValue(topo.ServerConfigs.PD, pdConfig, "replication.location-labels"))
+	if len(locationLabels) == 0 {
+		return
+	}
+
+	for i, s := range topo.TiKVServers {
+		if !s.Imported {
+			path := fmt.Sprintf("tikv_servers.%d.config", i)
+			labels := configValue(topo.ServerConfigs.TiKV, s.Config, "server.labels")
+			storeLabelsDetect(errs, path, fmt.Sprintf("tikv_servers:%s:%d", s.Host, s.Port), labels, locationLabels)
+		}
+	}
+	for i, s := range topo.TiFlashServers {
+		if !s.Imported {
+			path := fmt.Sprintf("tiflash_servers.%d.learner_config", i)
+			labels := configValue(topo.ServerConfigs.TiFlashLearner, s.LearnerConfig, "server.labels")
+			storeLabelsDetect(errs, path, fmt.Sprintf("tiflash_servers:%s:%d", s.Host, s.TCPPort), labels, locationLabels)
+		}
+	}
+
+	if len(topo.PDServers) == 0 {
+		return
+	}
+	maxReplicas := defaultMaxReplicas
+	if n, ok := intValue(configValue(topo.ServerConfigs.PD, pdConfig, "replication.max-replicas")); ok {
+		maxReplicas = n
+	}
+
+	type store struct {
+		name     string
+		location string
+	}
+	// the replicas can be placed in the same dc if they're only isolated by the
+	// lower levels, e.g: host, so only the top level is counted
+	zones := make(map[string]struct{})
+	hosts := make(map[string]store)
+	for i, s := range topo.TiKVServers {
+		if s.Imported {
+			return
+		}
+		labels := configValue(topo.ServerConfigs.TiKV, s.Config, "server.labels")
+		var values []string
+		for _, label := range locationLabels {
+			values = append(values, fmt.Sprintf("%v", mapValue(labels, label)))
+		}
+		location := strings.Join(values, ",")
+		zones[values[0]] = struct{}{}
+
+		name := fmt.Sprintf("tikv_servers:%s:%d", s.Host, s.Port)
+		if other, ok := hosts[s.Host]; !ok {
+			hosts[s.Host] = store{name, location}
+		} else if other.location != location {
+			errs.add(fmt.Sprintf("tikv_servers.%d.config", i),
+				"'%s' and '%s' are on the same host but in different locations, the replicas can be placed on the same host",
+				other.name, name)
+		}
+	}
+	if len(zones) < maxReplicas {
+		errs.add("server_configs.pd", "max-replicas %d of PD can't be spread across the %d distinct values of label '%s' of TiKV",
+			maxReplicas, len(zones), locationLabels[0])
+	}
+	if len(hosts) < maxReplicas {
+		errs.add("server_configs.pd", "max-replicas %d of PD can't be spread across the %d hosts of TiKV",
+			maxReplicas, len(hosts))
+	}
+}
+
+// storeLabelsDetect checks the labels of the store has all the location labels
+func storeLabelsDetect(errs *topologyErrors, path, name string, labels interface{}, locationLabels []string) {
+	keys := mapKeys(labels)
+	sort.Strings(keys)
+	known := set.NewStringSet(locationLabels...)
+
+	for _, label := range locationLabels {
+		if mapValue(labels, label) != nil {
+			continue
+		}
+		msg := fmt.Sprintf("label '%s' in replication.location-labels of PD is missing in server.labels of '%s'", label, name)
+		for _, key := range keys {
+			if !known.Exist(key) && editDistance(key, label) <= 2 {
+				msg += fmt.Sprintf(", '%s' may be a typo of it", key)
+				break
+			}
+		}
+		errs.add(path, "%s", msg)
+	}
+}
+
+// configValue returns the value of the key in the config of the instance
+// merged over the global one, e.g: server.labels, nil if it's not set
+func configValue(global, instance map[string]interface{}, key string) interface{} {
+	config, err := merge(global, instance)
+	if err != nil {
+		return nil
+	}
+	var val interface{} = config
+	for _, k := range strings.Split(key, ".") {
+		if val = mapValue(val, k); val == nil {
+			return nil
+		}
+	}
+	return val
+}
+
+// mapValue returns the value of the key if m is a map, nil otherwise
+func mapValue(m interface{}, key string) interface{} {
+	switch v := m.(type) {
+	case map[string]interface{}:
+		return v[key]
+	case map[interface{}]interface{}:
+		return v[key]
+	default:
+		return nil
+	}
+}
+
+// mapKeys returns the keys of m if it's a map
+func mapKeys(m interface{}) []string {
+	var keys []string
+	switch v := m.(type) {
+	case map[string]interface{}:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[interface{}]interface{}:
+		for k := range v {
+			keys = append(keys, fmt.Sprintf("%v", k))
+		}
+	}
+	return keys
+}
+
+// locationLabelsOf returns the location labels of the value of config, which
+// is a list or a string separated by commas
+func locationLabelsOf(val interface{}) []string {
+	var labels []string
+	switch v := val.(type) {
+	case string:
+		for _, label := range strings.Split(v, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				labels = append(labels, label)
+			}
+		}
+	case []interface{}:
+		for _, label := range v {
+			labels = append(labels, fmt.Sprintf("%v", label))
+		}
+	}
+	return labels
+}
+
+func intValue(val interface{}) (int, bool) {
+	switch v := val.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case uint64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if d := prev[j] + 1; d < cur[j] {
+				cur[j] = d
+			}
+			if d := cur[j-1] + 1; d < cur[j] {
+				cur[j] = d
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
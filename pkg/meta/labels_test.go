@@ -0,0 +1,121 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+func (s *metaSuite) TestLabelsDetect(c *C) {
+	topo := TopologySpecification{}
+	c.Assert(ParseTopologyYaml(filepath.Join("..", "..", "examples", "multi-dc.yaml"), &topo), IsNil)
+
+	problems := func(content string) []string {
+		topo := TopologySpecification{}
+		// the labels are not checked on loading, e.g: the meta of clusters
+		c.Assert(yaml.Unmarshal([]byte(content), &topo), IsNil)
+		var msgs []string
+		for _, e := range topo.ValidateLabels() {
+			msgs = append(msgs, e.Path+": "+e.Error())
+		}
+		return msgs
+	}
+
+	c.Assert(problems(`
+server_configs:
+  pd:
+    replication.location-labels: ["zone", "host"]
+  tikv:
+    server.labels: { zone: z1 }
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.141
+    config:
+      server.labels: { host: h1 }
+  - host: 172.16.5.142
+    config:
+      server.labels: { hots: h2 }
+  - host: 172.16.5.143
+    config:
+      server.labels: { zone: z2, host: h3 }
+tiflash_servers:
+  - host: 172.16.5.144
+`), DeepEquals, []string{
+		"tikv_servers.1.config: label 'host' in replication.location-labels of PD is missing in server.labels of 'tikv_servers:172.16.5.142:20160', 'hots' may be a typo of it",
+		"tiflash_servers.0.learner_config: label 'zone' in replication.location-labels of PD is missing in server.labels of 'tiflash_servers:172.16.5.144:9000'",
+		"tiflash_servers.0.learner_config: label 'host' in replication.location-labels of PD is missing in server.labels of 'tiflash_servers:172.16.5.144:9000'",
+		"server_configs.pd: max-replicas 3 of PD can't be spread across the 2 distinct values of label 'zone' of TiKV",
+	})
+
+	c.Assert(problems(`
+server_configs:
+  pd:
+    replication.location-labels: ["dc", "host"]
+    replication.max-replicas: 3
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.141
+    config:
+      server.labels: { dc: dc1, host: h1 }
+  - host: 172.16.5.141
+    port: 20161
+    status_port: 20181
+    config:
+      server.labels: { dc: dc1, host: h2 }
+  - host: 172.16.5.142
+    config:
+      server.labels: { dc: dc1, host: h2 }
+`), DeepEquals, []string{
+		"tikv_servers.1.config: 'tikv_servers:172.16.5.141:20160' and 'tikv_servers:172.16.5.141:20161' are on the same host but in different locations, the replicas can be placed on the same host",
+		"server_configs.pd: max-replicas 3 of PD can't be spread across the 1 distinct values of label 'dc' of TiKV",
+		"server_configs.pd: max-replicas 3 of PD can't be spread across the 2 hosts of TiKV",
+	})
+
+	// the stores are on distinct hosts, but all the replicas can be in one dc
+	c.Assert(problems(`
+server_configs:
+  pd:
+    replication.location-labels: ["dc", "host"]
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.141
+    config:
+      server.labels: { dc: dc1, host: h1 }
+  - host: 172.16.5.142
+    config:
+      server.labels: { dc: dc1, host: h2 }
+  - host: 172.16.5.143
+    config:
+      server.labels: { dc: dc2, host: h3 }
+`), DeepEquals, []string{
+		"server_configs.pd: max-replicas 3 of PD can't be spread across the 2 distinct values of label 'dc' of TiKV",
+	})
+
+	// the spread of replicas isn't checked without PD, e.g: the topology of scale-out
+	c.Assert(problems(`
+server_configs:
+  pd:
+    replication.location-labels: ["host"]
+tikv_servers:
+  - host: 172.16.5.141
+    config:
+      server.labels: { host: h1 }
+`), IsNil)
+}
@@ -0,0 +1,203 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// OperationLockName is the file under the cluster directory held by the
+	// operation changing the cluster
+	OperationLockName = "operation.lock"
+)
+
+var (
+	// ErrClusterLocked is ErrClusterLocked
+	ErrClusterLocked = errNSCluster.NewType("locked")
+)
+
+// OperationLock is the holder of the operation lock of a cluster
+type OperationLock struct {
+	PID       int       `yaml:"pid"`
+	User      string    `yaml:"user"`
+	Host      string    `yaml:"host"` // the hostname of the control machine
+	Command   string    `yaml:"command"`
+	StartTime time.Time `yaml:"start_time"`
+}
+
+// String implements the fmt.Stringer interface
+func (l *OperationLock) String() string {
+	return fmt.Sprintf("`%s` (PID %d of %s@%s, started at %s)",
+		l.Command, l.PID, l.User, l.Host, l.StartTime.Local().Format("2006-01-02 15:04:05"))
+}
+
+// stale returns whether the holder is known to be gone, i.e: it was running on
+// this machine and the process doesn't exist anymore.
+func (l *OperationLock) stale() bool {
+	if hostname, _ := os.Hostname(); hostname != l.Host {
+		return false
+	}
+	p, err := os.FindProcess(l.PID)
+	if err != nil {
+		return true
+	}
+	// the process exists but belongs to another user if it's not permitted
+	err = p.Signal(syscall.Signal(0))
+	return err != nil && !os.IsPermission(err)
+}
+
+// ClusterLock returns the holder of the operation lock of the cluster, or nil
+// if it's not locked.
+func ClusterLock(clusterName string) (*OperationLock, error) {
+	lock, _, err := readLock(clusterName, ClusterPath(clusterName, OperationLockName))
+	return lock, err
+}
+
+// readLock returns the holder of the lock file along with its content
+func readLock(clusterName, path string) (*OperationLock, []byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, errors.AddStack(err)
+	}
+
+	lock := &OperationLock{}
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, nil, errors.Annotatef(err, "failed to parse the operation lock of cluster %s", clusterName)
+	}
+	return lock, data, nil
+}
+
+// LockCluster takes the operation lock of the cluster, so that the operations
+// changing the same cluster are not run at the same time. The lock left by a
+// stale holder is taken over, and the one of a live holder is only removed if
+// force is set. The returned function releases the lock, and removes the
+// cluster directory if it's created for the lock and nothing else is saved in
+// it, e.g: the deploy failed before any step.
+func LockCluster(clusterName string, force bool) (func(), error) {
+	_, err := os.Stat(ClusterPath(clusterName))
+	created := os.IsNotExist(err)
+	if err := EnsureClusterDir(clusterName); err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	data, err := yaml.Marshal(&OperationLock{
+		PID:       os.Getpid(),
+		User:      utils.CurrentUser(),
+		Host:      hostname,
+		Command:   strings.Join(os.Args, " "),
+		StartTime: time.Now(),
+	})
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	// the lock is written aside and linked into place, so that it's created
+	// atomically and never seen half written
+	path := ClusterPath(clusterName, OperationLockName)
+	tmp := fmt.Sprintf("%s.%d", path, os.Getpid())
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return nil, errors.AddStack(err)
+	}
+	defer os.Remove(tmp)
+
+	for {
+		err := os.Link(tmp, path)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, errors.AddStack(err)
+		}
+
+		holder, seen, err := readLock(clusterName, path)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case holder == nil:
+			// released just now
+			continue
+		case holder.stale():
+			log.Warnf("Taking over the stale lock of cluster %s held by %s", clusterName, holder)
+		case force:
+			log.Warnf("Removing the lock of cluster %s held by %s", clusterName, holder)
+			// only the lock seen is removed, not the ones taken after it
+			force = false
+		default:
+			return nil, ErrClusterLocked.
+				New("Cluster %s is locked by %s", clusterName, holder).
+				WithProperty(cliutil.SuggestionFromFormat(
+					"Please wait for the operation to finish, or use --force-unlock if it's known to be gone."))
+		}
+		if err := removeLock(path, seen); err != nil {
+			return nil, err
+		}
+	}
+
+	return func() {
+		// the lock may have been removed by force, keep the one of others
+		if current, err := ioutil.ReadFile(path); err == nil && bytes.Equal(current, data) {
+			_ = os.Remove(path)
+		}
+		// only an empty directory is removed
+		if created {
+			_ = os.Remove(ClusterPath(clusterName))
+		}
+	}, nil
+}
+
+// removeLock removes the lock file if it's still the one seen, the lock may
+// have been taken over by others since it's seen. It's moved aside to be
+// checked, and put back if it's not the one seen.
+func removeLock(path string, seen []byte) error {
+	moved := fmt.Sprintf("%s.%d.removing", path, os.Getpid())
+	if err := os.Rename(path, moved); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.AddStack(err)
+	}
+	defer os.Remove(moved)
+
+	data, err := ioutil.ReadFile(moved)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if bytes.Equal(data, seen) {
+		return nil
+	}
+	// the one moved is lost if the lock is taken by others again in the
+	// meantime, which is unlikely
+	if err := os.Link(moved, path); err != nil && !os.IsExist(err) {
+		return errors.AddStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+func (s *metaSuite) TestLockCluster(c *C) {
+	defer func(dir string) { profileDir = dir }(profileDir)
+	profileDir = c.MkDir()
+
+	unlock, err := LockCluster("test", false)
+	c.Assert(err, IsNil)
+	holder, err := ClusterLock("test")
+	c.Assert(err, IsNil)
+	c.Assert(holder.PID, Equals, os.Getpid())
+
+	_, err = LockCluster("test", false)
+	c.Assert(errorx.IsOfType(err, ErrClusterLocked), IsTrue)
+
+	// the lock taken by force is kept when the previous holder exits
+	unlockForce, err := LockCluster("test", true)
+	c.Assert(err, IsNil)
+	unlock()
+	_, err = LockCluster("test", false)
+	c.Assert(errorx.IsOfType(err, ErrClusterLocked), IsTrue)
+	unlockForce()
+	holder, err = ClusterLock("test")
+	c.Assert(err, IsNil)
+	c.Assert(holder, IsNil)
+
+	// the process of the holder is gone
+	cmd := exec.Command("true")
+	c.Assert(cmd.Run(), IsNil)
+	hostname, _ := os.Hostname()
+	stale := OperationLock{PID: cmd.Process.Pid, User: "tidb", Host: hostname, Command: "tiup-cluster upgrade test v4.0.0", StartTime: time.Now()}
+	data, err := yaml.Marshal(&stale)
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(ClusterPath("test", OperationLockName), data, 0644), IsNil)
+	unlock, err = LockCluster("test", false)
+	c.Assert(err, IsNil)
+	unlock()
+
+	// the holder on another machine is never known to be gone
+	stale.Host = hostname + "-other"
+	data, err = yaml.Marshal(&stale)
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(ClusterPath("test", OperationLockName), data, 0644), IsNil)
+	_, err = LockCluster("test", false)
+	c.Assert(errorx.IsOfType(err, ErrClusterLocked), IsTrue)
+}
+
+func (s *metaSuite) TestLockNewCluster(c *C) {
+	defer func(dir string) { profileDir = dir }(profileDir)
+	profileDir = c.MkDir()
+
+	// the directory created for the lock is removed if nothing is saved in it
+	unlock, err := LockCluster("test", false)
+	c.Assert(err, IsNil)
+	unlock()
+	_, err = os.Stat(ClusterPath("test"))
+	c.Assert(os.IsNotExist(err), IsTrue)
+
+	unlock, err = LockCluster("test", false)
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(ClusterPath("test", MetaFileName), []byte("user: tidb\n"), 0644), IsNil)
+	unlock()
+	_, err = os.Stat(ClusterPath("test", MetaFileName))
+	c.Assert(err, IsNil)
+	holder, err := ClusterLock("test")
+	c.Assert(err, IsNil)
+	c.Assert(holder, IsNil)
+}
+
+func (s *metaSuite) TestRemoveLockTakenOver(c *C) {
+	dir := c.MkDir()
+	path := filepath.Join(dir, OperationLockName)
+	c.Assert(ioutil.WriteFile(path, []byte("pid: 1\n"), 0644), IsNil)
+
+	// the lock is taken over by others since it's seen as stale
+	c.Assert(ioutil.WriteFile(path, []byte("pid: 2\n"), 0644), IsNil)
+	c.Assert(removeLock(path, []byte("pid: 1\n")), IsNil)
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "pid: 2\n")
+
+	c.Assert(removeLock(path, []byte("pid: 2\n")), IsNil)
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), IsTrue)
+	// nothing is left aside
+	files, err := ioutil.ReadDir(dir)
+	c.Assert(err, IsNil)
+	c.Assert(files, HasLen, 0)
+}
@@ -15,6 +15,8 @@ package meta
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -69,21 +71,22 @@ type Component interface {
 type Instance interface {
 	InstanceSpec
 	ID() string
-	Ready(executor.TiOpsExecutor, int64) error
-	WaitForDown(executor.TiOpsExecutor, int64) error
+	Ready(context.Context, executor.TiOpsExecutor, int64) error
+	WaitForDown(context.Context, executor.TiOpsExecutor, int64) error
 	InitConfig(e executor.TiOpsExecutor, clusterName string, clusterVersion string, deployUser string, paths DirPaths) error
 	ScaleConfig(e executor.TiOpsExecutor, topo Specification, clusterName string, clusterVersion string, deployUser string, paths DirPaths) error
-	PrepareStart() error
+	PrepareStart(tlsCfg *tls.Config) error
 	ComponentName() string
 	InstanceName() string
 	ServiceName() string
 	GetHost() string
 	GetPort() int
 	GetSSHPort() int
+	GetSSHProxy() *SSHProxy
 	DeployDir() string
 	UsedPorts() []int
 	UsedDirs() []string
-	Status(pdList ...string) string
+	Status(tlsCfg *tls.Config, pdList ...string) string
 	DataDir() string
 	LogDir() string
 	OS() string // only linux supported now
@@ -104,23 +107,25 @@ type Specification interface {
 	GetDMSpecification() *DMSpecification
 }
 
-// PortStarted wait until a port is being listened
-func PortStarted(e executor.TiOpsExecutor, port int, timeout int64) error {
+// PortStarted wait until a port is being listened or ctx is done
+func PortStarted(ctx context.Context, e executor.TiOpsExecutor, port int, timeout int64) error {
 	c := module.WaitForConfig{
 		Port:    port,
 		State:   "started",
 		Timeout: time.Second * time.Duration(timeout),
+		Context: ctx,
 	}
 	w := module.NewWaitFor(c)
 	return w.Execute(e)
 }
 
-// PortStopped wait until a port is being released
-func PortStopped(e executor.TiOpsExecutor, port int, timeout int64) error {
+// PortStopped wait until a port is being released or ctx is done
+func PortStopped(ctx context.Context, e executor.TiOpsExecutor, port int, timeout int64) error {
 	c := module.WaitForConfig{
 		Port:    port,
 		State:   "stopped",
 		Timeout: time.Second * time.Duration(timeout),
+		Context: ctx,
 	}
 	w := module.NewWaitFor(c)
 	return w.Execute(e)
@@ -137,17 +142,17 @@ type instance struct {
 
 	usedPorts []int
 	usedDirs  []string
-	statusFn  func(pdHosts ...string) string
+	statusFn  func(tlsCfg *tls.Config, pdHosts ...string) string
 }
 
 // Ready implements Instance interface
-func (i *instance) Ready(e executor.TiOpsExecutor, timeout int64) error {
-	return PortStarted(e, i.port, timeout)
+func (i *instance) Ready(ctx context.Context, e executor.TiOpsExecutor, timeout int64) error {
+	return PortStarted(ctx, e, i.port, timeout)
 }
 
 // WaitForDown implements Instance interface
-func (i *instance) WaitForDown(e executor.TiOpsExecutor, timeout int64) error {
-	return PortStopped(e, i.port, timeout)
+func (i *instance) WaitForDown(ctx context.Context, e executor.TiOpsExecutor, timeout int64) error {
+	return PortStopped(ctx, e, i.port, timeout)
 }
 
 func (i *instance) InitConfig(e executor.TiOpsExecutor, _, _, user string, paths DirPaths) error {
@@ -187,6 +192,13 @@ func (i *instance) InitConfig(e executor.TiOpsExecutor, _, _, user string, paths
 // mergeServerConfig merges the server configuration and overwrite the global configuration
 func (i *instance) mergeServerConfig(e executor.TiOpsExecutor, globalConf, instanceConf map[string]interface{}, paths DirPaths) error {
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("%s-%s-%d.toml", i.ComponentName(), i.GetHost(), i.GetPort()))
+	// the certificates generated on deploy are used unless they're set by users
+	if tlsConf := i.topo.tlsServerConfig(i.ComponentName(), paths.Deploy); tlsConf != nil {
+		var err error
+		if globalConf, err = merge(tlsConf, globalConf); err != nil {
+			return err
+		}
+	}
 	conf, err := merge2Toml(i.ComponentName(), globalConf, instanceConf)
 	if err != nil {
 		return err
@@ -252,6 +264,11 @@ func (i *instance) GetSSHPort() int {
 	return i.sshp
 }
 
+// GetSSHProxy implements Instance interface
+func (i *instance) GetSSHProxy() *SSHProxy {
+	return reflect.ValueOf(i.InstanceSpec).FieldByName("SSHProxy").Interface().(*SSHProxy)
+}
+
 func (i *instance) DeployDir() string {
 	return reflect.ValueOf(i.InstanceSpec).FieldByName("DeployDir").String()
 }
@@ -279,7 +296,7 @@ func (i *instance) Arch() string {
 }
 
 // PrepareStart checks instance requirements before starting
-func (i *instance) PrepareStart() error {
+func (i *instance) PrepareStart(tlsCfg *tls.Config) error {
 	return nil
 }
 
@@ -335,8 +352,8 @@ func (i *instance) UsedDirs() []string {
 	return i.usedDirs
 }
 
-func (i *instance) Status(pdList ...string) string {
-	return i.statusFn(pdList...)
+func (i *instance) Status(tlsCfg *tls.Config, pdList ...string) string {
+	return i.statusFn(tlsCfg, pdList...)
 }
 
 // ClusterSpecification of cluster
@@ -606,13 +623,7 @@ func (i *PDInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clusterVe
 	}
 
 	spec := i.InstanceSpec.(PDSpec)
-	cfg := scripts.NewPDScript(
-		spec.Name,
-		i.GetHost(),
-		paths.Deploy,
-		paths.Data[0],
-		paths.Log,
-	).WithClientPort(spec.ClientPort).WithPeerPort(spec.PeerPort).AppendEndpoints(i.instance.topo.Endpoints(deployUser)...)
+	cfg := i.script(i.instance.topo, deployUser, paths)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_pd_%s_%d.sh", i.GetHost(), i.GetPort()))
 	if err := cfg.ConfigToFile(fp); err != nil {
@@ -672,15 +683,7 @@ func (i *PDInstance) ScaleConfig(e executor.TiOpsExecutor, b Specification, clus
 		return err
 	}
 
-	c := b.GetClusterSpecification()
-	spec := i.InstanceSpec.(PDSpec)
-	cfg := scripts.NewPDScaleScript(
-		i.Name,
-		i.GetHost(),
-		paths.Deploy,
-		paths.Data[0],
-		paths.Log,
-	).WithPeerPort(spec.PeerPort).WithNumaNode(spec.NumaNode).WithClientPort(spec.ClientPort).AppendEndpoints(c.Endpoints(deployUser)...)
+	cfg := i.scaleScript(b.GetClusterSpecification(), deployUser, paths)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_pd_%s_%d.sh", i.GetHost(), i.GetPort()))
 	log.Infof("script path: %s", fp)
@@ -698,6 +701,38 @@ func (i *PDInstance) ScaleConfig(e executor.TiOpsExecutor, b Specification, clus
 	return nil
 }
 
+// script returns the run script of the PD instance in the cluster topo
+func (i *PDInstance) script(topo *ClusterSpecification, deployUser string, paths DirPaths) *scripts.PDScript {
+	spec := i.InstanceSpec.(PDSpec)
+	cfg := scripts.NewPDScript(
+		spec.Name,
+		i.GetHost(),
+		paths.Deploy,
+		paths.Data[0],
+		paths.Log,
+	).WithClientPort(spec.ClientPort).WithPeerPort(spec.PeerPort).AppendEndpoints(topo.Endpoints(deployUser)...)
+	if topo.GlobalOptions.TLSEnabled {
+		cfg.WithScheme("https")
+	}
+	return cfg
+}
+
+// scaleScript returns the run script of the PD instance joining the cluster topo
+func (i *PDInstance) scaleScript(topo *ClusterSpecification, deployUser string, paths DirPaths) *scripts.PDScaleScript {
+	spec := i.InstanceSpec.(PDSpec)
+	cfg := scripts.NewPDScaleScript(
+		i.Name,
+		i.GetHost(),
+		paths.Deploy,
+		paths.Data[0],
+		paths.Log,
+	).WithPeerPort(spec.PeerPort).WithNumaNode(spec.NumaNode).WithClientPort(spec.ClientPort).AppendEndpoints(topo.Endpoints(deployUser)...)
+	if topo.GlobalOptions.TLSEnabled {
+		cfg.WithScheme("https")
+	}
+	return cfg
+}
+
 // TiFlashComponent represents TiFlash component.
 type TiFlashComponent struct{ *ClusterSpecification }
 
@@ -1027,10 +1062,10 @@ func (i *TiFlashInstance) getEndpoints() []string {
 }
 
 // PrepareStart checks TiFlash requirements before starting
-func (i *TiFlashInstance) PrepareStart() error {
+func (i *TiFlashInstance) PrepareStart(tlsCfg *tls.Config) error {
 	endPoints := i.getEndpoints()
 	// set enable-placement-rules to true via PDClient
-	pdClient := api.NewPDClient(endPoints, 10*time.Second, nil)
+	pdClient := api.NewPDClient(endPoints, 10*time.Second, tlsCfg)
 	enablePlacementRules, err := json.Marshal(replicateConfig{
 		EnablePlacementRules: "true",
 	})
@@ -1070,7 +1105,7 @@ func (c *MonitorComponent) Instances() []Instance {
 				s.DeployDir,
 				s.DataDir,
 			},
-			statusFn: func(_ ...string) string {
+			statusFn: func(_ *tls.Config, _ ...string) string {
 				return "-"
 			},
 		}})
@@ -1207,7 +1242,7 @@ func (c *GrafanaComponent) Instances() []Instance {
 				usedDirs: []string{
 					s.DeployDir,
 				},
-				statusFn: func(_ ...string) string {
+				statusFn: func(_ *tls.Config, _ ...string) string {
 					return "-"
 				},
 			},
@@ -1319,7 +1354,7 @@ func (c *AlertManagerComponent) Instances() []Instance {
 					s.DeployDir,
 					s.DataDir,
 				},
-				statusFn: func(_ ...string) string {
+				statusFn: func(_ *tls.Config, _ ...string) string {
 					return "-"
 				},
 			},
@@ -1494,6 +1529,9 @@ func (topo *ClusterSpecification) Endpoints(user string) []*scripts.PDScript {
 			logDir).
 			WithClientPort(spec.ClientPort).
 			WithPeerPort(spec.PeerPort)
+		if topo.GlobalOptions.TLSEnabled {
+			script.WithScheme("https")
+		}
 		ends = append(ends, script)
 	}
 	return ends
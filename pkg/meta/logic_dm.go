@@ -14,6 +14,8 @@
 package meta
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -40,17 +42,17 @@ type dmInstance struct {
 
 	usedPorts []int
 	usedDirs  []string
-	statusFn  func(pdHosts ...string) string
+	statusFn  func(tlsCfg *tls.Config, pdHosts ...string) string
 }
 
 // Ready implements Instance interface
-func (i *dmInstance) Ready(e executor.TiOpsExecutor, timeout int64) error {
-	return PortStarted(e, i.port, timeout)
+func (i *dmInstance) Ready(ctx context.Context, e executor.TiOpsExecutor, timeout int64) error {
+	return PortStarted(ctx, e, i.port, timeout)
 }
 
 // WaitForDown implements Instance interface
-func (i *dmInstance) WaitForDown(e executor.TiOpsExecutor, timeout int64) error {
-	return PortStopped(e, i.port, timeout)
+func (i *dmInstance) WaitForDown(ctx context.Context, e executor.TiOpsExecutor, timeout int64) error {
+	return PortStopped(ctx, e, i.port, timeout)
 }
 
 func (i *dmInstance) InitConfig(e executor.TiOpsExecutor, _, _, user string, paths DirPaths) error {
@@ -133,6 +135,11 @@ func (i *dmInstance) GetSSHPort() int {
 	return i.sshp
 }
 
+// GetSSHProxy implements Instance interface
+func (i *dmInstance) GetSSHProxy() *SSHProxy {
+	return reflect.ValueOf(i.InstanceSpec).FieldByName("SSHProxy").Interface().(*SSHProxy)
+}
+
 func (i *dmInstance) DeployDir() string {
 	return reflect.ValueOf(i.InstanceSpec).FieldByName("DeployDir").Interface().(string)
 }
@@ -170,7 +177,7 @@ func (i *dmInstance) Arch() string {
 	return reflect.ValueOf(i.InstanceSpec).FieldByName("Arch").Interface().(string)
 }
 
-func (i *dmInstance) PrepareStart() error {
+func (i *dmInstance) PrepareStart(tlsCfg *tls.Config) error {
 	return nil
 }
 
@@ -186,8 +193,8 @@ func (i *dmInstance) UsedDirs() []string {
 	return i.usedDirs
 }
 
-func (i *dmInstance) Status(pdList ...string) string {
-	return i.statusFn(pdList...)
+func (i *dmInstance) Status(tlsCfg *tls.Config, pdList ...string) string {
+	return i.statusFn(tlsCfg, pdList...)
 }
 
 // DMSpecification of cluster
@@ -0,0 +1,150 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// RevisionDirName is the directory to save the revisions of meta eg. {RevisionDirName}/meta-3.yaml
+	RevisionDirName = "revision"
+	// MaxMetaRevisions is the number of revisions of meta kept, the oldest ones
+	// are removed when a new one is saved
+	MaxMetaRevisions = 20
+
+	revisionIndexName = "revisions.yaml"
+)
+
+var (
+	// ErrMetaRevisionNotFound is ErrMetaRevisionNotFound
+	ErrMetaRevisionNotFound = errNSCluster.NewType("revision_not_found")
+)
+
+// the ID of the audit log of the current operation, which is recorded in
+// the revisions of meta saved
+var auditID string
+
+// SetAuditID sets the ID of the audit log of the current operation
+func SetAuditID(id string) {
+	auditID = id
+}
+
+// MetaRevision is a saved version of the meta of a cluster
+type MetaRevision struct {
+	ID      int       `yaml:"id"`
+	Time    time.Time `yaml:"time"`
+	AuditID string    `yaml:"audit_id,omitempty"` // empty if the audit log is not enabled
+	Command string    `yaml:"command,omitempty"`  // empty for the meta saved before revisions were kept
+}
+
+// MetaRevisions returns the revisions of the meta of the cluster kept, from
+// the oldest to the latest, which is the current meta.
+func MetaRevisions(clusterName string) ([]MetaRevision, error) {
+	data, err := ioutil.ReadFile(ClusterPath(clusterName, RevisionDirName, revisionIndexName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	var revisions []MetaRevision
+	if err := yaml.Unmarshal(data, &revisions); err != nil {
+		return nil, errors.Annotatef(err, "failed to parse the revisions of cluster %s", clusterName)
+	}
+	return revisions, nil
+}
+
+// MetaRevisionData returns the meta of the revision
+func MetaRevisionData(clusterName string, id int) ([]byte, error) {
+	revisions, err := MetaRevisions(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range revisions {
+		if r.ID == id {
+			data, err := ioutil.ReadFile(revisionPath(clusterName, id))
+			return data, errors.AddStack(err)
+		}
+	}
+	return nil, ErrMetaRevisionNotFound.New("Revision %d of the meta of cluster %s is not found", id, clusterName)
+}
+
+func revisionPath(clusterName string, id int) string {
+	return ClusterPath(clusterName, RevisionDirName, fmt.Sprintf("meta-%d.yaml", id))
+}
+
+// saveMetaRevision saves the meta as the latest revision, the meta saved
+// before revisions were kept is saved as the first revision.
+func saveMetaRevision(clusterName string, data []byte) error {
+	if err := os.MkdirAll(ClusterPath(clusterName, RevisionDirName), 0755); err != nil {
+		return errors.AddStack(err)
+	}
+	revisions, err := MetaRevisions(clusterName)
+	if err != nil {
+		return err
+	}
+
+	add := func(r MetaRevision, data []byte) error {
+		r.ID = 1
+		if len(revisions) > 0 {
+			r.ID = revisions[len(revisions)-1].ID + 1
+		}
+		if err := ioutil.WriteFile(revisionPath(clusterName, r.ID), data, 0644); err != nil {
+			return errors.AddStack(err)
+		}
+		revisions = append(revisions, r)
+		return nil
+	}
+
+	metaFile := ClusterPath(clusterName, MetaFileName)
+	if info, err := os.Stat(metaFile); err == nil && len(revisions) == 0 {
+		old, err := ioutil.ReadFile(metaFile)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		if err := add(MetaRevision{Time: info.ModTime()}, old); err != nil {
+			return err
+		}
+	}
+	r := MetaRevision{
+		Time:    time.Now(),
+		AuditID: auditID,
+		Command: strings.Join(os.Args, " "),
+	}
+	if err := add(r, data); err != nil {
+		return err
+	}
+
+	for len(revisions) > MaxMetaRevisions {
+		if err := os.Remove(revisionPath(clusterName, revisions[0].ID)); err != nil && !os.IsNotExist(err) {
+			return errors.AddStack(err)
+		}
+		revisions = revisions[1:]
+	}
+
+	index, err := yaml.Marshal(revisions)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	return errors.AddStack(ioutil.WriteFile(ClusterPath(clusterName, RevisionDirName, revisionIndexName), index, 0644))
+}
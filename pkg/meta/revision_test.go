@@ -0,0 +1,69 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+func (s *metaSuite) TestMetaRevisions(c *C) {
+	defer func(dir string) { profileDir = dir }(profileDir)
+	profileDir = c.MkDir()
+	defer SetAuditID("")
+
+	// the meta saved before revisions were kept
+	c.Assert(os.MkdirAll(ClusterPath("test"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(ClusterPath("test", MetaFileName), []byte("user: tidb\n"), 0644), IsNil)
+
+	SetAuditID("fTz1Ta")
+	for i := 0; i < MaxMetaRevisions+1; i++ {
+		c.Assert(SaveClusterMeta("test", &ClusterMeta{User: "tidb", Version: "v4.0.0"}), IsNil)
+	}
+
+	revisions, err := MetaRevisions("test")
+	c.Assert(err, IsNil)
+	c.Assert(revisions, HasLen, MaxMetaRevisions)
+	// the first revision is removed
+	c.Assert(revisions[0].ID, Equals, 3)
+	latest := revisions[len(revisions)-1]
+	c.Assert(latest.ID, Equals, MaxMetaRevisions+2)
+	c.Assert(latest.AuditID, Equals, "fTz1Ta")
+	c.Assert(latest.Command, Not(Equals), "")
+
+	data, err := MetaRevisionData("test", latest.ID)
+	c.Assert(err, IsNil)
+	current, err := ioutil.ReadFile(ClusterPath("test", MetaFileName))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, string(current))
+
+	_, err = MetaRevisionData("test", 1)
+	c.Assert(errorx.IsOfType(err, ErrMetaRevisionNotFound), IsTrue)
+
+	// the first revision is the meta saved before
+	profileDir = c.MkDir()
+	c.Assert(os.MkdirAll(ClusterPath("test"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(ClusterPath("test", MetaFileName), []byte("user: tidb\n"), 0644), IsNil)
+	c.Assert(SaveClusterMeta("test", &ClusterMeta{User: "tidb"}), IsNil)
+	revisions, err = MetaRevisions("test")
+	c.Assert(err, IsNil)
+	c.Assert(revisions, HasLen, 2)
+	c.Assert(revisions[0].Command, Equals, "")
+	data, err = MetaRevisionData("test", 1)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "user: tidb\n")
+}
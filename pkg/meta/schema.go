@@ -0,0 +1,137 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// the files of the JSON Schemas of topologies, which are generated to the
+// schema directory with `make schema`
+const (
+	TopologySchemaFile   = "topology.schema.json"
+	DMTopologySchemaFile = "dm-topology.schema.json"
+)
+
+// JSONSchemas returns the JSON Schemas of the topologies of TiDB and DM
+// clusters by their files
+func JSONSchemas() map[string]map[string]interface{} {
+	return map[string]map[string]interface{}{
+		TopologySchemaFile:   JSONSchema("TiDB cluster topology", TopologySpecification{}),
+		DMTopologySchemaFile: JSONSchema("DM cluster topology", DMTopologySpecification{}),
+	}
+}
+
+// JSONSchema returns the JSON Schema of the topology specification, e.g: of
+// TopologySpecification{}, which is generated from the yaml and default tags
+// of the fields. Unknown fields are not allowed as topologies are unmarshaled
+// strictly.
+func JSONSchema(title string, spec interface{}) map[string]interface{} {
+	schema := jsonSchemaOf(reflect.TypeOf(spec))
+	// the files included are merged before unmarshaling, see TopologyFiles
+	schema["properties"].(map[string]interface{})[includeKey] = map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"type": "string"},
+	}
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = title
+	return schema
+}
+
+func jsonSchemaOf(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonSchemaOf(t.Elem())
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			key := yamlKey(f.Tag.Get("yaml"))
+			if f.PkgPath != "" || key == "-" {
+				continue
+			}
+			// the same as the default key of yaml
+			if key == "" {
+				key = strings.ToLower(f.Name)
+			}
+			prop := jsonSchemaOf(f.Type)
+			if def, ok := f.Tag.Lookup("default"); ok {
+				prop["default"] = jsonDefault(def, f.Type)
+			}
+			properties[key] = prop
+			// all the instances and the ssh proxy need a host
+			if key == "host" {
+				required = append(required, key)
+			}
+		}
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": jsonSchemaOf(t.Elem()),
+		}
+	case reflect.Map:
+		schema := map[string]interface{}{
+			"type": "object",
+		}
+		if t.Elem().Kind() != reflect.Interface {
+			schema["additionalProperties"] = jsonSchemaOf(t.Elem())
+		}
+		return schema
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		// any value, e.g: interface{}
+		return map[string]interface{}{}
+	}
+}
+
+// jsonDefault converts the default tag to the value of the type of field
+func jsonDefault(def string, t reflect.Type) interface{} {
+	switch t.Kind() {
+	case reflect.Bool:
+		if v, err := strconv.ParseBool(def); err == nil {
+			return v
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v, err := strconv.ParseInt(def, 10, 64); err == nil {
+			return v
+		}
+	case reflect.Float32, reflect.Float64:
+		if v, err := strconv.ParseFloat(def, 64); err == nil {
+			return v
+		}
+	}
+	return def
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+)
+
+func (s *metaSuite) TestJSONSchema(c *C) {
+	schema := JSONSchema("test", TopologySpecification{})
+	c.Assert(schema["additionalProperties"], Equals, false)
+	tidb := schema["properties"].(map[string]interface{})["tidb_servers"].(map[string]interface{})["items"].(map[string]interface{})
+	c.Assert(tidb["required"], DeepEquals, []string{"host"})
+	c.Assert(tidb["properties"].(map[string]interface{})["port"], DeepEquals, map[string]interface{}{
+		"type":    "integer",
+		"default": int64(4000),
+	})
+	c.Assert(tidb["properties"].(map[string]interface{})["config"], DeepEquals, map[string]interface{}{
+		"type": "object",
+	})
+
+	// the generated files are up to date
+	for file, schema := range JSONSchemas() {
+		expected, err := json.MarshalIndent(schema, "", "  ")
+		c.Assert(err, IsNil)
+		data, err := ioutil.ReadFile(filepath.Join("..", "..", "schema", file))
+		c.Assert(err, IsNil)
+		c.Assert(string(data), Equals, string(expected)+"\n", Commentf("%s is outdated, please run `make schema`", file))
+	}
+}
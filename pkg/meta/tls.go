@@ -0,0 +1,144 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+)
+
+// file names of the certificates and keys of a TLS enabled cluster, they are
+// saved in the TLSCertKeyDir of both the cluster directory and the deploy
+// directory of instances
+const (
+	TLSCertKeyDir = "tls"
+	TLSCACert     = "ca.crt"
+	TLSCAKey      = "ca.pem"
+	TLSClientCert = "client.crt"
+	TLSClientKey  = "client.pem"
+)
+
+var (
+	errNSTLS = errNS.NewSubNamespace("tls")
+	// ErrTLSCertLoadFailed is ErrTLSCertLoadFailed
+	ErrTLSCertLoadFailed = errNSTLS.NewType("cert_load_failed")
+)
+
+// tlsComponents are the components secured by TLS in a TLS enabled cluster
+var tlsComponents = set.NewStringSet(
+	ComponentTiDB, ComponentTiKV, ComponentPD, ComponentPump, ComponentDrainer, ComponentCDC,
+)
+
+// NeedTLSCert returns whether the instances of the component need certificates
+func (topo *TopologySpecification) NeedTLSCert(comp string) bool {
+	return topo.GlobalOptions.TLSEnabled && tlsComponents.Exist(comp)
+}
+
+// TLSCertKeyPaths returns the paths of the CA certificate, the certificate and
+// the private key of the component in the deploy directory.
+func TLSCertKeyPaths(deployDir, comp string) (ca, cert, key string) {
+	dir := filepath.Join(deployDir, TLSCertKeyDir)
+	return filepath.Join(dir, TLSCACert), filepath.Join(dir, comp+".crt"), filepath.Join(dir, comp+".pem")
+}
+
+// TLSSignedCertKeyPaths returns the paths of the certificate and the private
+// key signed for the instance, which are kept in the cluster directory.
+func TLSSignedCertKeyPaths(clusterName string, inst Instance) (cert, key string) {
+	dir := ClusterPath(clusterName, TLSCertKeyDir)
+	name := fmt.Sprintf("%s-%s-%d", inst.ComponentName(), inst.GetHost(), inst.GetPort())
+	return filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".pem")
+}
+
+// TLSConfig loads the client certificate in dir, which is the TLSCertKeyDir of
+// the cluster directory, to access the APIs of the cluster. It returns nil if
+// TLS is not enabled.
+func (topo *TopologySpecification) TLSConfig(dir string) (*tls.Config, error) {
+	if !topo.GlobalOptions.TLSEnabled {
+		return nil, nil
+	}
+
+	caCert, err := ioutil.ReadFile(filepath.Join(dir, TLSCACert))
+	if err != nil {
+		return nil, tlsCertLoadError(err, dir)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, ErrTLSCertLoadFailed.New("No certificate found in '%s'", filepath.Join(dir, TLSCACert))
+	}
+	cert, err := tls.LoadX509KeyPair(filepath.Join(dir, TLSClientCert), filepath.Join(dir, TLSClientKey))
+	if err != nil {
+		return nil, tlsCertLoadError(err, dir)
+	}
+	return &tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{cert},
+	}, nil
+}
+
+func tlsCertLoadError(err error, dir string) error {
+	return ErrTLSCertLoadFailed.
+		Wrap(err, "Failed to load the TLS certificates of the cluster in '%s'", dir).
+		WithProperty(cliutil.SuggestionFromString("Please check that the certificates generated on deploy are not removed or modified."))
+}
+
+// scheme returns the scheme of URLs to access the APIs of the components
+func scheme(tlsCfg *tls.Config) string {
+	if tlsCfg != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// tlsServerConfig returns the TLS section of the config of the component, or
+// nil if TLS is not enabled or it's configured by the arguments in the script.
+func (topo *TopologySpecification) tlsServerConfig(comp, deployDir string) map[string]interface{} {
+	if !topo.GlobalOptions.TLSEnabled {
+		return nil
+	}
+
+	ca, cert, key := TLSCertKeyPaths(deployDir, comp)
+	switch comp {
+	case ComponentTiDB:
+		return map[string]interface{}{
+			"security.cluster-ssl-ca":   ca,
+			"security.cluster-ssl-cert": cert,
+			"security.cluster-ssl-key":  key,
+		}
+	case ComponentTiKV:
+		return map[string]interface{}{
+			"security.ca-path":   ca,
+			"security.cert-path": cert,
+			"security.key-path":  key,
+		}
+	case ComponentPD:
+		return map[string]interface{}{
+			"security.cacert-path": ca,
+			"security.cert-path":   cert,
+			"security.key-path":    key,
+		}
+	case ComponentPump, ComponentDrainer:
+		return map[string]interface{}{
+			"security.ssl-ca":   ca,
+			"security.ssl-cert": cert,
+			"security.ssl-key":  key,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/certutil"
+	. "github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+func (s *metaSuite) TestTLSConfig(c *C) {
+	topo := TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+`), &topo)
+	c.Assert(err, IsNil)
+	dir := c.MkDir()
+	tlsCfg, err := topo.TLSConfig(dir)
+	c.Assert(err, IsNil)
+	c.Assert(tlsCfg, IsNil)
+	c.Assert(topo.tlsServerConfig(ComponentPD, "/home/tidb/deploy"), IsNil)
+
+	topo.GlobalOptions.TLSEnabled = true
+	_, err = topo.TLSConfig(dir)
+	c.Assert(errorx.IsOfType(err, ErrTLSCertLoadFailed), IsTrue)
+
+	ca, err := certutil.NewCA("test-cluster")
+	c.Assert(err, IsNil)
+	c.Assert(ca.Save(filepath.Join(dir, TLSCACert), filepath.Join(dir, TLSCAKey)), IsNil)
+	certPEM, keyPEM, err := ca.Sign("tiup", nil)
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, TLSClientCert), certPEM, 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, TLSClientKey), keyPEM, 0600), IsNil)
+	tlsCfg, err = topo.TLSConfig(dir)
+	c.Assert(err, IsNil)
+	c.Assert(tlsCfg.Certificates, HasLen, 1)
+
+	c.Assert(topo.tlsServerConfig(ComponentPD, "/home/tidb/deploy"), DeepEquals, map[string]interface{}{
+		"security.cacert-path": "/home/tidb/deploy/tls/ca.crt",
+		"security.cert-path":   "/home/tidb/deploy/tls/pd.crt",
+		"security.key-path":    "/home/tidb/deploy/tls/pd.pem",
+	})
+	c.Assert(topo.tlsServerConfig(ComponentCDC, "/home/tidb/deploy"), IsNil)
+	c.Assert(topo.NeedTLSCert(ComponentCDC), IsTrue)
+	c.Assert(topo.NeedTLSCert(ComponentGrafana), IsFalse)
+	c.Assert(topo.Endpoints("tidb")[0].Scheme, Equals, "https")
+}
+
+func (s *metaSuite) TestPDScriptTLS(c *C) {
+	topo := TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  enable_tls: true
+pd_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+`), &topo)
+	c.Assert(err, IsNil)
+
+	paths := DirPaths{Deploy: "/home/tidb/deploy", Data: []string{"/home/tidb/data"}, Log: "/home/tidb/log"}
+	pd := (&PDComponent{&topo}).Instances()[0].(*PDInstance)
+	urls := regexp.MustCompile(`\w+://`)
+	for _, config := range []func() ([]byte, error){
+		pd.script(&topo, "tidb", paths).Config,
+		pd.scaleScript(&topo, "tidb", paths).Config,
+	} {
+		data, err := config()
+		c.Assert(err, IsNil)
+		schemes := urls.FindAllString(string(data), -1)
+		c.Assert(len(schemes) > 0, IsTrue)
+		for _, scheme := range schemes {
+			c.Assert(scheme, Equals, "https://", Commentf("%s", data))
+		}
+	}
+}
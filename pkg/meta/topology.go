@@ -14,9 +14,11 @@
 package meta
 
 import (
+	"crypto/tls"
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -24,6 +26,7 @@ import (
 
 	"github.com/creasty/defaults"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 	"github.com/pingcap-incubator/tiup/pkg/set"
 	"github.com/pingcap/errors"
@@ -61,14 +64,26 @@ type (
 	// GlobalOptions represents the global options for all groups in topology
 	// specification in topology.yaml
 	GlobalOptions struct {
-		User            string          `yaml:"user,omitempty" default:"tidb"`
-		SSHPort         int             `yaml:"ssh_port,omitempty" default:"22"`
-		DeployDir       string          `yaml:"deploy_dir,omitempty" default:"deploy"`
-		DataDir         string          `yaml:"data_dir,omitempty" default:"data"`
-		LogDir          string          `yaml:"log_dir,omitempty"`
-		ResourceControl ResourceControl `yaml:"resource_control,omitempty"`
-		OS              string          `yaml:"os,omitempty" default:"linux"`
-		Arch            string          `yaml:"arch,omitempty" default:"amd64"`
+		User            string           `yaml:"user,omitempty" default:"tidb"`
+		SSHPort         int              `yaml:"ssh_port,omitempty" default:"22"`
+		DeployDir       string           `yaml:"deploy_dir,omitempty" default:"deploy"`
+		DataDir         string           `yaml:"data_dir,omitempty" default:"data"`
+		LogDir          string           `yaml:"log_dir,omitempty"`
+		ResourceControl ResourceControl  `yaml:"resource_control,omitempty"`
+		OS              string           `yaml:"os,omitempty" default:"linux"`
+		Arch            string           `yaml:"arch,omitempty" default:"amd64"`
+		SSHType         executor.SSHType `yaml:"ssh_type,omitempty"`
+		SSHProxy        *SSHProxy        `yaml:"ssh_proxy,omitempty"`
+		TLSEnabled      bool             `yaml:"enable_tls,omitempty"`
+		Firewall        FirewallOptions  `yaml:"firewall,omitempty"`
+	}
+
+	// SSHProxy represents the jump host used to connect to the hosts via SSH
+	SSHProxy struct {
+		Host         string `yaml:"host"`
+		Port         int    `yaml:"port,omitempty"`
+		User         string `yaml:"user,omitempty"`
+		IdentityFile string `yaml:"identity_file,omitempty"`
 	}
 
 	// MonitoredOptions represents the monitored node configuration
@@ -99,6 +114,7 @@ type (
 		GlobalOptions    GlobalOptions      `yaml:"global,omitempty"`
 		MonitoredOptions MonitoredOptions   `yaml:"monitored,omitempty"`
 		ServerConfigs    ServerConfigs      `yaml:"server_configs,omitempty"`
+		HostGroups       HostGroups         `yaml:"host_groups,omitempty"`
 		TiDBServers      []TiDBSpec         `yaml:"tidb_servers"`
 		TiKVServers      []TiKVSpec         `yaml:"tikv_servers"`
 		TiFlashServers   []TiFlashSpec      `yaml:"tiflash_servers"`
@@ -126,7 +142,9 @@ func AllComponentNames() (roles []string) {
 // TiDBSpec represents the TiDB topology specification in topology.yaml
 type TiDBSpec struct {
 	Host            string                 `yaml:"host"`
+	Group           string                 `yaml:"group,omitempty"`
 	SSHPort         int                    `yaml:"ssh_port,omitempty"`
+	SSHProxy        *SSHProxy              `yaml:"ssh_proxy,omitempty"`
 	Imported        bool                   `yaml:"imported,omitempty"`
 	Port            int                    `yaml:"port" default:"4000"`
 	StatusPort      int                    `yaml:"status_port" default:"10080"`
@@ -140,8 +158,8 @@ type TiDBSpec struct {
 }
 
 // statusByURL queries current status of the instance by http status api.
-func statusByURL(url string) string {
-	client := utils.NewHTTPClient(statusQueryTimeout, nil)
+func statusByURL(url string, tlsCfg *tls.Config) string {
+	client := utils.NewHTTPClient(statusQueryTimeout, tlsCfg)
 
 	// body doesn't have any status section needed
 	body, err := client.Get(url)
@@ -156,9 +174,9 @@ func statusByURL(url string) string {
 }
 
 // Status queries current status of the instance
-func (s TiDBSpec) Status(pdList ...string) string {
-	url := fmt.Sprintf("http://%s:%d/status", s.Host, s.StatusPort)
-	return statusByURL(url)
+func (s TiDBSpec) Status(tlsCfg *tls.Config, pdList ...string) string {
+	url := fmt.Sprintf("%s://%s:%d/status", scheme(tlsCfg), s.Host, s.StatusPort)
+	return statusByURL(url, tlsCfg)
 }
 
 // Role returns the component role of the instance
@@ -184,7 +202,9 @@ func (s TiDBSpec) IsImported() bool {
 // TiKVSpec represents the TiKV topology specification in topology.yaml
 type TiKVSpec struct {
 	Host            string                 `yaml:"host"`
+	Group           string                 `yaml:"group,omitempty"`
 	SSHPort         int                    `yaml:"ssh_port,omitempty"`
+	SSHProxy        *SSHProxy              `yaml:"ssh_proxy,omitempty"`
 	Imported        bool                   `yaml:"imported,omitempty"`
 	Port            int                    `yaml:"port" default:"20160"`
 	StatusPort      int                    `yaml:"status_port" default:"20180"`
@@ -200,11 +220,11 @@ type TiKVSpec struct {
 }
 
 // checkStoreStatus checks the store status in current cluster
-func checkStoreStatus(storeAddr string, pdList ...string) string {
+func checkStoreStatus(storeAddr string, tlsCfg *tls.Config, pdList ...string) string {
 	if len(pdList) < 1 {
 		return "N/A"
 	}
-	pdapi := api.NewPDClient(pdList, statusQueryTimeout, nil)
+	pdapi := api.NewPDClient(pdList, statusQueryTimeout, tlsCfg)
 	stores, err := pdapi.GetStores()
 	if err != nil {
 		return "Down"
@@ -231,9 +251,9 @@ func checkStoreStatus(storeAddr string, pdList ...string) string {
 }
 
 // Status queries current status of the instance
-func (s TiKVSpec) Status(pdList ...string) string {
+func (s TiKVSpec) Status(tlsCfg *tls.Config, pdList ...string) string {
 	storeAddr := fmt.Sprintf("%s:%d", s.Host, s.Port)
-	return checkStoreStatus(storeAddr, pdList...)
+	return checkStoreStatus(storeAddr, tlsCfg, pdList...)
 }
 
 // Role returns the component role of the instance
@@ -258,9 +278,11 @@ func (s TiKVSpec) IsImported() bool {
 
 // PDSpec represents the PD topology specification in topology.yaml
 type PDSpec struct {
-	Host     string `yaml:"host"`
-	SSHPort  int    `yaml:"ssh_port,omitempty"`
-	Imported bool   `yaml:"imported,omitempty"`
+	Host     string    `yaml:"host"`
+	Group    string    `yaml:"group,omitempty"`
+	SSHPort  int       `yaml:"ssh_port,omitempty"`
+	SSHProxy *SSHProxy `yaml:"ssh_proxy,omitempty"`
+	Imported bool      `yaml:"imported,omitempty"`
 	// Use Name to get the name with a default value if it's empty.
 	Name            string                 `yaml:"name"`
 	ClientPort      int                    `yaml:"client_port" default:"2379"`
@@ -276,9 +298,9 @@ type PDSpec struct {
 }
 
 // Status queries current status of the instance
-func (s PDSpec) Status(pdList ...string) string {
+func (s PDSpec) Status(tlsCfg *tls.Config, pdList ...string) string {
 	pdapi := api.NewPDClient([]string{fmt.Sprintf("%s:%d", s.Host, s.ClientPort)},
-		statusQueryTimeout, nil)
+		statusQueryTimeout, tlsCfg)
 	healths, err := pdapi.GetHealth()
 	if err != nil {
 		return "Down"
@@ -336,7 +358,9 @@ func (s PDSpec) IsImported() bool {
 // TiFlashSpec represents the TiFlash topology specification in topology.yaml
 type TiFlashSpec struct {
 	Host                 string                 `yaml:"host"`
+	Group                string                 `yaml:"group,omitempty"`
 	SSHPort              int                    `yaml:"ssh_port,omitempty"`
+	SSHProxy             *SSHProxy              `yaml:"ssh_proxy,omitempty"`
 	Imported             bool                   `yaml:"imported,omitempty"`
 	TCPPort              int                    `yaml:"tcp_port" default:"9000"`
 	HTTPPort             int                    `yaml:"http_port" default:"8123"`
@@ -358,9 +382,9 @@ type TiFlashSpec struct {
 }
 
 // Status queries current status of the instance
-func (s TiFlashSpec) Status(pdList ...string) string {
+func (s TiFlashSpec) Status(tlsCfg *tls.Config, pdList ...string) string {
 	storeAddr := fmt.Sprintf("%s:%d", s.Host, s.FlashServicePort)
-	return checkStoreStatus(storeAddr, pdList...)
+	return checkStoreStatus(storeAddr, tlsCfg, pdList...)
 }
 
 // Role returns the component role of the instance
@@ -386,7 +410,9 @@ func (s TiFlashSpec) IsImported() bool {
 // PumpSpec represents the Pump topology specification in topology.yaml
 type PumpSpec struct {
 	Host            string                 `yaml:"host"`
+	Group           string                 `yaml:"group,omitempty"`
 	SSHPort         int                    `yaml:"ssh_port,omitempty"`
+	SSHProxy        *SSHProxy              `yaml:"ssh_proxy,omitempty"`
 	Imported        bool                   `yaml:"imported,omitempty"`
 	Port            int                    `yaml:"port" default:"8250"`
 	DeployDir       string                 `yaml:"deploy_dir,omitempty"`
@@ -423,7 +449,9 @@ func (s PumpSpec) IsImported() bool {
 // DrainerSpec represents the Drainer topology specification in topology.yaml
 type DrainerSpec struct {
 	Host            string                 `yaml:"host"`
+	Group           string                 `yaml:"group,omitempty"`
 	SSHPort         int                    `yaml:"ssh_port,omitempty"`
+	SSHProxy        *SSHProxy              `yaml:"ssh_proxy,omitempty"`
 	Imported        bool                   `yaml:"imported,omitempty"`
 	Port            int                    `yaml:"port" default:"8249"`
 	DeployDir       string                 `yaml:"deploy_dir,omitempty"`
@@ -461,7 +489,9 @@ func (s DrainerSpec) IsImported() bool {
 // CDCSpec represents the Drainer topology specification in topology.yaml
 type CDCSpec struct {
 	Host            string                 `yaml:"host"`
+	Group           string                 `yaml:"group,omitempty"`
 	SSHPort         int                    `yaml:"ssh_port,omitempty"`
+	SSHProxy        *SSHProxy              `yaml:"ssh_proxy,omitempty"`
 	Imported        bool                   `yaml:"imported,omitempty"`
 	Port            int                    `yaml:"port" default:"8300"`
 	DeployDir       string                 `yaml:"deploy_dir,omitempty"`
@@ -497,7 +527,9 @@ func (s CDCSpec) IsImported() bool {
 // PrometheusSpec represents the Prometheus Server topology specification in topology.yaml
 type PrometheusSpec struct {
 	Host            string          `yaml:"host"`
+	Group           string          `yaml:"group,omitempty"`
 	SSHPort         int             `yaml:"ssh_port,omitempty"`
+	SSHProxy        *SSHProxy       `yaml:"ssh_proxy,omitempty"`
 	Imported        bool            `yaml:"imported,omitempty"`
 	Port            int             `yaml:"port" default:"9090"`
 	DeployDir       string          `yaml:"deploy_dir,omitempty"`
@@ -533,7 +565,9 @@ func (s PrometheusSpec) IsImported() bool {
 // GrafanaSpec represents the Grafana topology specification in topology.yaml
 type GrafanaSpec struct {
 	Host            string          `yaml:"host"`
+	Group           string          `yaml:"group,omitempty"`
 	SSHPort         int             `yaml:"ssh_port,omitempty"`
+	SSHProxy        *SSHProxy       `yaml:"ssh_proxy,omitempty"`
 	Imported        bool            `yaml:"imported,omitempty"`
 	Port            int             `yaml:"port" default:"3000"`
 	DeployDir       string          `yaml:"deploy_dir,omitempty"`
@@ -565,7 +599,9 @@ func (s GrafanaSpec) IsImported() bool {
 // AlertManagerSpec represents the AlertManager topology specification in topology.yaml
 type AlertManagerSpec struct {
 	Host            string          `yaml:"host"`
+	Group           string          `yaml:"group,omitempty"`
 	SSHPort         int             `yaml:"ssh_port,omitempty"`
+	SSHProxy        *SSHProxy       `yaml:"ssh_proxy,omitempty"`
 	Imported        bool            `yaml:"imported,omitempty"`
 	WebPort         int             `yaml:"web_port" default:"9093"`
 	ClusterPort     int             `yaml:"cluster_port" default:"9094"`
@@ -627,6 +663,14 @@ func (topo *TopologySpecification) UnmarshalYAML(unmarshal func(interface{}) err
 		topo.MonitoredOptions.LogDir = filepath.Join(topo.MonitoredOptions.DeployDir, topo.MonitoredOptions.LogDir)
 	}
 
+	// the settings of host groups take precedence over the global options, so
+	// they are resolved before the custom default values
+	var errs topologyErrors
+	*topo = *topo.resolveHostGroups(&errs)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
 	// populate custom default values as needed
 	if err := fillCustomDefaults(&topo.GlobalOptions, topo); err != nil {
 		return err
@@ -646,7 +690,7 @@ func findField(v reflect.Value, fieldName string) (int, bool) {
 
 // platformConflictsDetect checks for conflicts in topology for different OS / Arch
 // for set to the same host / IP
-func (topo *TopologySpecification) platformConflictsDetect() error {
+func (topo *TopologySpecification) platformConflictsDetect(errs *topologyErrors) {
 	type (
 		conflict struct {
 			os   string
@@ -674,8 +718,10 @@ func (topo *TopologySpecification) platformConflictsDetect() error {
 			// check hostname
 			host := compSpec.FieldByName("Host").String()
 			cfg := topoType.Field(i).Tag.Get("yaml")
+			path := fmt.Sprintf("%s.%d", yamlKey(cfg), index)
 			if host == "" {
-				return errors.Errorf("`%s` contains empty host field", cfg)
+				errs.add(path, "`%s` contains empty host field", cfg)
+				continue
 			}
 
 			// platform conflicts
@@ -692,17 +738,17 @@ func (topo *TopologySpecification) platformConflictsDetect() error {
 			prev, exist := platformStats[host]
 			if exist {
 				if prev.os != stat.os || prev.arch != stat.arch {
-					return errors.Errorf("platform mismatch for '%s' as in '%s:%s/%s' and '%s:%s/%s'",
+					errs.add(path+".host", "platform mismatch for '%s' as in '%s:%s/%s' and '%s:%s/%s'",
 						host, prev.cfg, prev.os, prev.arch, stat.cfg, stat.os, stat.arch)
+					continue
 				}
 			}
 			platformStats[host] = stat
 		}
 	}
-	return nil
 }
 
-func (topo *TopologySpecification) portConflictsDetect() error {
+func (topo *TopologySpecification) portConflictsDetect(errs *topologyErrors) {
 	type (
 		usedPort struct {
 			host string
@@ -742,11 +788,11 @@ func (topo *TopologySpecification) portConflictsDetect() error {
 			if compSpec.Interface().(InstanceSpec).IsImported() {
 				continue
 			}
-			// check hostname
+			// empty hosts are reported by platformConflictsDetect
 			host := compSpec.FieldByName("Host").String()
 			cfg := topoType.Field(i).Tag.Get("yaml")
 			if host == "" {
-				return errors.Errorf("`%s` contains empty host field", cfg)
+				continue
 			}
 			uniqueHosts.Insert(host)
 
@@ -760,8 +806,10 @@ func (topo *TopologySpecification) portConflictsDetect() error {
 					tp := compSpec.Type().Field(j).Tag.Get("yaml")
 					prev, exist := portStats[item]
 					if exist {
-						return errors.Errorf("port '%d' conflicts between '%s:%s.%s' and '%s:%s.%s'",
+						errs.add(fmt.Sprintf("%s.%d.%s", yamlKey(cfg), index, yamlKey(tp)),
+							"port '%d' conflicts between '%s:%s.%s' and '%s:%s.%s'",
 							item.port, prev.cfg, item.host, prev.tp, cfg, item.host, tp)
+						continue
 					}
 					portStats[item] = conflict{
 						tp:  tp,
@@ -778,7 +826,12 @@ func (topo *TopologySpecification) portConflictsDetect() error {
 		"BlackboxExporterPort",
 	}
 	monitoredOpt := topoSpec.FieldByName(monitorOptionTypeName)
+	hosts := make([]string, 0, len(uniqueHosts))
 	for host := range uniqueHosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
 		cfg := "monitored"
 		for _, portType := range monitoredPortTypes {
 			f := monitoredOpt.FieldByName(portType)
@@ -788,14 +841,16 @@ func (topo *TopologySpecification) portConflictsDetect() error {
 			}
 			ft, found := monitoredOpt.Type().FieldByName(portType)
 			if !found {
-				return errors.Errorf("incompatible change `%s.%s`", monitorOptionTypeName, portType)
+				errs.add(cfg, "incompatible change `%s.%s`", monitorOptionTypeName, portType)
+				continue
 			}
 			// `yaml:"node_exporter_port,omitempty"`
 			tp := strings.Split(ft.Tag.Get("yaml"), ",")[0]
 			prev, exist := portStats[item]
 			if exist {
-				return errors.Errorf("port '%d' conflicts between '%s:%s.%s' and '%s:%s.%s'",
+				errs.add(cfg+"."+tp, "port '%d' conflicts between '%s:%s.%s' and '%s:%s.%s'",
 					item.port, prev.cfg, item.host, prev.tp, cfg, item.host, tp)
+				continue
 			}
 			portStats[item] = conflict{
 				tp:  tp,
@@ -804,10 +859,9 @@ func (topo *TopologySpecification) portConflictsDetect() error {
 		}
 	}
 
-	return nil
 }
 
-func (topo *TopologySpecification) dirConflictsDetect() error {
+func (topo *TopologySpecification) dirConflictsDetect(errs *topologyErrors) {
 	type (
 		usedDir struct {
 			host string
@@ -845,11 +899,11 @@ func (topo *TopologySpecification) dirConflictsDetect() error {
 			if compSpec.Interface().(InstanceSpec).IsImported() {
 				continue
 			}
-			// check hostname
+			// empty hosts are reported by platformConflictsDetect
 			host := compSpec.FieldByName("Host").String()
 			cfg := topoType.Field(i).Tag.Get("yaml")
 			if host == "" {
-				return errors.Errorf("`%s` contains empty host field", cfg)
+				continue
 			}
 			uniqueHosts.Insert(host)
 
@@ -869,8 +923,10 @@ func (topo *TopologySpecification) dirConflictsDetect() error {
 					tp := strings.Split(compSpec.Type().Field(j).Tag.Get("yaml"), ",")[0]
 					prev, exist := dirStats[item]
 					if exist {
-						return errors.Errorf("directory '%s' conflicts between '%s:%s.%s' and '%s:%s.%s'",
+						errs.add(fmt.Sprintf("%s.%d.%s", yamlKey(cfg), index, tp),
+							"directory '%s' conflicts between '%s:%s.%s' and '%s:%s.%s'",
 							item.dir, prev.cfg, item.host, prev.tp, cfg, item.host, tp)
+						continue
 					}
 					dirStats[item] = conflict{
 						tp:  tp,
@@ -880,22 +936,37 @@ func (topo *TopologySpecification) dirConflictsDetect() error {
 			}
 		}
 	}
-
-	return nil
 }
 
 // Validate validates the topology specification and produce error if the
-// specification invalid (e.g: port conflicts or directory conflicts)
+// specification invalid (e.g: port conflicts or directory conflicts), the
+// first problem found is returned
 func (topo *TopologySpecification) Validate() error {
-	if err := topo.platformConflictsDetect(); err != nil {
-		return err
+	if errs := topo.ValidateAll(); len(errs) > 0 {
+		return errs[0]
 	}
+	return nil
+}
 
-	if err := topo.portConflictsDetect(); err != nil {
-		return err
+// ValidateAll validates the topology specification like Validate, but returns
+// all the problems found
+func (topo *TopologySpecification) ValidateAll() []*TopologyError {
+	var errs topologyErrors
+	// the instances can't be checked before their host groups are resolved
+	topo.resolveHostGroups(&errs)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if topo.GlobalOptions.TLSEnabled && len(topo.TiFlashServers) > 0 {
+		errs.add("global.enable_tls", "TiFlash doesn't support TLS, `enable_tls` can't be set with `tiflash_servers`")
 	}
 
-	return topo.dirConflictsDetect()
+	topo.firewallSourcesDetect(&errs)
+	topo.platformConflictsDetect(&errs)
+	topo.portConflictsDetect(&errs)
+	topo.dirConflictsDetect(&errs)
+	return errs
 }
 
 // GetPDList returns a list of PD API hosts of the current cluster
@@ -910,9 +981,10 @@ func (topo *TopologySpecification) GetPDList() []string {
 }
 
 // GetEtcdClient load EtcdClient of current cluster
-func (topo *TopologySpecification) GetEtcdClient() (*clientv3.Client, error) {
+func (topo *TopologySpecification) GetEtcdClient(tlsCfg *tls.Config) (*clientv3.Client, error) {
 	return clientv3.New(clientv3.Config{
 		Endpoints: topo.GetPDList(),
+		TLS:       tlsCfg,
 	})
 }
 
@@ -922,6 +994,7 @@ func (topo *TopologySpecification) Merge(that *TopologySpecification) *TopologyS
 		GlobalOptions:    topo.GlobalOptions,
 		MonitoredOptions: topo.MonitoredOptions,
 		ServerConfigs:    topo.ServerConfigs,
+		HostGroups:       topo.HostGroups.merge(that.HostGroups),
 		TiDBServers:      append(topo.TiDBServers, that.TiDBServers...),
 		TiKVServers:      append(topo.TiKVServers, that.TiKVServers...),
 		PDServers:        append(topo.PDServers, that.PDServers...),
@@ -955,12 +1028,14 @@ var (
 	monitorOptionTypeName   = reflect.TypeOf(MonitoredOptions{}).Name()
 	serverConfigsTypeName   = reflect.TypeOf(ServerConfigs{}).Name()
 	dmServerConfigsTypeName = reflect.TypeOf(DMServerConfigs{}).Name()
+	hostGroupsTypeName      = reflect.TypeOf(HostGroups{}).Name()
 )
 
 // Skip global/monitored options
 func isSkipField(field reflect.Value) bool {
 	tp := field.Type().Name()
-	return tp == globalOptionTypeName || tp == monitorOptionTypeName || tp == serverConfigsTypeName || tp == dmServerConfigsTypeName
+	return tp == globalOptionTypeName || tp == monitorOptionTypeName || tp == serverConfigsTypeName ||
+		tp == dmServerConfigsTypeName || tp == hostGroupsTypeName
 }
 
 func setDefaultDir(parent, role, port string, field reflect.Value) {
@@ -1009,6 +1084,12 @@ func setCustomDefaults(globalOptions *GlobalOptions, field reflect.Value) error
 				continue
 			}
 			field.Field(j).Set(reflect.ValueOf(globalOptions.SSHPort))
+		case "SSHProxy":
+			if !field.Field(j).IsNil() || globalOptions.SSHProxy == nil {
+				continue
+			}
+			proxy := *globalOptions.SSHProxy
+			field.Field(j).Set(reflect.ValueOf(&proxy))
 		case "Name":
 			if field.Field(j).String() != "" {
 				continue
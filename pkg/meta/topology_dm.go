@@ -14,6 +14,7 @@
 package meta
 
 import (
+	"crypto/tls"
 	"fmt"
 	"path/filepath"
 	"reflect"
@@ -36,6 +37,7 @@ type (
 		GlobalOptions    GlobalOptions      `yaml:"global,omitempty"`
 		MonitoredOptions MonitoredOptions   `yaml:"monitored,omitempty"`
 		ServerConfigs    DMServerConfigs    `yaml:"server_configs,omitempty"`
+		HostGroups       HostGroups         `yaml:"host_groups,omitempty"`
 		Masters          []MasterSpec       `yaml:"dm_masters"`
 		Workers          []WorkerSpec       `yaml:"dm_workers"`
 		Monitors         []PrometheusSpec   `yaml:"monitoring_servers"`
@@ -46,9 +48,11 @@ type (
 
 // MasterSpec represents the Master topology specification in topology.yaml
 type MasterSpec struct {
-	Host     string `yaml:"host"`
-	SSHPort  int    `yaml:"ssh_port,omitempty"`
-	Imported bool   `yaml:"imported,omitempty"`
+	Host     string    `yaml:"host"`
+	Group    string    `yaml:"group,omitempty"`
+	SSHPort  int       `yaml:"ssh_port,omitempty"`
+	SSHProxy *SSHProxy `yaml:"ssh_proxy,omitempty"`
+	Imported bool      `yaml:"imported,omitempty"`
 	// Use Name to get the name with a default value if it's empty.
 	Name      string                 `yaml:"name"`
 	Port      int                    `yaml:"port" default:"8261"`
@@ -64,9 +68,9 @@ type MasterSpec struct {
 }
 
 // Status queries current status of the instance
-func (s MasterSpec) Status(pdList ...string) string {
-	url := fmt.Sprintf("http://%s:%d/status", s.Host, s.Port)
-	return statusByURL(url)
+func (s MasterSpec) Status(tlsCfg *tls.Config, pdList ...string) string {
+	url := fmt.Sprintf("%s://%s:%d/status", scheme(tlsCfg), s.Host, s.Port)
+	return statusByURL(url, tlsCfg)
 }
 
 // Role returns the component role of the instance
@@ -91,9 +95,11 @@ func (s MasterSpec) IsImported() bool {
 
 // WorkerSpec represents the Master topology specification in topology.yaml
 type WorkerSpec struct {
-	Host     string `yaml:"host"`
-	SSHPort  int    `yaml:"ssh_port,omitempty"`
-	Imported bool   `yaml:"imported,omitempty"`
+	Host     string    `yaml:"host"`
+	Group    string    `yaml:"group,omitempty"`
+	SSHPort  int       `yaml:"ssh_port,omitempty"`
+	SSHProxy *SSHProxy `yaml:"ssh_proxy,omitempty"`
+	Imported bool      `yaml:"imported,omitempty"`
 	// Use Name to get the name with a default value if it's empty.
 	Name      string                 `yaml:"name"`
 	Port      int                    `yaml:"port" default:"8262"`
@@ -108,7 +114,7 @@ type WorkerSpec struct {
 }
 
 // Status queries current status of the instance
-func (s WorkerSpec) Status(pdList ...string) string {
+func (s WorkerSpec) Status(tlsCfg *tls.Config, pdList ...string) string {
 	return "N/A"
 }
 
@@ -153,6 +159,14 @@ func (topo *DMTopologySpecification) UnmarshalYAML(unmarshal func(interface{}) e
 			fmt.Sprintf("%s-%d", RoleMonitor, topo.MonitoredOptions.NodeExporterPort))
 	}
 
+	// the settings of host groups take precedence over the global options, so
+	// they are resolved before the custom default values
+	var errs topologyErrors
+	*topo = *topo.resolveHostGroups(&errs)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
 	if err := fillDMCustomDefaults(&topo.GlobalOptions, topo); err != nil {
 		return err
 	}
@@ -420,6 +434,7 @@ func (topo *DMTopologySpecification) Merge(that *DMTopologySpecification) *DMTop
 		GlobalOptions:    topo.GlobalOptions,
 		MonitoredOptions: topo.MonitoredOptions,
 		ServerConfigs:    topo.ServerConfigs,
+		HostGroups:       topo.HostGroups.merge(that.HostGroups),
 		Masters:          append(topo.Masters, that.Masters...),
 		Workers:          append(topo.Workers, that.Workers...),
 		Monitors:         append(topo.Monitors, that.Monitors...),
@@ -479,6 +494,12 @@ func setDMCustomDefaults(globalOptions *GlobalOptions, field reflect.Value) erro
 				continue
 			}
 			field.Field(j).Set(reflect.ValueOf(globalOptions.SSHPort))
+		case "SSHProxy":
+			if !field.Field(j).IsNil() || globalOptions.SSHProxy == nil {
+				continue
+			}
+			proxy := *globalOptions.SSHProxy
+			field.Field(j).Set(reflect.ValueOf(&proxy))
 		case "Name":
 			if field.Field(j).String() != "" {
 				continue
@@ -96,6 +96,35 @@ pd_servers:
 	c.Assert(topo.PDServers[0].DataDir, Equals, "pd-data")
 }
 
+func (s *metaSuite) TestSSHProxy(c *C) {
+	topo := TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  ssh_proxy:
+    host: 10.0.0.1
+    user: jump
+tidb_servers:
+  - host: 172.16.5.138
+pd_servers:
+  - host: 172.16.5.53
+    ssh_proxy:
+      host: 10.0.0.2
+      port: 2222
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.TiDBServers[0].SSHProxy, DeepEquals, &SSHProxy{Host: "10.0.0.1", User: "jump"})
+	c.Assert(topo.PDServers[0].SSHProxy, DeepEquals, &SSHProxy{Host: "10.0.0.2", Port: 2222})
+
+	// instances are connected directly without global ssh_proxy
+	topo = TopologySpecification{}
+	err = yaml.Unmarshal([]byte(`
+tidb_servers:
+  - host: 172.16.5.138
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.TiDBServers[0].SSHProxy, IsNil)
+}
+
 func (s *metaSuite) TestDataDirAbsolute(c *C) {
 	topo := TopologySpecification{}
 	err := yaml.Unmarshal([]byte(`
@@ -585,3 +614,116 @@ item7 = 780
 	c.Assert(err, IsNil)
 	c.Assert(string(merge2), DeepEquals, expected)
 }
+
+func (s *metaSuite) TestHostGroups(c *C) {
+	topo := TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  deploy_dir: "/tidb-deploy"
+host_groups:
+  ssd:
+    ssh_port: 2222
+    deploy_dir: "/data1/deploy"
+    data_dir: "/{{ .Host.disk }}/data"
+    numa_node: "0"
+    resource_control:
+      memory_limit: "32G"
+      cpu_quota: "800%"
+    labels:
+      zone: "z1"
+      rack: "{{ .Host.rack }}"
+    hosts:
+      172.16.5.140:
+        disk: nvme0
+        rack: r1
+      172.16.5.141:
+        disk: nvme1
+        rack: r2
+tikv_servers:
+  - host: 172.16.5.140
+    group: ssd
+    config:
+      server.labels: { host: "h1" }
+      storage.data-dir: "/{{ .Host.disk }}/storage"
+  - host: 172.16.5.141
+    group: ssd
+    port: 20161
+    ssh_port: 22
+    resource_control:
+      cpu_quota: "400%"
+tidb_servers:
+  - host: 172.16.5.140
+    group: ssd
+`), &topo)
+	c.Assert(err, IsNil)
+
+	tikv := topo.TiKVServers[0]
+	c.Assert(tikv.SSHPort, Equals, 2222)
+	c.Assert(tikv.DeployDir, Equals, "/data1/deploy/tikv-20160")
+	c.Assert(tikv.DataDir, Equals, "/nvme0/data/tikv-20160")
+	c.Assert(tikv.NumaNode, Equals, "0")
+	c.Assert(tikv.ResourceControl, DeepEquals, ResourceControl{MemoryLimit: "32G", CPUQuota: "800%"})
+	c.Assert(tikv.Config, DeepEquals, map[string]interface{}{
+		"server": map[string]interface{}{
+			"labels": map[string]interface{}{"zone": "z1", "rack": "r1", "host": "h1"},
+		},
+		"storage": map[string]interface{}{"data-dir": "/nvme0/storage"},
+	})
+
+	tikv = topo.TiKVServers[1]
+	c.Assert(tikv.SSHPort, Equals, 22)
+	c.Assert(tikv.DataDir, Equals, "/nvme1/data/tikv-20161")
+	c.Assert(tikv.ResourceControl, DeepEquals, ResourceControl{MemoryLimit: "32G", CPUQuota: "400%"})
+	c.Assert(tikv.Config, DeepEquals, map[string]interface{}{
+		"server": map[string]interface{}{
+			"labels": map[string]interface{}{"zone": "z1", "rack": "r2"},
+		},
+	})
+
+	// only the stores have labels
+	c.Assert(topo.TiDBServers[0].DeployDir, Equals, "/data1/deploy/tidb-4000")
+	c.Assert(topo.TiDBServers[0].Config, IsNil)
+
+	// the resolved topology is kept as is
+	data, err := yaml.Marshal(&topo)
+	c.Assert(err, IsNil)
+	resolved := TopologySpecification{}
+	c.Assert(yaml.Unmarshal(data, &resolved), IsNil)
+	c.Assert(resolved, DeepEquals, topo)
+
+	for _, tc := range []struct {
+		topo string
+		err  string
+	}{
+		{`
+tikv_servers:
+  - host: 172.16.5.140
+    group: hdd
+`, "host group 'hdd' of tikv_servers.0 is not defined in host_groups"},
+		{`
+host_groups:
+  ssd:
+    hosts:
+      172.16.5.140:
+tikv_servers:
+  - host: 172.16.5.141
+    group: ssd
+`, "host '172.16.5.141' of tikv_servers.0 is not in host group 'ssd'"},
+		{`
+host_groups:
+  ssd:
+    hosts:
+      172.16.5.140:
+        disk: nvme0
+tikv_servers:
+  - host: 172.16.5.140
+    group: ssd
+    data_dir: "/{{ .Host.ssd }}"
+`, `failed to render data_dir of tikv_servers.0: .* map has no entry for key "ssd"`},
+	} {
+		topo = TopologySpecification{}
+		err = yaml.Unmarshal([]byte(tc.topo), &topo)
+		c.Assert(err, ErrorMatches, tc.err)
+		c.Assert(topo.ValidateAll(), HasLen, 1)
+	}
+}
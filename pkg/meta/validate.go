@@ -0,0 +1,46 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// TopologyError is a problem of the topology found by validation
+type TopologyError struct {
+	Path string // the path of the field with the problem, e.g: tidb_servers.1.port
+	Err  error
+}
+
+// Error implements the error interface
+func (e *TopologyError) Error() string {
+	return e.Err.Error()
+}
+
+// topologyErrors collects the problems found by validation
+type topologyErrors []*TopologyError
+
+func (errs *topologyErrors) add(path, format string, args ...interface{}) {
+	*errs = append(*errs, &TopologyError{
+		Path: path,
+		Err:  errors.Errorf(format, args...),
+	})
+}
+
+// yamlKey returns the key in the yaml tag, e.g: pump_servers of `yaml:"pump_servers,omitempty"`
+func yamlKey(tag string) string {
+	return strings.Split(tag, ",")[0]
+}
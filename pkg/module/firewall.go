@@ -0,0 +1,152 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package module
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+)
+
+// FirewallRule is a rule of the host firewall accepting the TCP connections to
+// the port, only from the source if it's not empty
+type FirewallRule struct {
+	Port   int
+	Source string // an IP address or a CIDR
+}
+
+// String implements the fmt.Stringer interface
+func (r FirewallRule) String() string {
+	if r.Source == "" {
+		return fmt.Sprintf("%d/tcp", r.Port)
+	}
+	return fmt.Sprintf("%d/tcp from %s", r.Port, r.Source)
+}
+
+// FirewallModuleConfig is the configurations used to initialize a FirewallModule
+type FirewallModuleConfig struct {
+	Allow  []FirewallRule // the rules to add
+	Remove []FirewallRule // the rules to remove
+}
+
+// FirewallModule is the module used to change the rules of the host firewall.
+// firewalld is used if it's running, or else iptables (ip6tables for IPv6
+// sources) if it's installed, note that the rules of iptables are not saved
+// to survive a reboot.
+type FirewallModule struct {
+	cmd string // the built command
+}
+
+// NewFirewallModule builds and returns a FirewallModule object base on given
+// config.
+func NewFirewallModule(config FirewallModuleConfig) *FirewallModule {
+	cmd := fmt.Sprintf(
+		"if systemctl is-active --quiet firewalld 2>/dev/null; then %s; elif command -v iptables >/dev/null 2>&1; then %s; fi",
+		firewalldCommand(config), iptablesCommand(config))
+	return &FirewallModule{cmd: cmd}
+}
+
+// Execute passes the command to executor and returns its results, the executor
+// should be already initialized.
+func (mod *FirewallModule) Execute(exec executor.TiOpsExecutor) ([]byte, []byte, error) {
+	return exec.Execute(mod.cmd, true)
+}
+
+// firewalldCommand changes the permanent rules of the default zone and then
+// reloads them, adding existing rules or removing missing ones is only warned
+// by firewall-cmd.
+func firewalldCommand(config FirewallModuleConfig) string {
+	var cmds []string
+	for _, c := range []struct {
+		action string
+		rules  []FirewallRule
+	}{
+		{"add", config.Allow},
+		{"remove", config.Remove},
+	} {
+		var ports, richRules []string
+		for _, r := range c.rules {
+			if r.Source == "" {
+				ports = append(ports, fmt.Sprintf("--%s-port=%d/tcp", c.action, r.Port))
+				continue
+			}
+			richRules = append(richRules, fmt.Sprintf(
+				`--%s-rich-rule='rule family="%s" source address="%s" port port="%d" protocol="tcp" accept'`,
+				c.action, ipFamily(r.Source), r.Source, r.Port))
+		}
+		for _, opts := range [][]string{ports, richRules} {
+			if len(opts) > 0 {
+				cmds = append(cmds, "firewall-cmd --permanent "+strings.Join(opts, " "))
+			}
+		}
+	}
+	cmds = append(cmds, "firewall-cmd --reload")
+	return strings.Join(cmds, " && ")
+}
+
+// iptablesCommand inserts the rules at the top of the INPUT chain. The ports of
+// the rules with sources are closed to the other sources by rules appended to
+// the chain, which are removed with the last rule with source of the port.
+// The rules to add are handled before the ones to remove so that the rule
+// closing the port is kept when the sources of the port are changed.
+func iptablesCommand(config FirewallModuleConfig) string {
+	var cmds []string
+	for _, r := range config.Allow {
+		iptables := iptablesBinary(r.Source)
+		accept := iptablesRule(r, "ACCEPT")
+		cmds = append(cmds, fmt.Sprintf("{ %[1]s -C %[2]s 2>/dev/null || %[1]s -I %[2]s; }", iptables, accept))
+		if r.Source != "" {
+			drop := iptablesRule(FirewallRule{Port: r.Port}, "DROP")
+			cmds = append(cmds, fmt.Sprintf("{ %[1]s -C %[2]s 2>/dev/null || %[1]s -A %[2]s; }", iptables, drop))
+		}
+	}
+	for _, r := range config.Remove {
+		iptables := iptablesBinary(r.Source)
+		accept := iptablesRule(r, "ACCEPT")
+		cmds = append(cmds, fmt.Sprintf("{ while %s -D %s 2>/dev/null; do :; done; }", iptables, accept))
+		if r.Source != "" {
+			drop := iptablesRule(FirewallRule{Port: r.Port}, "DROP")
+			cmds = append(cmds, fmt.Sprintf(
+				"{ %[1]s -S INPUT | grep -q -- '-s .* --dport %[2]d -j ACCEPT' || while %[1]s -D %[3]s 2>/dev/null; do :; done; }",
+				iptables, r.Port, drop))
+		}
+	}
+	if len(cmds) == 0 {
+		return "true"
+	}
+	return strings.Join(cmds, " && ")
+}
+
+func iptablesRule(r FirewallRule, target string) string {
+	rule := "INPUT -p tcp"
+	if r.Source != "" {
+		rule += " -s " + r.Source
+	}
+	return fmt.Sprintf("%s --dport %d -j %s", rule, r.Port, target)
+}
+
+func iptablesBinary(source string) string {
+	if ipFamily(source) == "ipv6" {
+		return "ip6tables"
+	}
+	return "iptables"
+}
+
+func ipFamily(source string) string {
+	if strings.Contains(source, ":") {
+		return "ipv6"
+	}
+	return "ipv4"
+}
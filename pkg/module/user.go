@@ -15,6 +15,7 @@ package module
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 )
@@ -51,17 +52,22 @@ type UserModuleConfig struct {
 	Home   string // home directory of user
 	Shell  string // login shell of the user
 	Sudoer bool   // when true, the user will be added to sudoers list
+	// Password is set to the user if it's created, and the user has to sudo
+	// with it if it's not empty, otherwise without password
+	Password string
 }
 
 // UserModule is the module used to control systemd units
 type UserModule struct {
 	config UserModuleConfig
 	cmd    string // the built command
+	stdin  string // the data sent to the stdin of the command
 }
 
 // NewUserModule builds and returns a UserModule object base on given config.
 func NewUserModule(config UserModuleConfig) *UserModule {
 	cmd := ""
+	stdin := ""
 
 	switch config.Action {
 	case UserActionAdd:
@@ -82,16 +88,29 @@ func NewUserModule(config UserModuleConfig) *UserModule {
 
 		cmd = fmt.Sprintf("%s %s", cmd, config.Name)
 
+		// the password is read from stdin to keep it out of the command line
+		if config.Password != "" {
+			cmd = fmt.Sprintf("{ %s && printf '%%s:%%s\\n' %s \"$pass\" | chpasswd; }", cmd, config.Name)
+			stdin = config.Password + "\n"
+		}
+
 		// prevent errors when username already in use
 		cmd = fmt.Sprintf("id -u %s > /dev/null 2>&1 || %s", config.Name, cmd)
+		if config.Password != "" {
+			cmd = "IFS= read -r pass; " + cmd
+		}
 
 		// add user to sudoers list
 		if config.Sudoer {
-			sudoLine := fmt.Sprintf("%s ALL=(ALL) NOPASSWD:ALL",
-				config.Name)
+			rule := "NOPASSWD:ALL"
+			if config.Password != "" {
+				rule = "ALL"
+			}
+			stdin += fmt.Sprintf("%s ALL=(ALL) %s\n",
+				config.Name, rule)
 			cmd = fmt.Sprintf("%s && %s",
 				cmd,
-				fmt.Sprintf("echo '%s' > /etc/sudoers.d/%s", sudoLine, config.Name))
+				fmt.Sprintf("cat > /etc/sudoers.d/%s", config.Name))
 		}
 	case UserActionDel:
 		cmd = fmt.Sprintf("%s -r %s", userdelCmd, config.Name)
@@ -105,13 +124,14 @@ func NewUserModule(config UserModuleConfig) *UserModule {
 	return &UserModule{
 		config: config,
 		cmd:    cmd,
+		stdin:  stdin,
 	}
 }
 
 // Execute passes the command to executor and returns its results, the executor
 // should be already initialized.
 func (mod *UserModule) Execute(exec executor.TiOpsExecutor) ([]byte, []byte, error) {
-	a, b, err := exec.Execute(mod.cmd, true)
+	a, b, err := exec.ExecuteWithStdin(mod.cmd, strings.NewReader(mod.stdin), true)
 	if err != nil {
 		switch mod.config.Action {
 		case UserActionAdd:
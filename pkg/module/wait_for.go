@@ -15,6 +15,7 @@ package module
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"time"
 
@@ -34,6 +35,8 @@ type WaitForConfig struct {
 	// When checking a port started will ensure the port is open, stopped will check that it is closed
 	State   string
 	Timeout time.Duration // Maximum duration to wait for.
+	// Context stops the wait once it's done, e.g: the operation is interrupted.
+	Context context.Context
 }
 
 // WaitFor is the module used to wait for some condition.
@@ -67,6 +70,7 @@ func (w *WaitFor) Execute(e executor.TiOpsExecutor) (err error) {
 	retryOpt := utils.RetryOption{
 		Delay:   w.c.Sleep,
 		Timeout: w.c.Timeout,
+		Context: w.c.Context,
 	}
 	if err := utils.Retry(func() error {
 		// only listing TCP ports
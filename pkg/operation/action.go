@@ -42,6 +42,9 @@ func Start(
 	components = FilterComponent(components, roleFilter)
 
 	for _, com := range components {
+		if err := interrupted(getter); err != nil {
+			return err
+		}
 		insts := FilterInstance(com.Instances(), nodeFilter)
 		err := StartComponent(getter, insts, options)
 		if err != nil {
@@ -51,7 +54,7 @@ func Start(
 			for _, inst := range insts {
 				if !uniqueHosts.Exist(inst.GetHost()) {
 					uniqueHosts.Insert(inst.GetHost())
-					if err := StartMonitored(getter, inst, clusterSpec.MonitoredOptions, options.OptTimeout); err != nil {
+					if err := StartMonitored(getter, inst, clusterSpec.MonitoredOptions, options); err != nil {
 						return err
 					}
 				}
@@ -79,6 +82,9 @@ func Stop(
 	})
 
 	for _, com := range components {
+		if err := interrupted(getter); err != nil {
+			return err
+		}
 		insts := FilterInstance(com.Instances(), nodeFilter)
 		err := StopComponent(getter, insts)
 		if err != nil {
@@ -88,7 +94,7 @@ func Stop(
 			for _, inst := range insts {
 				instCount[inst.GetHost()]--
 				if instCount[inst.GetHost()] == 0 {
-					if err := StopMonitored(getter, inst, clusterSpec.MonitoredOptions, options.OptTimeout); err != nil {
+					if err := StopMonitored(getter, inst, clusterSpec.MonitoredOptions, options); err != nil {
 						return err
 					}
 				}
@@ -145,9 +151,9 @@ func DestroyClusterTombstone(
 	returNodesOnly bool,
 	options Options,
 ) (nodes []string, err error) {
-	var pdClient = api.NewPDClient(spec.GetPDList(), 10*time.Second, nil)
+	var pdClient = api.NewPDClient(spec.GetPDList(), 10*time.Second, options.TLSConfig)
 
-	binlogClient, err := api.NewBinlogClient(spec.GetPDList(), nil)
+	binlogClient, err := api.NewBinlogClient(spec.GetPDList(), options.TLSConfig)
 	if err != nil {
 		return nil, errors.AddStack(err)
 	}
@@ -193,7 +199,7 @@ func DestroyClusterTombstone(
 			return nil, errors.AddStack(err)
 		}
 
-		err = DestroyComponent(getter, instances, options.OptTimeout)
+		err = DestroyComponent(getter, instances, options)
 		if err != nil {
 			return nil, errors.AddStack(err)
 		}
@@ -232,7 +238,7 @@ func DestroyClusterTombstone(
 			return nil, errors.AddStack(err)
 		}
 
-		err = DestroyComponent(getter, instances, options.OptTimeout)
+		err = DestroyComponent(getter, instances, options)
 		if err != nil {
 			return nil, errors.AddStack(err)
 		}
@@ -269,7 +275,7 @@ func DestroyClusterTombstone(
 			return nil, errors.AddStack(err)
 		}
 
-		err = DestroyComponent(getter, instances, options.OptTimeout)
+		err = DestroyComponent(getter, instances, options)
 		if err != nil {
 			return nil, errors.AddStack(err)
 		}
@@ -307,7 +313,7 @@ func DestroyClusterTombstone(
 			return nil, errors.AddStack(err)
 		}
 
-		err = DestroyComponent(getter, instances, options.OptTimeout)
+		err = DestroyComponent(getter, instances, options)
 		if err != nil {
 			return nil, errors.AddStack(err)
 		}
@@ -345,10 +351,10 @@ func Restart(
 }
 
 // StartMonitored start BlackboxExporter and NodeExporter
-func StartMonitored(getter ExecutorGetter, instance meta.Instance, options meta.MonitoredOptions, timeout int64) error {
+func StartMonitored(getter ExecutorGetter, instance meta.Instance, monitoredOptions meta.MonitoredOptions, options Options) error {
 	ports := map[string]int{
-		meta.ComponentNodeExporter:     options.NodeExporterPort,
-		meta.ComponentBlackboxExporter: options.BlackboxExporterPort,
+		meta.ComponentNodeExporter:     monitoredOptions.NodeExporterPort,
+		meta.ComponentBlackboxExporter: monitoredOptions.BlackboxExporterPort,
 	}
 	e := getter.Get(instance.GetHost())
 	for _, comp := range []string{meta.ComponentNodeExporter, meta.ComponentBlackboxExporter} {
@@ -374,7 +380,10 @@ func StartMonitored(getter ExecutorGetter, instance meta.Instance, options meta.
 		}
 
 		// Check ready.
-		if err := meta.PortStarted(e, ports[comp], timeout); err != nil {
+		err = waitForPort(getter, options, instance.GetHost(), ports[comp], "started", func() error {
+			return meta.PortStarted(stdContext(getter), e, ports[comp], options.OptTimeout)
+		})
+		if err != nil {
 			str := fmt.Sprintf("\t%s failed to start: %s", instance.GetHost(), err)
 			log.Errorf(str)
 			return errors.Annotatef(err, str)
@@ -387,7 +396,7 @@ func StartMonitored(getter ExecutorGetter, instance meta.Instance, options meta.
 }
 
 // RestartComponent restarts the component.
-func RestartComponent(getter ExecutorGetter, instances []meta.Instance, timeout int64) error {
+func RestartComponent(getter ExecutorGetter, instances []meta.Instance, options Options) error {
 	if len(instances) <= 0 {
 		return nil
 	}
@@ -396,6 +405,9 @@ func RestartComponent(getter ExecutorGetter, instances []meta.Instance, timeout
 	log.Infof("Restarting component %s", name)
 
 	for _, ins := range instances {
+		if err := interrupted(getter); err != nil {
+			return err
+		}
 		e := getter.Get(ins.GetHost())
 		log.Infof("\tRestarting instance %s", ins.GetHost())
 
@@ -420,7 +432,9 @@ func RestartComponent(getter ExecutorGetter, instances []meta.Instance, timeout
 		}
 
 		// Check ready.
-		err = ins.Ready(e, timeout)
+		err = waitForPort(getter, options, ins.GetHost(), ins.GetPort(), "started", func() error {
+			return ins.Ready(stdContext(getter), e, options.OptTimeout)
+		})
 		if err != nil {
 			str := fmt.Sprintf("\t%s failed to restart: %s", ins.GetHost(), err)
 			log.Errorf(str)
@@ -433,7 +447,8 @@ func RestartComponent(getter ExecutorGetter, instances []meta.Instance, timeout
 	return nil
 }
 
-func startInstance(getter ExecutorGetter, ins meta.Instance, timeout int64) error {
+func startInstance(getter ExecutorGetter, ins meta.Instance, options Options) error {
+	defer timing(getter, TimingStartInstance, ins.GetHost())()
 	e := getter.Get(ins.GetHost())
 	log.Infof("\tStarting instance %s %s:%d",
 		ins.ComponentName(),
@@ -465,7 +480,9 @@ func startInstance(getter ExecutorGetter, ins meta.Instance, timeout int64) erro
 	}
 
 	// Check ready.
-	err = ins.Ready(e, timeout)
+	err = waitForPort(getter, options, ins.GetHost(), ins.GetPort(), "started", func() error {
+		return ins.Ready(stdContext(getter), e, options.OptTimeout)
+	})
 	if err != nil {
 		str := fmt.Sprintf("\t%s %s:%d failed to start: %s, please check the log of the instance",
 			ins.ComponentName(),
@@ -498,10 +515,15 @@ func StartComponent(getter ExecutorGetter, instances []meta.Instance, options Op
 		ins := ins
 
 		errg.Go(func() error {
-			if err := ins.PrepareStart(); err != nil {
+			// PrepareStart of TiFlash calls the API of PD
+			if options.DryRun {
+				if ins.ComponentName() == meta.ComponentTiFlash {
+					options.note(ins.GetHost(), "enable placement rules via the API of PD")
+				}
+			} else if err := ins.PrepareStart(options.TLSConfig); err != nil {
 				return err
 			}
-			err := startInstance(getter, ins, options.OptTimeout)
+			err := startInstance(getter, ins, options)
 			if err != nil {
 				return errors.AddStack(err)
 			}
@@ -513,10 +535,10 @@ func StartComponent(getter ExecutorGetter, instances []meta.Instance, options Op
 }
 
 // StopMonitored stop BlackboxExporter and NodeExporter
-func StopMonitored(getter ExecutorGetter, instance meta.Instance, options meta.MonitoredOptions, timeout int64) error {
+func StopMonitored(getter ExecutorGetter, instance meta.Instance, monitoredOptions meta.MonitoredOptions, options Options) error {
 	ports := map[string]int{
-		meta.ComponentNodeExporter:     options.NodeExporterPort,
-		meta.ComponentBlackboxExporter: options.BlackboxExporterPort,
+		meta.ComponentNodeExporter:     monitoredOptions.NodeExporterPort,
+		meta.ComponentBlackboxExporter: monitoredOptions.BlackboxExporterPort,
 	}
 	e := getter.Get(instance.GetHost())
 	for _, comp := range []string{meta.ComponentNodeExporter, meta.ComponentBlackboxExporter} {
@@ -554,7 +576,10 @@ func StopMonitored(getter ExecutorGetter, instance meta.Instance, options meta.M
 				instance.GetPort())
 		}
 
-		if err := meta.PortStopped(e, ports[comp], timeout); err != nil {
+		err = waitForPort(getter, options, instance.GetHost(), ports[comp], "stopped", func() error {
+			return meta.PortStopped(stdContext(getter), e, ports[comp], options.OptTimeout)
+		})
+		if err != nil {
 			str := fmt.Sprintf("\t%s %s:%d failed to stop: %s",
 				instance.ComponentName(),
 				instance.GetHost(),
@@ -568,6 +593,7 @@ func StopMonitored(getter ExecutorGetter, instance meta.Instance, options meta.M
 }
 
 func stopInstance(getter ExecutorGetter, ins meta.Instance) error {
+	defer timing(getter, TimingStopInstance, ins.GetHost())()
 	e := getter.Get(ins.GetHost())
 	log.Infof("\tStopping instance %s", ins.GetHost())
 
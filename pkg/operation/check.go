@@ -27,6 +27,7 @@ import (
 	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/module"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 	"github.com/pingcap/tidb-insight/collector/insight"
 )
 
@@ -59,6 +60,7 @@ var (
 	CheckNameSELinux     = "selinux"
 	CheckNameCommand     = "command"
 	CheckNameFio         = "fio"
+	CheckNameFirewall    = "firewall"
 )
 
 // CheckResult is the result of a check
@@ -443,6 +445,228 @@ func CheckSELinux(e executor.TiOpsExecutor) *CheckResult {
 	return result
 }
 
+// CheckFirewall checks if the firewall of the host would block the ports used
+// on the host by the cluster, which are opened in deploy if the firewall is
+// managed. The blocked ports are returned in Msg, separated by commas, or the
+// ones possibly blocked along with a warning if it's unknown.
+func CheckFirewall(e executor.TiOpsExecutor, host string, topo *meta.TopologySpecification) *CheckResult {
+	result := &CheckResult{
+		Name: CheckNameFirewall,
+	}
+	if topo.GlobalOptions.Firewall.Enabled {
+		result.Msg = "the ports will be opened in deploy"
+		return result
+	}
+
+	// find out the ports as if the firewall is managed
+	managed := *topo
+	managed.GlobalOptions.Firewall = meta.FirewallOptions{Enabled: true}
+	var ports []int
+	for _, r := range managed.FirewallRules()[host] {
+		ports = append(ports, r.Port)
+	}
+
+	m := module.NewShellModule(module.ShellModuleConfig{
+		Command: "if systemctl is-active --quiet firewalld 2>/dev/null; then echo firewalld; firewall-cmd --list-ports; " +
+			"elif command -v iptables >/dev/null 2>&1; then echo iptables; iptables -S INPUT; fi",
+		Sudo: true,
+	})
+	stdout, stderr, err := m.Execute(e)
+	if err != nil {
+		result.Err = fmt.Errorf("%w %s", err, stderr)
+		return result
+	}
+
+	var blocked, unknown []int
+	lines := strings.Split(strings.TrimSpace(string(stdout)), "\n")
+	switch lines[0] {
+	case "firewalld":
+		if len(lines) > 1 {
+			blocked = firewalldBlockedPorts(lines[1], ports)
+		} else {
+			blocked = ports
+		}
+	case "iptables":
+		blocked, unknown = iptablesBlockedPorts(lines[1:], ports)
+	default:
+		return result
+	}
+	switch {
+	case len(blocked) > 0:
+		result.Err = fmt.Errorf("%s blocks the ports %s used by the cluster, enable `firewall` in global options to open them in deploy",
+			lines[0], utils.JoinInt(blocked, ","))
+		result.Msg = utils.JoinInt(blocked, ",")
+	case len(unknown) > 0:
+		result.Warn = true
+		result.Err = fmt.Errorf("it's unknown whether %s blocks the ports %s used by the cluster, as they are passed to other chains or rejected in some cases, enable `firewall` in global options to open them in deploy",
+			lines[0], utils.JoinInt(unknown, ","))
+		result.Msg = utils.JoinInt(unknown, ",")
+	}
+	return result
+}
+
+// firewalldBlockedPorts returns the ports not in the output of firewall-cmd
+// --list-ports, e.g: "4000/tcp 20160-20170/tcp"
+func firewalldBlockedPorts(list string, ports []int) []int {
+	var blocked []int
+	for _, port := range ports {
+		open := false
+		for _, item := range strings.Fields(list) {
+			if !strings.HasSuffix(item, "/tcp") {
+				continue
+			}
+			if inPortRange(strings.TrimSuffix(item, "/tcp"), "-", port) {
+				open = true
+				break
+			}
+		}
+		if !open {
+			blocked = append(blocked, port)
+		}
+	}
+	return blocked
+}
+
+// the verdicts of the iptables rules on the connections to a port
+const (
+	iptablesAccept = iota + 1
+	iptablesReject
+	iptablesUnknown // passed to other chains or rejected in some cases
+)
+
+// iptablesBlockedPorts returns the ports rejected by the rules of iptables -S
+// INPUT, and the ones unknown to be rejected or not as they are passed to
+// other chains, e.g: the ones of ufw or firewalld, which are not checked. The
+// rules are roughly evaluated in order for new TCP connections. The ones with
+// other matches, e.g: sources, or negations don't apply to all the connections
+// to the ports, so the ones accepting are ignored, and the ports the others
+// reject are unknown.
+func iptablesBlockedPorts(rules []string, ports []int) (blocked, unknown []int) {
+	policyRejects := false // the policy of the chain drops the connections
+	verdicts := make(map[int]int)
+	for _, rule := range rules {
+		fields := strings.Fields(rule)
+		if len(fields) < 3 {
+			continue
+		}
+		if fields[0] == "-P" {
+			policyRejects = fields[2] == "DROP" || fields[2] == "REJECT"
+			continue
+		}
+
+		var target, dports string
+		protocol := "all"
+		conditional := false
+		negated := false
+		newConns := true // the rule applies to new connections
+		// the options of the target after it are not concerned
+		for i := 2; i < len(fields) && target == ""; i++ {
+			var value string
+			if i+1 < len(fields) {
+				value = fields[i+1]
+			}
+			switch fields[i] {
+			case "-j", "-g":
+				target = value
+			case "-p":
+				protocol = value
+			case "--dport", "--dports":
+				dports = value
+			case "--state", "--ctstate":
+				newConns = strings.Contains(","+value+",", ",NEW,")
+			case "!":
+				negated = true
+			case "-m", "--comment":
+			default:
+				// the other matches, e.g: sources, interfaces or rate limits
+				if strings.HasPrefix(fields[i], "-") {
+					conditional = true
+				}
+			}
+		}
+		if !newConns || (protocol != "all" && protocol != "tcp" && protocol != "6") {
+			continue
+		}
+
+		var verdict int
+		switch target {
+		case "ACCEPT":
+			verdict = iptablesAccept
+		case "DROP", "REJECT":
+			verdict = iptablesReject
+		case "RETURN":
+			// the policy is applied to the connections returned
+			verdict = iptablesAccept
+			if policyRejects {
+				verdict = iptablesReject
+			}
+		case "", "LOG", "NFLOG", "ULOG", "AUDIT", "TRACE":
+			// the connections go on to the next rule
+			continue
+		default:
+			// the connections may be rejected by some of them in the chain
+			verdict = iptablesUnknown
+		}
+		// the rules applying to some of the connections can't be relied
+		// on, but the ones rejecting may reject the connections needed
+		if conditional || negated {
+			if verdict == iptablesAccept {
+				continue
+			}
+			verdict = iptablesUnknown
+		}
+		if negated {
+			dports = ""
+		}
+
+		for _, port := range ports {
+			if verdicts[port] == 0 && (dports == "" || inPortList(dports, port)) {
+				verdicts[port] = verdict
+			}
+		}
+	}
+
+	for _, port := range ports {
+		switch verdicts[port] {
+		case iptablesReject:
+			blocked = append(blocked, port)
+		case iptablesUnknown:
+			unknown = append(unknown, port)
+		case 0:
+			if policyRejects {
+				blocked = append(blocked, port)
+			}
+		}
+	}
+	return blocked, unknown
+}
+
+// inPortList checks if the port is in the list of iptables, e.g: "80,8000:8080"
+func inPortList(list string, port int) bool {
+	for _, item := range strings.Split(list, ",") {
+		if inPortRange(item, ":", port) {
+			return true
+		}
+	}
+	return false
+}
+
+// inPortRange checks if the port is in the range, e.g: "8000-8080"
+func inPortRange(r, sep string, port int) bool {
+	bounds := strings.SplitN(r, sep, 2)
+	low, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return false
+	}
+	high := low
+	if len(bounds) == 2 {
+		if high, err = strconv.Atoi(bounds[1]); err != nil {
+			return false
+		}
+	}
+	return port >= low && port <= high
+}
+
 // CheckListeningPort checks if the ports are already binded by some process on host
 func CheckListeningPort(opt *CheckOptions, host string, topo *meta.TopologySpecification, rawData []byte) []*CheckResult {
 	var results []*CheckResult
@@ -0,0 +1,155 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"testing"
+
+	"github.com/pingcap/check"
+)
+
+func TestOperation(t *testing.T) { check.TestingT(t) }
+
+type firewallSuite struct{}
+
+var _ = check.Suite(&firewallSuite{})
+
+func (s *firewallSuite) TestIptablesBlockedPorts(c *check.C) {
+	ports := []int{22, 2379, 4000, 20160}
+	for _, tc := range []struct {
+		name    string
+		rules   []string
+		blocked []int
+		unknown []int
+	}{
+		{
+			name:  "accept all by policy",
+			rules: []string{"-P INPUT ACCEPT"},
+		},
+		{
+			name:    "drop by policy",
+			rules:   []string{"-P INPUT DROP", "-A INPUT -p tcp -m multiport --dports 22,2379:2380 -j ACCEPT"},
+			blocked: []int{4000, 20160},
+		},
+		{
+			name: "reject the rest",
+			rules: []string{
+				"-P INPUT ACCEPT",
+				"-A INPUT -m state --state RELATED,ESTABLISHED -j ACCEPT",
+				"-A INPUT -p tcp -m state --state NEW -m tcp --dport 22 -j ACCEPT",
+				"-A INPUT -p tcp -m tcp --dport 4000 -j ACCEPT",
+				"-A INPUT -j REJECT --reject-with icmp-host-prohibited",
+			},
+			blocked: []int{2379, 20160},
+		},
+		{
+			name: "accepted before rejected",
+			rules: []string{
+				"-P INPUT ACCEPT",
+				"-A INPUT -p tcp -m tcp --dport 2379 -j ACCEPT",
+				"-A INPUT -p tcp -m tcp --dport 2379 -j DROP",
+				"-A INPUT -p tcp -m tcp --dport 4000 -j DROP",
+			},
+			blocked: []int{4000},
+		},
+		{
+			name: "other protocols",
+			rules: []string{
+				"-P INPUT ACCEPT",
+				"-A INPUT -p udp -m udp --dport 4000 -j DROP",
+				"-A INPUT -p icmp -j REJECT",
+			},
+		},
+		{
+			name: "sources",
+			rules: []string{
+				"-P INPUT DROP",
+				"-A INPUT -i lo -j ACCEPT",
+				"-A INPUT -p tcp -m limit --limit 5/min -j ACCEPT",
+				"-A INPUT -s 10.0.0.0/8 -p tcp -m tcp --dport 4000 -j DROP",
+				"-A INPUT -p tcp -m tcp --dport 22 -j ACCEPT",
+			},
+			blocked: []int{2379, 20160},
+			unknown: []int{4000},
+		},
+		{
+			name: "negations",
+			rules: []string{
+				"-P INPUT ACCEPT",
+				"-A INPUT -p tcp -m tcp --dport 22 -j ACCEPT",
+				"-A INPUT -p tcp -m tcp ! --dport 4000 -j DROP",
+			},
+			unknown: []int{2379, 4000, 20160},
+		},
+		{
+			name: "ufw",
+			rules: []string{
+				"-P INPUT DROP",
+				"-A INPUT -j ufw-before-logging-input",
+				"-A INPUT -j ufw-before-input",
+			},
+			unknown: []int{22, 2379, 4000, 20160},
+		},
+		{
+			name: "firewalld direct rules",
+			rules: []string{
+				"-P INPUT ACCEPT",
+				"-A INPUT -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT",
+				"-A INPUT -p tcp -m tcp --dport 22 -j ACCEPT",
+				"-A INPUT -j INPUT_direct",
+				"-A INPUT -j REJECT --reject-with icmp-host-prohibited",
+			},
+			unknown: []int{2379, 4000, 20160},
+		},
+		{
+			name: "chain for some sources",
+			rules: []string{
+				"-P INPUT ACCEPT",
+				"-A INPUT -s 172.17.0.0/16 -p tcp -m tcp --dport 4000 -j DOCKER-INPUT",
+				"-A INPUT -p tcp -m tcp --dport 20160 -m comment --comment \"log tikv\" -j LOG --log-prefix \"tikv \"",
+			},
+			unknown: []int{4000},
+		},
+		{
+			name: "returned to the policy",
+			rules: []string{
+				"-P INPUT DROP",
+				"-A INPUT -p tcp -m tcp --dport 22 -j ACCEPT",
+				"-A INPUT -p tcp -m tcp --dport 4000 -j RETURN",
+				"-A INPUT -j ACCEPT",
+			},
+			blocked: []int{4000},
+		},
+	} {
+		blocked, unknown := iptablesBlockedPorts(tc.rules, ports)
+		c.Assert(blocked, check.DeepEquals, tc.blocked, check.Commentf(tc.name))
+		c.Assert(unknown, check.DeepEquals, tc.unknown, check.Commentf(tc.name))
+	}
+}
+
+func (s *firewallSuite) TestFirewalldBlockedPorts(c *check.C) {
+	ports := []int{22, 2379, 4000, 20160}
+	for _, tc := range []struct {
+		list    string
+		blocked []int
+	}{
+		{"", []int{22, 2379, 4000, 20160}},
+		{"22/tcp 4000/tcp", []int{2379, 20160}},
+		{"2379-2380/tcp 20160-20170/tcp 4000/udp", []int{22, 4000}},
+		{"1-65535/tcp", nil},
+		{"abc/tcp 4000-x/tcp", []int{22, 2379, 4000, 20160}},
+	} {
+		c.Assert(firewalldBlockedPorts(tc.list, ports), check.DeepEquals, tc.blocked, check.Commentf(tc.list))
+	}
+}
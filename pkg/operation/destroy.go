@@ -41,7 +41,7 @@ func Destroy(
 
 	for _, com := range coms {
 		insts := com.Instances()
-		err := DestroyComponent(getter, insts, options.OptTimeout)
+		err := DestroyComponent(getter, insts, options)
 		if err != nil {
 			return errors.Annotatef(err, "failed to destroy %s", com.Name())
 		}
@@ -49,7 +49,7 @@ func Destroy(
 			for _, inst := range insts {
 				instCount[inst.GetHost()]--
 				if instCount[inst.GetHost()] == 0 {
-					if err := DestroyMonitored(getter, inst, clusterSpec.MonitoredOptions, options.OptTimeout); err != nil {
+					if err := DestroyMonitored(getter, inst, clusterSpec.MonitoredOptions, options); err != nil {
 						return err
 					}
 				}
@@ -104,7 +104,7 @@ func DeleteGlobalDirs(getter ExecutorGetter, host string, options meta.GlobalOpt
 }
 
 // DestroyMonitored destroy the monitored service.
-func DestroyMonitored(getter ExecutorGetter, inst meta.Instance, options meta.MonitoredOptions, timeout int64) error {
+func DestroyMonitored(getter ExecutorGetter, inst meta.Instance, monitoredOptions meta.MonitoredOptions, options Options) error {
 	e := getter.Get(inst.GetHost())
 	log.Infof("Destroying monitored %s", inst.GetHost())
 
@@ -114,22 +114,22 @@ func DestroyMonitored(getter ExecutorGetter, inst meta.Instance, options meta.Mo
 	// Stop by systemd.
 	delPaths := make([]string, 0)
 
-	delPaths = append(delPaths, options.DataDir)
-	delPaths = append(delPaths, options.LogDir)
+	delPaths = append(delPaths, monitoredOptions.DataDir)
+	delPaths = append(delPaths, monitoredOptions.LogDir)
 
 	// In TiDB-Ansible, deploy dir are shared by all components on the same
 	// host, so not deleting it.
 	// TODO: this may leave undeleted files when destroying the cluster, fix
 	// that later.
 	if !inst.IsImported() {
-		delPaths = append(delPaths, options.DeployDir)
+		delPaths = append(delPaths, monitoredOptions.DeployDir)
 	} else {
 		log.Warnf("Monitored deploy dir %s not deleted for TiDB-Ansible imported instance %s.",
-			options.DeployDir, inst.InstanceName())
+			monitoredOptions.DeployDir, inst.InstanceName())
 	}
 
-	delPaths = append(delPaths, fmt.Sprintf("/etc/systemd/system/%s-%d.service", meta.ComponentNodeExporter, options.NodeExporterPort))
-	delPaths = append(delPaths, fmt.Sprintf("/etc/systemd/system/%s-%d.service", meta.ComponentBlackboxExporter, options.BlackboxExporterPort))
+	delPaths = append(delPaths, fmt.Sprintf("/etc/systemd/system/%s-%d.service", meta.ComponentNodeExporter, monitoredOptions.NodeExporterPort))
+	delPaths = append(delPaths, fmt.Sprintf("/etc/systemd/system/%s-%d.service", meta.ComponentBlackboxExporter, monitoredOptions.BlackboxExporterPort))
 
 	c := module.ShellModuleConfig{
 		Command:  fmt.Sprintf("rm -rf %s;", strings.Join(delPaths, " ")),
@@ -151,12 +151,18 @@ func DestroyMonitored(getter ExecutorGetter, inst meta.Instance, options meta.Mo
 		return errors.Annotatef(err, "failed to destroy monitored: %s", inst.GetHost())
 	}
 
-	if err := meta.PortStopped(e, options.NodeExporterPort, timeout); err != nil {
+	err = waitForPort(getter, options, inst.GetHost(), monitoredOptions.NodeExporterPort, "stopped", func() error {
+		return meta.PortStopped(stdContext(getter), e, monitoredOptions.NodeExporterPort, options.OptTimeout)
+	})
+	if err != nil {
 		str := fmt.Sprintf("%s failed to destroy node exportoer: %s", inst.GetHost(), err)
 		log.Errorf(str)
 		return errors.Annotatef(err, str)
 	}
-	if err := meta.PortStopped(e, options.BlackboxExporterPort, timeout); err != nil {
+	err = waitForPort(getter, options, inst.GetHost(), monitoredOptions.BlackboxExporterPort, "stopped", func() error {
+		return meta.PortStopped(stdContext(getter), e, monitoredOptions.BlackboxExporterPort, options.OptTimeout)
+	})
+	if err != nil {
 		str := fmt.Sprintf("%s failed to destroy blackbox exportoer: %s", inst.GetHost(), err)
 		log.Errorf(str)
 		return errors.Annotatef(err, str)
@@ -168,7 +174,7 @@ func DestroyMonitored(getter ExecutorGetter, inst meta.Instance, options meta.Mo
 }
 
 // DestroyComponent destroy the instances.
-func DestroyComponent(getter ExecutorGetter, instances []meta.Instance, timeout int64) error {
+func DestroyComponent(getter ExecutorGetter, instances []meta.Instance, options Options) error {
 	if len(instances) <= 0 {
 		return nil
 	}
@@ -224,7 +230,9 @@ func DestroyComponent(getter ExecutorGetter, instances []meta.Instance, timeout
 			return errors.Annotatef(err, "failed to destroy: %s", ins.GetHost())
 		}
 
-		err = ins.WaitForDown(e, timeout)
+		err = waitForPort(getter, options, ins.GetHost(), ins.GetPort(), "stopped", func() error {
+			return ins.WaitForDown(stdContext(getter), e, options.OptTimeout)
+		})
 		if err != nil {
 			str := fmt.Sprintf("%s failed to destroy: %s", ins.GetHost(), err)
 			log.Errorf(str)
@@ -14,7 +14,10 @@
 package operator
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"time"
 
 	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
@@ -29,6 +32,41 @@ type Options struct {
 	SSHTimeout int64 // timeout in seconds when connecting an SSH server
 	OptTimeout int64 // timeout in seconds for operations that support it, not to confuse with SSH timeout
 	APITimeout int64 // timeout in seconds for API operations that support it, like transfering store leader
+	DryRun     bool  // only show the operations to be performed on hosts
+
+	// Recorder notes the operations not performed by executors in dry run,
+	// e.g: calling the API of PD
+	Recorder *executor.Recorder
+
+	Concurrency     int // max number of tasks executed in parallel
+	HostConcurrency int // max number of concurrent SSH sessions to each host
+
+	TLSConfig *tls.Config // used by the API clients of TLS enabled clusters, nil if TLS is not enabled
+}
+
+// note records an operation which is not performed in dry run, e.g: calling
+// the API of PD, to the recorder of the options.
+func (o Options) note(host, format string, a ...interface{}) {
+	if o.Recorder != nil {
+		o.Recorder.Note(host, format, a...)
+	}
+}
+
+// waitForPort calls wait to wait for the port on host to be in state, it's
+// noted instead in dry run as the state of ports never changes. The wait is
+// stopped if the operation is interrupted.
+func waitForPort(getter ExecutorGetter, options Options, host string, port int, state string, wait func() error) error {
+	if options.DryRun {
+		options.note(host, "wait for port %d to be %s", port, state)
+		return nil
+	}
+	if err := wait(); err != nil {
+		if ierr := interrupted(getter); ierr != nil {
+			return ierr
+		}
+		return err
+	}
+	return nil
 }
 
 // Operation represents the type of cluster operation
@@ -103,3 +141,70 @@ func FilterInstance(instances []meta.Instance, nodes set.StringSet) (res []meta.
 type ExecutorGetter interface {
 	Get(host string) (e executor.TiOpsExecutor)
 }
+
+// Interruptible is implemented by the ExecutorGetter of the operations which
+// can be interrupted, e.g: by Ctrl-C.
+type Interruptible interface {
+	// Interrupted returns a non-nil error if the operation is interrupted
+	Interrupted() error
+	// Context returns the context.Context of the operation, which is done
+	// once it's interrupted
+	Context() context.Context
+	// AddCleanup registers fn to be run before exiting if the operation is
+	// interrupted or failed, the returned func unregisters it.
+	AddCleanup(name string, fn func() error) (remove func())
+}
+
+// interrupted returns the error if the operation is interrupted, it should be
+// checked before each step which can't be stopped in the middle.
+func interrupted(getter ExecutorGetter) error {
+	if i, ok := getter.(Interruptible); ok {
+		return i.Interrupted()
+	}
+	return nil
+}
+
+// stdContext returns the context.Context of the operation, the waits for the
+// state of instances are stopped once it's done.
+func stdContext(getter ExecutorGetter) context.Context {
+	if i, ok := getter.(Interruptible); ok {
+		return i.Context()
+	}
+	return context.Background()
+}
+
+// addCleanup registers fn to be run if the operation is interrupted or failed
+// before the returned func is called.
+func addCleanup(getter ExecutorGetter, name string, fn func() error) (remove func()) {
+	if i, ok := getter.(Interruptible); ok {
+		return i.AddCleanup(name, fn)
+	}
+	return func() {}
+}
+
+// TimingRecorder is implemented by the ExecutorGetter of the operations which
+// report how long the phases of them take, e.g: transferring leaders.
+type TimingRecorder interface {
+	// RecordTiming records that the phase of kind on host took d
+	RecordTiming(kind, host string, d time.Duration)
+}
+
+// phases of the operations recorded by TimingRecorder
+const (
+	TimingTransferLeader = "transfer leader"
+	TimingStartInstance  = "start instance"
+	TimingStopInstance   = "stop instance"
+)
+
+// timing starts timing the phase of kind on host, the returned func records it
+// when the phase is done.
+func timing(getter ExecutorGetter, kind, host string) (done func()) {
+	r, ok := getter.(TimingRecorder)
+	if !ok {
+		return func() {}
+	}
+	begin := time.Now()
+	return func() {
+		r.RecordTiming(kind, host, time.Since(begin))
+	}
+}
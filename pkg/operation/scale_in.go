@@ -124,7 +124,7 @@ func ScaleInCluster(
 				if err := StopComponent(getter, []meta.Instance{instance}); err != nil {
 					log.Warnf("failed to stop %s: %v", component.Name(), err)
 				}
-				if err := DestroyComponent(getter, []meta.Instance{instance}, options.OptTimeout); err != nil {
+				if err := DestroyComponent(getter, []meta.Instance{instance}, options); err != nil {
 					log.Warnf("failed to destroy %s: %v", component.Name(), err)
 				}
 
@@ -149,11 +149,16 @@ func ScaleInCluster(
 		return errors.New("cannot find available PD instance")
 	}
 
-	pdClient = api.NewPDClient(pdEndpoint, 10*time.Second, nil)
+	// the members are not deleted in dry run, so the clients are not needed
+	var binlogClient *api.BinlogClient
+	if !options.DryRun {
+		pdClient = api.NewPDClient(pdEndpoint, 10*time.Second, options.TLSConfig)
 
-	binlogClient, err := api.NewBinlogClient(pdEndpoint, nil /* tls.Config */)
-	if err != nil {
-		return err
+		var err error
+		binlogClient, err = api.NewBinlogClient(pdEndpoint, options.TLSConfig)
+		if err != nil {
+			return err
+		}
 	}
 
 	var tiflashInstances []meta.Instance
@@ -163,7 +168,7 @@ func ScaleInCluster(
 		}
 	}
 
-	if len(tiflashInstances) > 0 {
+	if len(tiflashInstances) > 0 && !options.DryRun {
 		var tikvInstances []meta.Instance
 		for _, instance := range (&meta.TiKVComponent{ClusterSpecification: spec}).Instances() {
 			if !deletedNodes.Exist(instance.ID()) {
@@ -203,31 +208,40 @@ func ScaleInCluster(
 				continue
 			}
 
-			switch component.Name() {
-			case meta.ComponentTiKV:
-				if err := pdClient.DelStore(instance.ID(), timeoutOpt); err != nil {
-					return err
-				}
-			case meta.ComponentTiFlash:
-				addr := instance.GetHost() + ":" + strconv.Itoa(instance.(*meta.TiFlashInstance).GetServicePort())
-				if err := pdClient.DelStore(addr, timeoutOpt); err != nil {
-					return err
+			if options.DryRun {
+				switch component.Name() {
+				case meta.ComponentTiKV, meta.ComponentTiFlash, meta.ComponentPD:
+					options.note(instance.GetHost(), "delete %s from the cluster via the API of PD", instance.ID())
+				case meta.ComponentDrainer, meta.ComponentPump:
+					options.note(instance.GetHost(), "make %s offline via the API of %s", instance.ID(), component.Name())
 				}
-			case meta.ComponentPD:
-				if err := pdClient.DelPD(instance.(*meta.PDInstance).Name, timeoutOpt); err != nil {
-					return err
-				}
-			case meta.ComponentDrainer:
-				addr := instance.GetHost() + ":" + strconv.Itoa(instance.GetPort())
-				err := binlogClient.OfflineDrainer(addr, addr)
-				if err != nil {
-					return errors.AddStack(err)
-				}
-			case meta.ComponentPump:
-				addr := instance.GetHost() + ":" + strconv.Itoa(instance.GetPort())
-				err := binlogClient.OfflinePump(addr, addr)
-				if err != nil {
-					return errors.AddStack(err)
+			} else {
+				switch component.Name() {
+				case meta.ComponentTiKV:
+					if err := pdClient.DelStore(instance.ID(), timeoutOpt); err != nil {
+						return err
+					}
+				case meta.ComponentTiFlash:
+					addr := instance.GetHost() + ":" + strconv.Itoa(instance.(*meta.TiFlashInstance).GetServicePort())
+					if err := pdClient.DelStore(addr, timeoutOpt); err != nil {
+						return err
+					}
+				case meta.ComponentPD:
+					if err := pdClient.DelPD(instance.(*meta.PDInstance).Name, timeoutOpt); err != nil {
+						return err
+					}
+				case meta.ComponentDrainer:
+					addr := instance.GetHost() + ":" + strconv.Itoa(instance.GetPort())
+					err := binlogClient.OfflineDrainer(addr, addr)
+					if err != nil {
+						return errors.AddStack(err)
+					}
+				case meta.ComponentPump:
+					addr := instance.GetHost() + ":" + strconv.Itoa(instance.GetPort())
+					err := binlogClient.OfflinePump(addr, addr)
+					if err != nil {
+						return errors.AddStack(err)
+					}
 				}
 			}
 
@@ -235,7 +249,7 @@ func ScaleInCluster(
 				if err := StopComponent(getter, []meta.Instance{instance}); err != nil {
 					return errors.Annotatef(err, "failed to stop %s", component.Name())
 				}
-				if err := DestroyComponent(getter, []meta.Instance{instance}, options.OptTimeout); err != nil {
+				if err := DestroyComponent(getter, []meta.Instance{instance}, options); err != nil {
 					return errors.Annotatef(err, "failed to destroy %s", component.Name())
 				}
 			} else {
@@ -14,6 +14,7 @@
 package operator
 
 import (
+	"fmt"
 	"strconv"
 	"time"
 
@@ -48,23 +49,38 @@ func Upgrade(
 		if len(instances) < 1 {
 			continue
 		}
+		if err := interrupted(getter); err != nil {
+			return err
+		}
 
 		if clusterSpec := spec.GetClusterSpecification(); clusterSpec != nil {
 			// Transfer leader of evict leader if the component is TiKV/PD in non-force mode
 			if !options.Force && leaderAware.Exist(component.Name()) {
-				pdClient := api.NewPDClient(clusterSpec.GetPDList(), 5*time.Second, nil)
+				if options.DryRun {
+					if err := restartLeaderAwareDryRun(getter, instances, options); err != nil {
+						return err
+					}
+					continue
+				}
+				pdClient := api.NewPDClient(clusterSpec.GetPDList(), 5*time.Second, options.TLSConfig)
 				switch component.Name() {
 				case meta.ComponentPD:
 					log.Infof("Restarting component %s", component.Name())
 
 					for _, instance := range instances {
+						if err := interrupted(getter); err != nil {
+							return err
+						}
 						leader, err := pdClient.GetLeader()
 						if err != nil {
 							return errors.Annotatef(err, "failed to get PD leader %s", instance.GetHost())
 						}
 
 						if len(clusterSpec.PDServers) > 1 && leader.Name == instance.(*meta.PDInstance).Name {
-							if err := pdClient.EvictPDLeader(timeoutOpt); err != nil {
+							done := timing(getter, TimingTransferLeader, instance.GetHost())
+							err := pdClient.EvictPDLeader(timeoutOpt)
+							done()
+							if err != nil {
 								return errors.Annotatef(err, "failed to evict PD leader %s", instance.GetHost())
 							}
 						}
@@ -72,14 +88,14 @@ func Upgrade(
 						if err := stopInstance(getter, instance); err != nil {
 							return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
 						}
-						if err := startInstance(getter, instance, options.OptTimeout); err != nil {
+						if err := startInstance(getter, instance, options); err != nil {
 							return errors.Annotatef(err, "failed to start %s", instance.GetHost())
 						}
 					}
 
 				case meta.ComponentTiKV:
 					log.Infof("Restarting component %s", component.Name())
-					pdClient := api.NewPDClient(clusterSpec.GetPDList(), 5*time.Second, nil)
+					pdClient := api.NewPDClient(clusterSpec.GetPDList(), 5*time.Second, options.TLSConfig)
 					// Make sure there's leader of PD.
 					// Although we evict pd leader when restart pd,
 					// But when there's only one PD instance the pd might not serve request right away after restart.
@@ -89,7 +105,19 @@ func Upgrade(
 					}
 
 					for _, instance := range instances {
-						if err := pdClient.EvictStoreLeader(addr(instance), timeoutOpt); err != nil {
+						if err := interrupted(getter); err != nil {
+							return err
+						}
+						storeAddr := addr(instance)
+						// the scheduler is removed even if the upgrade is interrupted or
+						// failed, otherwise the store never gets leaders again
+						removeCleanup := addCleanup(getter,
+							fmt.Sprintf("remove the evict leader scheduler of %s", storeAddr),
+							func() error { return pdClient.RemoveStoreEvict(storeAddr) })
+						done := timing(getter, TimingTransferLeader, instance.GetHost())
+						err := pdClient.EvictStoreLeader(storeAddr, timeoutOpt)
+						done()
+						if err != nil {
 							if utils.IsTimeoutOrMaxRetry(err) {
 								log.Warnf("Ignore evicting store leader from %s, %v", instance.ID(), err)
 							} else {
@@ -100,20 +128,21 @@ func Upgrade(
 						if err := stopInstance(getter, instance); err != nil {
 							return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
 						}
-						if err := startInstance(getter, instance, options.OptTimeout); err != nil {
+						if err := startInstance(getter, instance, options); err != nil {
 							return errors.Annotatef(err, "failed to start %s", instance.GetHost())
 						}
 						// remove store leader evict scheduler after restart
-						if err := pdClient.RemoveStoreEvict(addr(instance)); err != nil {
+						if err := pdClient.RemoveStoreEvict(storeAddr); err != nil {
 							return errors.Annotatef(err, "failed to remove evict store scheduler for %s", instance.GetHost())
 						}
+						removeCleanup()
 					}
 				}
 				continue
 			}
 		}
 
-		if err := RestartComponent(getter, instances, options.OptTimeout); err != nil {
+		if err := RestartComponent(getter, instances, options); err != nil {
 			return errors.Annotatef(err, "failed to restart %s", component.Name())
 		}
 	}
@@ -121,6 +150,31 @@ func Upgrade(
 	return nil
 }
 
+// restartLeaderAwareDryRun restarts the PD or TiKV instances like Upgrade does,
+// the calls to the API of PD are noted instead.
+func restartLeaderAwareDryRun(getter ExecutorGetter, instances []meta.Instance, options Options) error {
+	for _, instance := range instances {
+		switch instance.ComponentName() {
+		case meta.ComponentPD:
+			options.note(instance.GetHost(), "evict the PD leader if it's %s", instance.ID())
+		case meta.ComponentTiKV:
+			options.note(instance.GetHost(), "evict the store leaders from %s", addr(instance))
+		}
+
+		if err := stopInstance(getter, instance); err != nil {
+			return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
+		}
+		if err := startInstance(getter, instance, options); err != nil {
+			return errors.Annotatef(err, "failed to start %s", instance.GetHost())
+		}
+
+		if instance.ComponentName() == meta.ComponentTiKV {
+			options.note(instance.GetHost(), "remove the evict leader scheduler of %s", addr(instance))
+		}
+	}
+	return nil
+}
+
 func addr(ins meta.Instance) string {
 	if ins.GetPort() == 0 || ins.GetPort() == 80 {
 		panic(ins)
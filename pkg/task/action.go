@@ -30,31 +30,35 @@ type ClusterOperate struct {
 
 // Execute implements the Task interface
 func (c *ClusterOperate) Execute(ctx *Context) error {
+	// the operations not performed by executors are noted in dry run
+	c.options.DryRun = ctx.DryRun()
+	c.options.Recorder = ctx.Recorder
+
 	switch c.op {
 	case operator.StartOperation:
 		err := operator.Start(ctx, c.spec, c.options)
 		if err != nil {
 			return errors.Annotate(err, "failed to start")
 		}
-		operator.PrintClusterStatus(ctx, c.spec)
+		c.printClusterStatus(ctx)
 	case operator.StopOperation:
 		err := operator.Stop(ctx, c.spec, c.options)
 		if err != nil {
 			return errors.Annotate(err, "failed to stop")
 		}
-		operator.PrintClusterStatus(ctx, c.spec)
+		c.printClusterStatus(ctx)
 	case operator.RestartOperation:
 		err := operator.Restart(ctx, c.spec, c.options)
 		if err != nil {
 			return errors.Annotate(err, "failed to restart")
 		}
-		operator.PrintClusterStatus(ctx, c.spec)
+		c.printClusterStatus(ctx)
 	case operator.UpgradeOperation:
 		err := operator.Upgrade(ctx, c.spec, c.options)
 		if err != nil {
 			return errors.Annotate(err, "failed to upgrade")
 		}
-		operator.PrintClusterStatus(ctx, c.spec)
+		c.printClusterStatus(ctx)
 	case operator.DestroyOperation:
 		err := operator.Destroy(ctx, c.spec, c.options)
 		if err != nil {
@@ -78,6 +82,15 @@ func (c *ClusterOperate) Execute(ctx *Context) error {
 	return nil
 }
 
+// printClusterStatus prints the status of the cluster after the operation,
+// there's no status to print in dry run since nothing is changed.
+func (c *ClusterOperate) printClusterStatus(ctx *Context) {
+	if ctx.DryRun() {
+		return
+	}
+	operator.PrintClusterStatus(ctx, c.spec)
+}
+
 // Rollback implements the Task interface
 func (c *ClusterOperate) Rollback(ctx *Context) error {
 	return ErrUnsupportedRollback
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap/errors"
 )
 
@@ -28,6 +29,8 @@ type BackupComponent struct {
 	fromVer   string
 	host      string
 	deployDir string
+
+	exec executor.TiOpsExecutor
 }
 
 // Execute implements the Task interface
@@ -38,6 +41,7 @@ func (c *BackupComponent) Execute(ctx *Context) error {
 		return ErrNoExecutor
 	}
 
+	c.exec = exec
 	binDir := filepath.Join(c.deployDir, "bin")
 
 	// Make upgrade idempotent
@@ -59,8 +63,18 @@ func (c *BackupComponent) Execute(ctx *Context) error {
 	return nil
 }
 
-// Rollback implements the Task interface
+// Rollback implements the Task interface, the binaries are restored from the
+// backup.
 func (c *BackupComponent) Rollback(ctx *Context) error {
+	if c.exec == nil {
+		return nil
+	}
+	binDir := filepath.Join(c.deployDir, "bin")
+	cmd := fmt.Sprintf(`test ! -d %[2]s || { rm -rf %[1]s && cp -r %[2]s %[1]s; }`, binDir, binDir+".old."+c.fromVer)
+	_, stderr, err := c.exec.Execute(cmd, false)
+	if err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
 	return nil
 }
 
@@ -14,7 +14,9 @@
 package task
 
 import (
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/module"
 	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
 )
 
@@ -34,6 +36,8 @@ func (b *Builder) RootSSH(
 	port int,
 	user, password, keyFile, passphrase string,
 	sshTimeout int64,
+	sshType executor.SSHType,
+	proxy *meta.SSHProxy,
 ) *Builder {
 	b.tasks = append(b.tasks, &RootSSH{
 		host:       host,
@@ -43,17 +47,34 @@ func (b *Builder) RootSSH(
 		keyFile:    keyFile,
 		passphrase: passphrase,
 		timeout:    sshTimeout,
+		sshType:    sshType,
+		proxy:      proxy,
 	})
 	return b
 }
 
 // UserSSH append a UserSSH task to the current task collection
-func (b *Builder) UserSSH(host string, port int, deployUser string, sshTimeout int64) *Builder {
+func (b *Builder) UserSSH(host string, port int, deployUser string, sshTimeout int64, sshType executor.SSHType, proxy *meta.SSHProxy) *Builder {
 	b.tasks = append(b.tasks, &UserSSH{
 		host:       host,
 		port:       port,
 		deployUser: deployUser,
 		timeout:    sshTimeout,
+		sshType:    sshType,
+		proxy:      proxy,
+	})
+	return b
+}
+
+// TrustHostKey appends a TrustHostKey task to the current task collection
+func (b *Builder) TrustHostKey(host string, port int, user string, sshTimeout int64, sshType executor.SSHType, proxy *meta.SSHProxy) *Builder {
+	b.tasks = append(b.tasks, &TrustHostKey{
+		host:    host,
+		port:    port,
+		user:    user,
+		timeout: sshTimeout,
+		sshType: sshType,
+		proxy:   proxy,
 	})
 	return b
 }
@@ -67,6 +88,12 @@ func (b *Builder) Func(name string, fn func(ctx *Context) error) *Builder {
 	return b
 }
 
+// FuncWithRollback append a func task which is undone by rollback
+func (b *Builder) FuncWithRollback(name string, fn, rollback func(ctx *Context) error) *Builder {
+	b.tasks = append(b.tasks, NewFunc(name, fn).WithRollback(rollback))
+	return b
+}
+
 // ClusterSSH init all UserSSH need for the cluster.
 func (b *Builder) ClusterSSH(spec meta.Specification, deployUser string, sshTimeout int64) *Builder {
 	var tasks []Task
@@ -77,6 +104,8 @@ func (b *Builder) ClusterSSH(spec meta.Specification, deployUser string, sshTime
 				port:       in.GetSSHPort(),
 				deployUser: deployUser,
 				timeout:    sshTimeout,
+				sshType:    spec.GetGlobalOptions().SSHType,
+				proxy:      in.GetSSHProxy(),
 			})
 		}
 	}
@@ -203,6 +232,33 @@ func (b *Builder) SSHKeyGen(keypath string) *Builder {
 	return b
 }
 
+// TLSCAGen appends a TLSCAGen task to the current task collection
+func (b *Builder) TLSCAGen(clusterName string) *Builder {
+	b.tasks = append(b.tasks, &TLSCAGen{
+		clusterName: clusterName,
+	})
+	return b
+}
+
+// TLSRotate appends a TLSRotate task to the current task collection
+func (b *Builder) TLSRotate(clusterName string, ca bool) *Builder {
+	b.tasks = append(b.tasks, &TLSRotate{
+		clusterName: clusterName,
+		ca:          ca,
+	})
+	return b
+}
+
+// TLSCert appends a TLSCert task to the current task collection
+func (b *Builder) TLSCert(clusterName string, inst meta.Instance, deployDir string) *Builder {
+	b.tasks = append(b.tasks, &TLSCert{
+		clusterName: clusterName,
+		instance:    inst,
+		deployDir:   deployDir,
+	})
+	return b
+}
+
 // SSHKeySet appends a SSHKeySet task to the current task collection
 func (b *Builder) SSHKeySet(privKeyPath, pubKeyPath string) *Builder {
 	b.tasks = append(b.tasks, &SSHKeySet{
@@ -286,6 +342,16 @@ func (b *Builder) Sysctl(host, key, val string) *Builder {
 	return b
 }
 
+// Firewall changes the rules of the host firewall
+func (b *Builder) Firewall(host string, allow, remove []module.FirewallRule) *Builder {
+	b.tasks = append(b.tasks, &Firewall{
+		host:   host,
+		allow:  allow,
+		remove: remove,
+	})
+	return b
+}
+
 // Limit set a system limit
 func (b *Builder) Limit(host, domain, limit, item, value string) *Builder {
 	b.tasks = append(b.tasks, &Limit{
@@ -316,6 +382,12 @@ func (b *Builder) Parallel(tasks ...Task) *Builder {
 	return b
 }
 
+// Graph appends a task executing the tasks in g by their dependencies
+func (b *Builder) Graph(g *Graph) *Builder {
+	b.tasks = append(b.tasks, g)
+	return b
+}
+
 // Serial appends the tasks to the tail of queue
 func (b *Builder) Serial(tasks ...Task) *Builder {
 	b.tasks = append(b.tasks, tasks...)
@@ -344,6 +416,13 @@ func (b *Builder) ParallelStep(prefix string, tasks ...*StepDisplay) *Builder {
 	return b
 }
 
+// GraphStep appends a new GraphStepDisplay task, which will print multi line progress for the
+// tasks in g. The tasks in g to be displayed must be a StepDisplay task.
+func (b *Builder) GraphStep(prefix string, g *Graph) *Builder {
+	b.tasks = append(b.tasks, newGraphStepDisplay(prefix, g))
+	return b
+}
+
 // BuildAsStep returns a task that is wrapped by a StepDisplay. The task will print single line progress.
 func (b *Builder) BuildAsStep(prefix string) *StepDisplay {
 	inner := b.Build()
@@ -86,8 +86,7 @@ func (c *CheckSys) Execute(ctx *Context) error {
 		results = append(
 			results,
 			operator.CheckServices(e, c.host, "irqbalance", false),
-			// FIXME: set firewalld rules in deploy, and not disabling it anymore
-			operator.CheckServices(e, c.host, "firewalld", true),
+			operator.CheckFirewall(e, c.host, c.topo),
 		)
 		ctx.SetCheckResults(c.host, results)
 	case CheckTypePackage: // check if a command present, and if a package installed
@@ -131,9 +130,10 @@ func (c *CheckSys) Execute(ctx *Context) error {
 	return nil
 }
 
-// Rollback implements the Task interface
+// Rollback implements the Task interface, there is nothing to undo as the
+// checks change nothing on the host
 func (c *CheckSys) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	return nil
 }
 
 // String implements the fmt.Stringer interface
@@ -0,0 +1,159 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+// Checkpoint records the tasks done by an operation to a file, one task per
+// line, so that the operation can be resumed from the failed task instead of
+// starting over. A task is identified by its String(), which contains the host
+// it operates on. The first line is the digest of what the operation is run
+// with, e.g: the topology and version, as the tasks done with others, e.g: the
+// configs generated, can't be skipped.
+type Checkpoint struct {
+	mu     sync.Mutex
+	path   string
+	digest string
+	done   map[string]struct{}
+}
+
+// checkpointHeader is the first line of the checkpoint file
+type checkpointHeader struct {
+	Digest string `json:"digest"`
+}
+
+// NewCheckpoint creates the checkpoint saved in path for the operation run with
+// the digest. The tasks recorded by the last run of the operation are skipped
+// if resume is true and it's run with the same digest, otherwise they are
+// cleared and all tasks are executed.
+func NewCheckpoint(path, digest string, resume bool) (*Checkpoint, error) {
+	cp := &Checkpoint{
+		path:   path,
+		digest: digest,
+		done:   make(map[string]struct{}),
+	}
+	if !resume {
+		return cp, cp.Remove()
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to open checkpoint %s", path)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	var header checkpointHeader
+	if scanner.Scan() {
+		_ = json.Unmarshal(scanner.Bytes(), &header)
+	}
+	if header.Digest != digest {
+		log.Warnf("The topology or version is changed since the last run, the steps done by it are not skipped")
+		return cp, cp.Remove()
+	}
+	for scanner.Scan() {
+		var key string
+		// the last line may be incomplete if the process was killed
+		if err := json.Unmarshal(scanner.Bytes(), &key); err != nil {
+			continue
+		}
+		cp.done[key] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Annotatef(err, "failed to read checkpoint %s", path)
+	}
+	return cp, nil
+}
+
+// Len returns the number of the tasks done
+func (cp *Checkpoint) Len() int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return len(cp.done)
+}
+
+// Remove deletes the checkpoint file, it should be called when the operation
+// is done successfully.
+func (cp *Checkpoint) Remove() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.done = make(map[string]struct{})
+	if err := os.Remove(cp.path); err != nil && !os.IsNotExist(err) {
+		return errors.Annotatef(err, "failed to remove checkpoint %s", cp.path)
+	}
+	return nil
+}
+
+func (cp *Checkpoint) isDone(key string) bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	_, ok := cp.done[key]
+	return ok
+}
+
+// record appends the key of a task to the checkpoint file
+func (cp *Checkpoint) record(key string) error {
+	line, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(cp.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(cp.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Annotatef(err, "failed to open checkpoint %s", cp.path)
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err == nil && fi.Size() == 0 {
+		header, err := json.Marshal(checkpointHeader{Digest: cp.digest})
+		if err != nil {
+			return err
+		}
+		line = append(append(header, '\n'), line...)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return errors.Annotatef(err, "failed to write checkpoint %s", cp.path)
+	}
+	cp.done[key] = struct{}{}
+	return nil
+}
+
+// isCheckpointable returns whether the task is recorded to the checkpoint, the
+// tasks setting up the context for the following tasks, e.g: RootSSH, or cheap
+// to be executed again are always executed.
+func isCheckpointable(t Task) bool {
+	switch t.(type) {
+	case *Downloader, *EnvInit, *Mkdir, *CopyComponent, *InstallPackage,
+		*BackupComponent, *InitConfig, *ScaleConfig, *MonitoredConfig, *TLSCert, *Firewall:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap/check"
+)
+
+func TestTask(t *testing.T) { check.TestingT(t) }
+
+type checkpointSuite struct{}
+
+var _ = check.Suite(&checkpointSuite{})
+
+func (s *checkpointSuite) TestResume(c *check.C) {
+	path := filepath.Join(c.MkDir(), "checkpoint", "deploy")
+
+	cp, err := NewCheckpoint(path, "digest", true)
+	c.Assert(err, check.IsNil)
+	c.Assert(cp.Len(), check.Equals, 0)
+	c.Assert(cp.record("task-1"), check.IsNil)
+	c.Assert(cp.record("task-2"), check.IsNil)
+	c.Assert(cp.Len(), check.Equals, 2)
+
+	cp, err = NewCheckpoint(path, "digest", true)
+	c.Assert(err, check.IsNil)
+	c.Assert(cp.Len(), check.Equals, 2)
+	c.Assert(cp.isDone("task-1"), check.IsTrue)
+	c.Assert(cp.isDone("task-2"), check.IsTrue)
+	c.Assert(cp.isDone("task-3"), check.IsFalse)
+
+	// the tasks done are cleared if it's not resumed
+	cp, err = NewCheckpoint(path, "digest", false)
+	c.Assert(err, check.IsNil)
+	c.Assert(cp.Len(), check.Equals, 0)
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), check.IsTrue)
+}
+
+func (s *checkpointSuite) TestDigestChanged(c *check.C) {
+	path := filepath.Join(c.MkDir(), "deploy")
+
+	cp, err := NewCheckpoint(path, "digest", false)
+	c.Assert(err, check.IsNil)
+	c.Assert(cp.record("task-1"), check.IsNil)
+
+	cp, err = NewCheckpoint(path, "changed", true)
+	c.Assert(err, check.IsNil)
+	c.Assert(cp.Len(), check.Equals, 0)
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), check.IsTrue)
+
+	// the new digest is saved by the next run
+	c.Assert(cp.record("task-2"), check.IsNil)
+	cp, err = NewCheckpoint(path, "changed", true)
+	c.Assert(err, check.IsNil)
+	c.Assert(cp.Len(), check.Equals, 1)
+	c.Assert(cp.isDone("task-2"), check.IsTrue)
+}
+
+func (s *checkpointSuite) TestIncompleteFile(c *check.C) {
+	path := filepath.Join(c.MkDir(), "deploy")
+
+	// the file without the header is not resumed
+	err := ioutil.WriteFile(path, []byte("\"task-1\"\n"), 0644)
+	c.Assert(err, check.IsNil)
+	cp, err := NewCheckpoint(path, "digest", true)
+	c.Assert(err, check.IsNil)
+	c.Assert(cp.Len(), check.Equals, 0)
+
+	// the last line is truncated when the process was killed
+	err = ioutil.WriteFile(path, []byte("{\"digest\":\"digest\"}\n\"task-1\"\n\"tas"), 0644)
+	c.Assert(err, check.IsNil)
+	cp, err = NewCheckpoint(path, "digest", true)
+	c.Assert(err, check.IsNil)
+	c.Assert(cp.Len(), check.Equals, 1)
+	c.Assert(cp.isDone("task-1"), check.IsTrue)
+}
+
+func (s *checkpointSuite) TestSkipTasksDone(c *check.C) {
+	path := filepath.Join(c.MkDir(), "deploy")
+	run := func(digest string) []executor.Action {
+		r := executor.NewRecorder()
+		ctx := NewContext()
+		defer ctx.Close()
+		ctx.SetExecutor("127.0.0.1", executor.NewRecordingExecutor(executor.SSHConfig{Host: "127.0.0.1"}, false, r))
+		cp, err := NewCheckpoint(path, digest, true)
+		c.Assert(err, check.IsNil)
+		ctx.Checkpoint = cp
+
+		t := NewBuilder().
+			Mkdir("tidb", "127.0.0.1", "/data/a").
+			Mkdir("tidb", "127.0.0.1", "/data/b").
+			Build()
+		c.Assert(t.Execute(ctx), check.IsNil)
+		return r.Actions("127.0.0.1")
+	}
+
+	c.Assert(run("digest"), check.HasLen, 4)
+	c.Assert(run("digest"), check.HasLen, 0)
+	c.Assert(run("changed"), check.HasLen, 4)
+}
@@ -25,6 +25,7 @@ import (
 func (ctx *Context) SetSSHKeySet(privateKeyPath string, publicKeyPath string) error {
 	ctx.PrivateKeyPath = privateKeyPath
 	ctx.PublicKeyPath = publicKeyPath
+	ctx.KnownHosts = newKnownHosts(privateKeyPath)
 	return nil
 }
 
@@ -42,9 +43,16 @@ func (ctx *Context) SetClusterSSH(topo meta.Specification, deployUser string, ss
 				KeyFile: ctx.PrivateKeyPath,
 				User:    deployUser,
 				Timeout: time.Second * time.Duration(sshTimeout),
+				Proxy:   proxyConfig(in.GetSSHProxy(), sshTimeout),
+
+				KnownHosts:   ctx.KnownHosts,
+				SudoPassword: ctx.DeploySudoPassword,
 			}
 
-			e := executor.NewSSHExecutor(cf, false /* sudo */)
+			e, err := ctx.newExecutor(topo.GetGlobalOptions().SSHType, false /* sudo */, cf)
+			if err != nil {
+				return err
+			}
 			ctx.SetExecutor(in.GetHost(), e)
 		}
 	}
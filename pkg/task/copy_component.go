@@ -28,6 +28,8 @@ type CopyComponent struct {
 	version   string
 	host      string
 	dstDir    string
+
+	install *InstallPackage
 }
 
 // Execute implements the Task interface
@@ -37,18 +39,21 @@ func (c *CopyComponent) Execute(ctx *Context) error {
 	fileName := fmt.Sprintf("%s-%s-%s.tar.gz", resName, c.os, c.arch)
 	srcPath := meta.ProfilePath(meta.TiOpsPackageCacheDir, fileName)
 
-	install := &InstallPackage{
+	c.install = &InstallPackage{
 		srcPath: srcPath,
 		host:    c.host,
 		dstDir:  c.dstDir,
 	}
 
-	return install.Execute(ctx)
+	return c.install.Execute(ctx)
 }
 
 // Rollback implements the Task interface
 func (c *CopyComponent) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	if c.install == nil {
+		return nil
+	}
+	return c.install.Rollback(ctx)
 }
 
 // String implements the fmt.Stringer interface
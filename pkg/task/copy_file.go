@@ -15,7 +15,9 @@ package task
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap/errors"
 )
 
@@ -25,6 +27,8 @@ type CopyFile struct {
 	dst      string
 	remote   string
 	download bool
+
+	exec executor.TiOpsExecutor
 }
 
 // Execute implements the Task interface
@@ -34,6 +38,7 @@ func (c *CopyFile) Execute(ctx *Context) error {
 		return ErrNoExecutor
 	}
 
+	c.exec = e
 	err := e.Transfer(c.src, c.dst, c.download)
 	if err != nil {
 		return errors.Annotate(err, "failed to transfer file")
@@ -42,9 +47,19 @@ func (c *CopyFile) Execute(ctx *Context) error {
 	return nil
 }
 
-// Rollback implements the Task interface
+// Rollback implements the Task interface, the copied file is removed
 func (c *CopyFile) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	if c.exec == nil {
+		return nil
+	}
+	if c.download {
+		if err := os.Remove(c.dst); err != nil && !os.IsNotExist(err) {
+			return errors.Trace(err)
+		}
+		return nil
+	}
+	_, _, err := c.exec.Execute(fmt.Sprintf("rm -f %s", c.dst), false)
+	return errors.Trace(err)
 }
 
 // String implements the fmt.Stringer interface
@@ -39,7 +39,11 @@ func NewDownloader(component string, os string, arch string, version string) *Do
 }
 
 // Execute implements the Task interface
-func (d *Downloader) Execute(_ *Context) error {
+func (d *Downloader) Execute(ctx *Context) error {
+	if ctx.DryRun() {
+		ctx.Recorder.Note("", "download %s:%s (%s/%s) if it's not cached", d.component, d.version, d.os, d.arch)
+		return nil
+	}
 	return operator.Download(d.component, d.os, d.arch, d.version)
 }
 
@@ -16,15 +16,22 @@ package task
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"strings"
 
 	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/module"
+	"github.com/pingcap/errors"
 )
 
 var (
 	errNSEnvInit               = errNS.NewSubNamespace("env_init")
 	errEnvInitSubCommandFailed = errNSEnvInit.NewType("sub_command_failed")
+	// ErrEnvInitSudoPasswordRequired is ErrEnvInitSudoPasswordRequired
+	ErrEnvInitSudoPasswordRequired = errNSEnvInit.NewType("sudo_password_required", errutil.ErrTraitPreCheck)
 	// ErrEnvInitFailed is ErrEnvInitFailed
 	ErrEnvInitFailed = errNSEnvInit.NewType("failed")
 	// SSH authorized_keys file
@@ -37,6 +44,13 @@ var (
 type EnvInit struct {
 	host       string
 	deployUser string
+
+	// what are done by the task, to be undone on rollback
+	exec           executor.TiOpsExecutor
+	userCreated    bool
+	sudoerAdded    bool
+	pubKey         string
+	authorizedKeys string // the file the public key is added to
 }
 
 // Execute implements the Task interface
@@ -49,15 +63,49 @@ func (e *EnvInit) execute(ctx *Context) error {
 		return ErrEnvInitFailed.Wrap(err, "Failed to initialize TiDB environment on remote host '%s'", e.host)
 	}
 
+	// the hosts requiring the login user to sudo with password are not
+	// supposed to allow the deploy user to sudo without password
+	password := ctx.DeploySudoPassword
+	if password == "" && ctx.SudoPassword != "" {
+		return ErrEnvInitSudoPasswordRequired.
+			New("The deploy user '%s' can't be allowed to sudo without password on '%s' as the sudo password is set", e.deployUser, e.host).
+			WithProperty(cliutil.SuggestionFromTemplate(`
+Please provide the sudo password of the deploy user with {{ColorKeyword}}--deploy-sudo-password{{ColorReset}}
+or {{ColorKeyword}}--deploy-sudo-password-file{{ColorReset}}, or set it in the environment variable
+{{ColorKeyword}}{{.Env}}{{ColorReset}}. It's set to the deploy user if the user is created.
+`, map[string]string{
+				"Env": cliutil.EnvNameDeploySudoPassword,
+			}))
+	}
+	if password != "" && ctx.DryRun() {
+		// keep the password out of the plan
+		password = "<sudo password of the deploy user>"
+	}
+
 	exec, found := ctx.GetExecutor(e.host)
 	if !found {
 		panic(ErrNoExecutor)
 	}
 
+	// find out whether the user and the sudoers file exist before
+	cmd := fmt.Sprintf(`id -u %[1]s > /dev/null 2>&1 && echo user; test -e /etc/sudoers.d/%[1]s && echo sudoer; true`, e.deployUser)
+	stdout, _, err := exec.Execute(cmd, true)
+	if err != nil {
+		return wrapError(err)
+	}
+	existing := make(map[string]bool)
+	for _, s := range strings.Fields(string(stdout)) {
+		existing[s] = true
+	}
+	e.exec = exec
+	e.userCreated = !ctx.DryRun() && !existing["user"]
+	e.sudoerAdded = !ctx.DryRun() && !existing["sudoer"]
+
 	um := module.NewUserModule(module.UserModuleConfig{
-		Action: module.UserActionAdd,
-		Name:   e.deployUser,
-		Sudoer: true,
+		Action:   module.UserActionAdd,
+		Name:     e.deployUser,
+		Sudoer:   true,
+		Password: password,
 	})
 
 	_, _, errx := um.Execute(exec)
@@ -67,11 +115,15 @@ func (e *EnvInit) execute(ctx *Context) error {
 
 	pubKey, err := ioutil.ReadFile(ctx.PublicKeyPath)
 	if err != nil {
-		return wrapError(err)
+		// the key is not generated in dry run
+		if !ctx.DryRun() || !os.IsNotExist(err) {
+			return wrapError(err)
+		}
+		pubKey = []byte("<public key of " + ctx.PublicKeyPath + ">")
 	}
 
 	// Authorize
-	cmd := `su - ` + e.deployUser + ` -c 'test -d ~/.ssh || mkdir -p ~/.ssh && chmod 700 ~/.ssh'`
+	cmd = `su - ` + e.deployUser + ` -c 'test -d ~/.ssh || mkdir -p ~/.ssh && chmod 700 ~/.ssh'`
 	_, _, err = exec.Execute(cmd, true)
 	if err != nil {
 		return wrapError(errEnvInitSubCommandFailed.
@@ -80,12 +132,12 @@ func (e *EnvInit) execute(ctx *Context) error {
 
 	// detect if custom path of authorized keys file is set
 	// NOTE: we do not yet support:
-	//   - custom config for user (~/.ssh/config)
+	//   - custom config for user (~/.ssh/config) on the remote host
 	//   - sshd started with custom config (other than /etc/ssh/sshd_config)
 	//   - ssh server implementations other than OpenSSH (such as dropbear)
 	sshAuthorizedKeys := defaultSSHAuthorizedKeys
 	cmd = "grep -Ev '^\\s*#|^\\s*$' /etc/ssh/sshd_config"
-	stdout, _, _ := exec.Execute(cmd, true) // error ignored as we have default value
+	stdout, _, _ = exec.Execute(cmd, true) // error ignored as we have default value
 	for _, line := range strings.Split(string(stdout), "\n") {
 		if !strings.Contains(line, "AuthorizedKeysFile") {
 			continue
@@ -100,21 +152,55 @@ func (e *EnvInit) execute(ctx *Context) error {
 		sshAuthorizedKeys = fmt.Sprintf("~/%s", sshAuthorizedKeys)
 	}
 
+	// the key is passed via stdin to keep it out of the command line
 	pk := strings.TrimSpace(string(pubKey))
-	cmd = fmt.Sprintf(`su - %[1]s -c 'grep $(echo %[2]s) %[3]s || echo %[2]s >> %[3]s && chmod 600 %[3]s'`,
-		e.deployUser, pk, sshAuthorizedKeys)
-	_, _, err = exec.Execute(cmd, true)
+	cmd = fmt.Sprintf(`su - %[1]s -c 'key=$(cat); grep -qxF -- "$key" %[2]s || { echo "$key" >> %[2]s && echo added; } && chmod 600 %[2]s'`,
+		e.deployUser, sshAuthorizedKeys)
+	stdout, _, err = exec.ExecuteWithStdin(cmd, strings.NewReader(pk), true)
 	if err != nil {
 		return wrapError(errEnvInitSubCommandFailed.
 			Wrap(err, "Failed to write public keys to '%s' for user '%s'", sshAuthorizedKeys, e.deployUser))
 	}
+	// the output of the login shell may come before
+	e.pubKey, e.authorizedKeys = "", ""
+	if strings.HasSuffix(strings.TrimSpace(string(stdout)), "added") {
+		e.pubKey = pk
+		e.authorizedKeys = sshAuthorizedKeys
+	}
 
 	return nil
 }
 
-// Rollback implements the Task interface
+// Rollback implements the Task interface, the user is deleted if it's created
+// by the task, otherwise only the public key added is removed.
 func (e *EnvInit) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	if e.exec == nil {
+		return nil
+	}
+
+	if e.sudoerAdded {
+		if _, _, err := e.exec.Execute(fmt.Sprintf("rm -f /etc/sudoers.d/%s", e.deployUser), true); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	if e.userCreated {
+		um := module.NewUserModule(module.UserModuleConfig{
+			Action: module.UserActionDel,
+			Name:   e.deployUser,
+		})
+		_, _, err := um.Execute(e.exec)
+		return err
+	}
+
+	if e.pubKey != "" {
+		cmd := fmt.Sprintf(`su - %[1]s -c 'key=$(cat); grep -vxF -- "$key" %[2]s > %[2]s.tmp; mv %[2]s.tmp %[2]s && chmod 600 %[2]s'`,
+			e.deployUser, e.authorizedKeys)
+		if _, _, err := e.exec.ExecuteWithStdin(cmd, strings.NewReader(e.pubKey), true); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
 }
 
 // String implements the fmt.Stringer interface
@@ -0,0 +1,71 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap/check"
+)
+
+type envInitSuite struct{}
+
+var _ = check.Suite(&envInitSuite{})
+
+// dryRunEnvInit runs EnvInit in dry run and returns the command adding the user
+func dryRunEnvInit(c *check.C, sudoPassword, deploySudoPassword string) (string, error) {
+	ctx := NewContext()
+	defer ctx.Close()
+	ctx.SetDryRun(c.MkDir())
+	ctx.PublicKeyPath = filepath.Join(c.MkDir(), "id_rsa.pub")
+	ctx.SudoPassword = sudoPassword
+	ctx.DeploySudoPassword = deploySudoPassword
+	ctx.SetExecutor("127.0.0.1", executor.NewRecordingExecutor(executor.SSHConfig{Host: "127.0.0.1"}, false, ctx.Recorder))
+
+	t := &EnvInit{host: "127.0.0.1", deployUser: "tidb"}
+	if err := t.Execute(ctx); err != nil {
+		return "", err
+	}
+	for _, a := range ctx.Recorder.Actions("127.0.0.1") {
+		if strings.Contains(a.Detail, "useradd") {
+			return a.Detail, nil
+		}
+	}
+	c.Fatal("no user is added")
+	return "", nil
+}
+
+func (s *envInitSuite) TestSudoWithoutPassword(c *check.C) {
+	cmd, err := dryRunEnvInit(c, "", "")
+	c.Assert(err, check.IsNil)
+	c.Assert(cmd, check.Matches, "(?s).*tidb ALL=\\(ALL\\) NOPASSWD:ALL.*")
+	c.Assert(cmd, check.Not(check.Matches), "(?s).*chpasswd.*")
+}
+
+func (s *envInitSuite) TestSudoWithPassword(c *check.C) {
+	cmd, err := dryRunEnvInit(c, "login-secret", "deploy-secret")
+	c.Assert(err, check.IsNil)
+	c.Assert(cmd, check.Matches, "(?s).*chpasswd.*tidb ALL=\\(ALL\\) ALL\n.*")
+	// the password is not put in the plan
+	c.Assert(strings.Contains(cmd, "deploy-secret"), check.IsFalse)
+	c.Assert(strings.Contains(cmd, "login-secret"), check.IsFalse)
+}
+
+func (s *envInitSuite) TestDeploySudoPasswordRequired(c *check.C) {
+	_, err := dryRunEnvInit(c, "login-secret", "")
+	c.Assert(errorx.IsOfType(errorx.Cast(err), ErrEnvInitSudoPasswordRequired), check.IsTrue)
+}
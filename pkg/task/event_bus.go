@@ -49,9 +49,10 @@ func (ev *EventBus) PublishTaskBegin(task Task) {
 }
 
 // PublishTaskFinish publishes a TaskFinish event. This should be called only by Parallel or Serial.
+// The handlers get a pointer to err, because the bus is not able to pass a nil error.
 func (ev *EventBus) PublishTaskFinish(task Task, err error) {
 	zap.L().Debug("TaskFinish", zap.String("task", task.String()), zap.Error(err))
-	ev.eventBus.Publish(string(EventTaskFinish), task, err)
+	ev.eventBus.Publish(string(EventTaskFinish), task, &err)
 }
 
 // PublishTaskProgress publishes a TaskProgress event.
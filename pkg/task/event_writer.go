@@ -0,0 +1,136 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// status of the tasks in events
+const (
+	EventStatusRunning = "running"
+	EventStatusSuccess = "success"
+	EventStatusFailed  = "failed"
+)
+
+// Event is a task event written by EventWriter as a line of JSON
+type Event struct {
+	Kind      EventKind  `json:"event"`
+	Time      time.Time  `json:"time"`
+	Task      string     `json:"task"`           // the description of the task, i.e: its String()
+	Host      string     `json:"host,omitempty"` // empty if the task is not on a single host
+	Status    string     `json:"status"`
+	BeginTime *time.Time `json:"begin_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Progress  string     `json:"progress,omitempty"`
+}
+
+// EventWriter writes the events of the tasks as JSON lines, so that the
+// operations can be watched by other programs. Only the events of the leaf
+// tasks are written, i.e: Serial, Parallel and the steps are omitted. It can be
+// shared by multiple contexts.
+type EventWriter struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	begins map[Task]time.Time
+}
+
+// NewEventWriter creates an EventWriter writing to w
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{
+		enc:    json.NewEncoder(w),
+		begins: make(map[Task]time.Time),
+	}
+}
+
+// SetEventWriter makes the events of the tasks executed with ctx written by w
+func (ctx *Context) SetEventWriter(w *EventWriter) {
+	ctx.ev.Subscribe(EventTaskBegin, w.handleTaskBegin)
+	ctx.ev.Subscribe(EventTaskFinish, w.handleTaskFinish)
+	ctx.ev.Subscribe(EventTaskProgress, w.handleTaskProgress)
+}
+
+func (w *EventWriter) handleTaskBegin(t Task) {
+	if isDisplayTask(t) {
+		return
+	}
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.begins[t] = now
+	w.write(&Event{
+		Kind:      EventTaskBegin,
+		Time:      now,
+		Task:      t.String(),
+		Host:      taskHost(t),
+		Status:    EventStatusRunning,
+		BeginTime: &now,
+	})
+}
+
+func (w *EventWriter) handleTaskFinish(t Task, err *error) {
+	if isDisplayTask(t) {
+		return
+	}
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	e := &Event{
+		Kind:    EventTaskFinish,
+		Time:    now,
+		Task:    t.String(),
+		Host:    taskHost(t),
+		Status:  EventStatusSuccess,
+		EndTime: &now,
+	}
+	if begin, ok := w.begins[t]; ok {
+		e.BeginTime = &begin
+		delete(w.begins, t)
+	}
+	if *err != nil {
+		e.Status = EventStatusFailed
+		e.Error = (*err).Error()
+	}
+	w.write(e)
+}
+
+func (w *EventWriter) handleTaskProgress(t Task, p string) {
+	if isDisplayTask(t) {
+		return
+	}
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	e := &Event{
+		Kind:     EventTaskProgress,
+		Time:     now,
+		Task:     t.String(),
+		Host:     taskHost(t),
+		Status:   EventStatusRunning,
+		Progress: p,
+	}
+	if begin, ok := w.begins[t]; ok {
+		e.BeginTime = &begin
+	}
+	w.write(e)
+}
+
+// write writes e as a line, the events are best-effort so the error is ignored
+func (w *EventWriter) write(e *Event) {
+	_ = w.enc.Encode(e)
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/pingcap/check"
+)
+
+type eventWriterSuite struct{}
+
+var _ = check.Suite(&eventWriterSuite{})
+
+func (s *eventWriterSuite) TestEvents(c *check.C) {
+	buf := new(bytes.Buffer)
+	ctx := newFakeContext(&fakeExecutor{})
+	defer ctx.Close()
+	ctx.Concurrency = 1
+	ctx.SetEventWriter(NewEventWriter(buf))
+
+	var a *Func
+	a = NewFunc("a", func(ctx *Context) error {
+		ctx.ev.PublishTaskProgress(a, "half done")
+		return nil
+	})
+	backup := &BackupComponent{component: "tikv", fromVer: "v4.0.0", host: "127.0.0.1", deployDir: "/deploy"}
+	serial := &Serial{inner: []Task{
+		newStepDisplay("+ Step", NewParallel(false, a, backup)).SetHidden(true),
+		NewFunc("c", func(ctx *Context) error { return errors.New("c failed") }),
+	}}
+	c.Assert(serial.Execute(ctx), check.NotNil)
+
+	var events []Event
+	dec := json.NewDecoder(buf)
+	for {
+		var e Event
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, check.IsNil)
+		events = append(events, e)
+	}
+
+	// the display tasks are omitted
+	type brief struct {
+		kind   EventKind
+		task   string
+		host   string
+		status string
+	}
+	var briefs []brief
+	for _, e := range events {
+		briefs = append(briefs, brief{e.Kind, e.Task, e.Host, e.Status})
+	}
+	c.Assert(briefs, check.DeepEquals, []brief{
+		{EventTaskBegin, "a", "", EventStatusRunning},
+		{EventTaskProgress, "a", "", EventStatusRunning},
+		{EventTaskFinish, "a", "", EventStatusSuccess},
+		{EventTaskBegin, backup.String(), "127.0.0.1", EventStatusRunning},
+		{EventTaskFinish, backup.String(), "127.0.0.1", EventStatusSuccess},
+		{EventTaskBegin, "c", "", EventStatusRunning},
+		{EventTaskFinish, "c", "", EventStatusFailed},
+	})
+
+	// the begin time of a task is carried by its later events
+	begin, progress, finish := events[0], events[1], events[2]
+	c.Assert(begin.BeginTime, check.NotNil)
+	c.Assert(begin.BeginTime.Equal(begin.Time), check.IsTrue)
+	c.Assert(begin.EndTime, check.IsNil)
+	c.Assert(progress.Progress, check.Equals, "half done")
+	c.Assert(progress.BeginTime.Equal(*begin.BeginTime), check.IsTrue)
+	c.Assert(finish.BeginTime.Equal(*begin.BeginTime), check.IsTrue)
+	c.Assert(finish.EndTime.Equal(finish.Time), check.IsTrue)
+	c.Assert(finish.EndTime.Before(*finish.BeginTime), check.IsFalse)
+	c.Assert(finish.Error, check.Equals, "")
+
+	failed := events[6]
+	c.Assert(failed.Error, check.Equals, "c failed")
+	c.Assert(failed.BeginTime.Equal(*events[5].BeginTime), check.IsTrue)
+}
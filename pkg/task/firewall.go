@@ -0,0 +1,67 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/module"
+	"github.com/pingcap/errors"
+)
+
+// Firewall changes the rules of the host firewall, to open the ports used by
+// the instances on the host and close the ones no longer used
+type Firewall struct {
+	host   string
+	allow  []module.FirewallRule
+	remove []module.FirewallRule
+}
+
+// Execute implements the Task interface
+func (f *Firewall) Execute(ctx *Context) error {
+	return f.apply(ctx, f.allow, f.remove)
+}
+
+// Rollback implements the Task interface, the added rules are removed and the
+// removed rules are added back
+func (f *Firewall) Rollback(ctx *Context) error {
+	return f.apply(ctx, f.remove, f.allow)
+}
+
+func (f *Firewall) apply(ctx *Context, allow, remove []module.FirewallRule) error {
+	if len(allow) == 0 && len(remove) == 0 {
+		return nil
+	}
+
+	e, ok := ctx.GetExecutor(f.host)
+	if !ok {
+		return ErrNoExecutor
+	}
+
+	m := module.NewFirewallModule(module.FirewallModuleConfig{
+		Allow:  allow,
+		Remove: remove,
+	})
+	stdout, stderr, err := m.Execute(e)
+	ctx.SetOutputs(f.host, stdout, stderr)
+	if err != nil {
+		return errors.Annotatef(err, "failed to change the firewall of %s: %s", f.host, stderr)
+	}
+	return nil
+}
+
+// String implements the fmt.Stringer interface
+func (f *Firewall) String() string {
+	return fmt.Sprintf("Firewall: host=%s, allow=%v, remove=%v", f.host, f.allow, f.remove)
+}
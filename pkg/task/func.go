@@ -15,8 +15,9 @@ package task
 
 // Func wrap a closure.
 type Func struct {
-	name string
-	fn   func(ctx *Context) error
+	name     string
+	fn       func(ctx *Context) error
+	rollback func(ctx *Context) error
 }
 
 // NewFunc create a Func task
@@ -27,14 +28,23 @@ func NewFunc(name string, fn func(ctx *Context) error) *Func {
 	}
 }
 
+// WithRollback sets the closure to undo fn on rollback
+func (m *Func) WithRollback(rollback func(ctx *Context) error) *Func {
+	m.rollback = rollback
+	return m
+}
+
 // Execute implements the Task interface
 func (m *Func) Execute(ctx *Context) error {
 	return m.fn(ctx)
 }
 
 // Rollback implements the Task interface
-func (m *Func) Rollback(_ *Context) error {
-	return ErrUnsupportedRollback
+func (m *Func) Rollback(ctx *Context) error {
+	if m.rollback == nil {
+		return ErrUnsupportedRollback
+	}
+	return m.rollback(ctx)
 }
 
 // String implements the fmt.Stringer interface
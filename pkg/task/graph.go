@@ -0,0 +1,171 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+)
+
+// Graph executes tasks by their dependencies, a task is started as soon as all
+// the tasks it depends on are done, so that independent chains of tasks, e.g:
+// the steps on different hosts, don't wait for each other. The tasks take the
+// slots of ctx like the ones of Parallel.
+type Graph struct {
+	hideDetailDisplay bool
+	nodes             []*graphNode
+	index             map[Task]int
+}
+
+type graphNode struct {
+	task Task
+	deps []int
+}
+
+type graphResult struct {
+	node int
+	err  error
+}
+
+// NewGraph creates an empty Graph
+func NewGraph() *Graph {
+	return &Graph{index: make(map[Task]int)}
+}
+
+// Add adds t to the graph, it's executed after all the tasks in deps, which
+// must have been added before. It returns t for chaining the dependencies.
+func (g *Graph) Add(t Task, deps ...Task) Task {
+	if _, ok := g.index[t]; ok {
+		panic(fmt.Sprintf("task added to the graph twice: %s", t))
+	}
+	node := &graphNode{task: t}
+	for _, dep := range deps {
+		i, ok := g.index[dep]
+		if !ok {
+			panic(fmt.Sprintf("dependency not added to the graph: %s", dep))
+		}
+		node.deps = append(node.deps, i)
+	}
+	g.index[t] = len(g.nodes)
+	g.nodes = append(g.nodes, node)
+	return t
+}
+
+// Len returns the number of tasks in the graph
+func (g *Graph) Len() int {
+	return len(g.nodes)
+}
+
+// Execute implements the Task interface. Once a task fails, no more tasks are
+// started, and the errors of all the failed tasks are returned after the
+// running ones are done.
+func (g *Graph) Execute(ctx *Context) error {
+	waiting := make([]int, len(g.nodes)) // number of the deps not done
+	dependents := make([][]int, len(g.nodes))
+	var ready []int
+	for i, node := range g.nodes {
+		waiting[i] = len(node.deps)
+		for _, dep := range node.deps {
+			dependents[dep] = append(dependents[dep], i)
+		}
+		if waiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	results := make(chan graphResult)
+	running := 0
+	var failed []Task
+	var failedErrs []error
+	for {
+		// start the ready tasks in order until one has to wait for a slot
+		var slots chan struct{}
+		for len(failed) == 0 && len(ready) > 0 {
+			if slots = ctx.taskSlots(g.nodes[ready[0]].task); slots != nil {
+				break
+			}
+			g.start(ctx, ready[0], results, func() {})
+			ready = ready[1:]
+			running++
+		}
+		if running == 0 && slots == nil {
+			break
+		}
+
+		// sending to the nil slots blocks forever
+		select {
+		case slots <- struct{}{}:
+			g.start(ctx, ready[0], results, func() { <-slots })
+			ready = ready[1:]
+			running++
+		case r := <-results:
+			running--
+			if r.err != nil {
+				failed = append(failed, g.nodes[r.node].task)
+				failedErrs = append(failedErrs, r.err)
+				continue
+			}
+			for _, i := range dependents[r.node] {
+				if waiting[i]--; waiting[i] == 0 {
+					ready = append(ready, i)
+				}
+			}
+		}
+	}
+	return mergeTaskErrors(failed, failedErrs)
+}
+
+// start executes the task of node i in a goroutine and sends the result to
+// results, the slot it takes is released by release.
+func (g *Graph) start(ctx *Context, i int, results chan<- graphResult, release func()) {
+	go func() {
+		t := g.nodes[i].task
+		if !isDisplayTask(t) && !g.hideDetailDisplay {
+			log.Infof("+ [ Graph ] - %s", t.String())
+		}
+		err := executeTask(ctx, t)
+		release()
+		results <- graphResult{node: i, err: err}
+	}()
+}
+
+// Rollback implements the Task interface, the executed tasks are rolled back
+// in the reverse order of being added, so a task is always rolled back before
+// the ones it depends on. It goes on when a task fails to roll back.
+func (g *Graph) Rollback(ctx *Context) error {
+	var failed []Task
+	var failedErrs []error
+	for i := len(g.nodes) - 1; i >= 0; i-- {
+		t := g.nodes[i].task
+		release := ctx.acquireSlot(t)
+		err := rollbackTask(ctx, t)
+		release()
+		if err != nil {
+			failed = append(failed, t)
+			failedErrs = append(failedErrs, err)
+		}
+	}
+	return mergeTaskErrors(failed, failedErrs)
+}
+
+// String implements the fmt.Stringer interface
+func (g *Graph) String() string {
+	var ss []string
+	for _, node := range g.nodes {
+		ss = append(ss, node.task.String())
+	}
+	return strings.Join(ss, "\n")
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pingcap/check"
+)
+
+type graphSuite struct{}
+
+var _ = check.Suite(&graphSuite{})
+
+// graphRecorder records the order in which the tasks are executed and rolled
+// back, along with the max number of them running at the same time
+type graphRecorder struct {
+	sync.Mutex
+	executed   []string
+	rolledBack []string
+	running    int
+	maxRunning int
+}
+
+func (r *graphRecorder) task(name string, d time.Duration, err error) *Func {
+	return NewFunc(name, func(ctx *Context) error {
+		r.Lock()
+		r.running++
+		if r.running > r.maxRunning {
+			r.maxRunning = r.running
+		}
+		r.Unlock()
+
+		time.Sleep(d)
+
+		r.Lock()
+		defer r.Unlock()
+		r.running--
+		r.executed = append(r.executed, name)
+		return err
+	}).WithRollback(func(ctx *Context) error {
+		r.Lock()
+		defer r.Unlock()
+		r.rolledBack = append(r.rolledBack, name)
+		return nil
+	})
+}
+
+func (s *graphSuite) TestDependencies(c *check.C) {
+	r := &graphRecorder{}
+	g := NewGraph()
+	a := g.Add(r.task("a", 20*time.Millisecond, nil))
+	b := g.Add(r.task("b", 0, nil), a)
+	g.Add(r.task("c", 0, nil))
+	g.Add(r.task("d", 0, nil), a, b)
+
+	ctx := NewContext()
+	defer ctx.Close()
+	c.Assert(g.Execute(ctx), check.IsNil)
+	// c doesn't wait for a
+	c.Assert(r.executed, check.DeepEquals, []string{"c", "a", "b", "d"})
+
+	c.Assert(g.Rollback(ctx), check.IsNil)
+	c.Assert(r.rolledBack, check.DeepEquals, []string{"d", "c", "b", "a"})
+}
+
+func (s *graphSuite) TestConcurrency(c *check.C) {
+	r := &graphRecorder{}
+	g := NewGraph()
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		g.Add(r.task(name, 10*time.Millisecond, nil))
+	}
+
+	ctx := NewContext()
+	defer ctx.Close()
+	ctx.Concurrency = 2
+	c.Assert(g.Execute(ctx), check.IsNil)
+	c.Assert(r.executed, check.HasLen, 6)
+	c.Assert(r.maxRunning, check.Equals, 2)
+}
+
+func (s *graphSuite) TestStopOnFailure(c *check.C) {
+	r := &graphRecorder{}
+	g := NewGraph()
+	g.Add(r.task("fail", 0, errors.New("failed")))
+	slow := g.Add(r.task("slow", 50*time.Millisecond, nil))
+	g.Add(r.task("after-slow", 0, nil), slow)
+
+	ctx := NewContext()
+	defer ctx.Close()
+	err := g.Execute(ctx)
+	c.Assert(err, check.NotNil)
+	c.Assert(err, check.ErrorMatches, ".*failed.*")
+	// the running task is waited for, but no more tasks are started
+	c.Assert(r.executed, check.DeepEquals, []string{"fail", "slow"})
+
+	// only the executed tasks are rolled back
+	c.Assert(g.Rollback(ctx), check.IsNil)
+	c.Assert(r.rolledBack, check.DeepEquals, []string{"slow", "fail"})
+}
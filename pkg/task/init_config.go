@@ -29,6 +29,8 @@ type InitConfig struct {
 	instance       meta.Instance
 	deployUser     string
 	paths          meta.DirPaths
+
+	unit *systemdUnit
 }
 
 // Execute implements the Task interface
@@ -39,11 +41,19 @@ func (c *InitConfig) Execute(ctx *Context) error {
 		return ErrNoExecutor
 	}
 
-	if err := os.MkdirAll(c.paths.Cache, 0755); err != nil {
-		return errors.Annotatef(err, "create cache directory failed: %s", c.paths.Cache)
+	paths := c.paths
+	paths.Cache = ctx.cachePath(paths.Cache)
+	if err := os.MkdirAll(paths.Cache, 0755); err != nil {
+		return errors.Annotatef(err, "create cache directory failed: %s", paths.Cache)
+	}
+
+	unit, err := probeSystemdUnit(exec, c.instance.ServiceName())
+	if err != nil {
+		return err
 	}
+	c.unit = unit
 
-	err := c.instance.InitConfig(exec, c.clusterName, c.clusterVersion, c.deployUser, c.paths)
+	err = c.instance.InitConfig(exec, c.clusterName, c.clusterVersion, c.deployUser, paths)
 	if err != nil {
 		return errors.Annotatef(err, "init config failed: %s:%d", c.instance.GetHost(), c.instance.GetPort())
 	}
@@ -52,7 +62,7 @@ func (c *InitConfig) Execute(ctx *Context) error {
 
 // Rollback implements the Task interface
 func (c *InitConfig) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	return c.unit.rollback()
 }
 
 // String implements the fmt.Stringer interface
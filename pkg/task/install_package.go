@@ -18,6 +18,7 @@ import (
 	"path"
 	"path/filepath"
 
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap/errors"
 )
 
@@ -27,6 +28,9 @@ type InstallPackage struct {
 	srcPath string
 	host    string
 	dstDir  string
+
+	exec    executor.TiOpsExecutor
+	dstPath string
 }
 
 // Execute implements the Task interface
@@ -39,6 +43,8 @@ func (c *InstallPackage) Execute(ctx *Context) error {
 
 	dstDir := filepath.Join(c.dstDir, "bin")
 	dstPath := filepath.Join(dstDir, path.Base(c.srcPath))
+	c.exec = exec
+	c.dstPath = dstPath
 
 	err := exec.Transfer(c.srcPath, dstPath, false)
 	if err != nil {
@@ -54,9 +60,18 @@ func (c *InstallPackage) Execute(ctx *Context) error {
 	return nil
 }
 
-// Rollback implements the Task interface
+// Rollback implements the Task interface, it removes the package left if it's
+// not extracted. The extracted files are removed along with the directory by
+// the rollback of Mkdir, or overwritten by the rollback of BackupComponent.
 func (c *InstallPackage) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	if c.exec == nil {
+		return nil
+	}
+	_, stderr, err := c.exec.Execute(fmt.Sprintf("rm -f %s", c.dstPath), false)
+	if err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	return nil
 }
 
 // String implements the fmt.Stringer interface
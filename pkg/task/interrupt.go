@@ -0,0 +1,150 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+)
+
+var (
+	// ErrInterrupted means the operation is interrupted, e.g: by Ctrl-C
+	ErrInterrupted = errNS.NewType("interrupted")
+)
+
+// the operations run by the tasks are interrupted along with them
+var _ operator.Interruptible = &Context{}
+
+// cleanup is a step to be run before exiting if the operation is interrupted
+// or failed in the middle, e.g: removing the evict leader scheduler added to PD
+type cleanup struct {
+	name string
+	fn   func() error
+}
+
+type cleanupState struct {
+	sync.Mutex
+	nextID int
+	steps  map[int]cleanup
+}
+
+// SetContext sets the context.Context of the operation, no new task is
+// executed after it's done. The tasks being executed are not aborted, the
+// commands in flight are left to finish as stopping them in the middle may
+// leave the hosts broken, e.g: half copied files. Only the waits for the ports
+// of instances, which change nothing, are stopped.
+func (ctx *Context) SetContext(c context.Context) {
+	ctx.stdCtx = c
+}
+
+// Context returns the context.Context of the operation
+func (ctx *Context) Context() context.Context {
+	if ctx.stdCtx == nil {
+		return context.Background()
+	}
+	return ctx.stdCtx
+}
+
+// CancelOnInterrupt makes the operation interrupted by SIGINT (Ctrl-C) and
+// SIGTERM until the context is closed, so that the cleanup steps are run before
+// exiting. The process exits immediately if it's interrupted again, the local
+// commands in flight are not killed by the signal as they are in their own
+// process groups.
+func (ctx *Context) CancelOnInterrupt() {
+	c, cancel := context.WithCancel(ctx.Context())
+	ctx.SetContext(c)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	closed := make(chan struct{})
+	ctx.stopInterrupt = func() {
+		signal.Stop(sigCh)
+		close(closed)
+		cancel()
+	}
+
+	go func() {
+		select {
+		case <-sigCh:
+		case <-closed:
+			return
+		}
+		log.Warnf("Interrupted, waiting for the running steps to finish before exiting, press Ctrl-C again to exit immediately")
+		cancel()
+
+		select {
+		case <-sigCh:
+			log.Errorf("Interrupted again, exiting without cleaning up")
+			os.Exit(1)
+		case <-closed:
+		}
+	}()
+}
+
+// Interrupted returns an ErrInterrupted error if the context.Context of the
+// operation is done, or nil if it's not.
+func (ctx *Context) Interrupted() error {
+	if err := ctx.Context().Err(); err != nil {
+		return ErrInterrupted.Wrap(err, "The operation is interrupted")
+	}
+	return nil
+}
+
+// AddCleanup registers fn to be run when the context is closed, which undoes
+// the temporary changes made by the operation in case it's interrupted or
+// failed. The returned func unregisters fn, it should be called once the
+// changes are undone as usual.
+func (ctx *Context) AddCleanup(name string, fn func() error) (remove func()) {
+	ctx.cleanup.Lock()
+	defer ctx.cleanup.Unlock()
+	if ctx.cleanup.steps == nil {
+		ctx.cleanup.steps = make(map[int]cleanup)
+	}
+	id := ctx.cleanup.nextID
+	ctx.cleanup.nextID++
+	ctx.cleanup.steps[id] = cleanup{name: name, fn: fn}
+
+	return func() {
+		ctx.cleanup.Lock()
+		defer ctx.cleanup.Unlock()
+		delete(ctx.cleanup.steps, id)
+	}
+}
+
+// runCleanups runs the registered cleanup steps in reverse order, the errors
+// are logged so that all the steps are run.
+func (ctx *Context) runCleanups() {
+	ctx.cleanup.Lock()
+	steps := ctx.cleanup.steps
+	ctx.cleanup.steps = nil
+	n := ctx.cleanup.nextID
+	ctx.cleanup.Unlock()
+
+	for id := n - 1; id >= 0; id-- {
+		step, ok := steps[id]
+		if !ok {
+			continue
+		}
+		log.Infof("Cleaning up: %s", step.name)
+		if err := step.fn(); err != nil {
+			log.Errorf("Failed to clean up (%s): %s", step.name, err)
+		}
+	}
+}
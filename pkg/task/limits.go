@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap/errors"
 )
 
@@ -31,6 +32,9 @@ type Limit struct {
 	limit  string // limit type
 	item   string
 	value  string
+
+	exec     executor.TiOpsExecutor
+	oldLines string // the lines of the limit in the file before
 }
 
 // Execute implements the Task interface
@@ -40,15 +44,22 @@ func (l *Limit) Execute(ctx *Context) error {
 		return ErrNoExecutor
 	}
 
+	// keep the current lines to be restored on rollback
+	stdout, _, err := e.Execute(fmt.Sprintf("sed -n '/%s/p' %s 2>/dev/null; true", l.pattern(), limitsFilePath), true)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	l.oldLines = string(stdout)
+	l.exec = e
+
 	cmd := strings.Join([]string{
 		fmt.Sprintf("cp %s{,.bak} 2>/dev/null", limitsFilePath),
-		fmt.Sprintf("sed -i '/%s\\s*%s\\s*%s/d' %s 2>/dev/null",
-			l.domain, l.limit, l.item, limitsFilePath),
-		fmt.Sprintf("echo '%s    %s    %s    %s' >> %s",
-			l.domain, l.limit, l.item, l.value, limitsFilePath),
+		fmt.Sprintf("sed -i '/%s/d' %s 2>/dev/null", l.pattern(), limitsFilePath),
+		fmt.Sprintf("cat >> %s", limitsFilePath),
 	}, " && ")
 
-	stdout, stderr, err := e.Execute(cmd, true)
+	stdin := strings.NewReader(fmt.Sprintf("%s    %s    %s    %s\n", l.domain, l.limit, l.item, l.value))
+	stdout, stderr, err := e.ExecuteWithStdin(cmd, stdin, true)
 	ctx.SetOutputs(l.host, stdout, stderr)
 	if err != nil {
 		return errors.Trace(err)
@@ -57,9 +68,25 @@ func (l *Limit) Execute(ctx *Context) error {
 	return nil
 }
 
-// Rollback implements the Task interface
+// pattern returns the regexp of sed matching the lines of the limit
+func (l *Limit) pattern() string {
+	return fmt.Sprintf("%s\\s*%s\\s*%s", l.domain, l.limit, l.item)
+}
+
+// Rollback implements the Task interface, the lines of the limit in the file
+// are restored.
 func (l *Limit) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	if l.exec == nil {
+		return nil
+	}
+
+	cmd := strings.Join([]string{
+		fmt.Sprintf("sed -i '/%s/d' %s 2>/dev/null", l.pattern(), limitsFilePath),
+		fmt.Sprintf("cat >> %s", limitsFilePath),
+	}, " && ")
+	stdout, stderr, err := l.exec.ExecuteWithStdin(cmd, strings.NewReader(l.oldLines), true)
+	ctx.SetOutputs(l.host, stdout, stderr)
+	return errors.Trace(err)
 }
 
 // String implements the fmt.Stringer interface
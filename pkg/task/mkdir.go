@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap/errors"
 )
 
@@ -25,6 +26,9 @@ type Mkdir struct {
 	user string
 	host string
 	dirs []string
+
+	exec    executor.TiOpsExecutor
+	created []string // the topmost directories not existing before
 }
 
 // Execute implements the Task interface
@@ -39,12 +43,33 @@ func (m *Mkdir) Execute(ctx *Context) error {
 		}
 	}
 
+	// find out the topmost directories to be created, only they are removed
+	// on rollback so that the existing directories are untouched
 	cmd := fmt.Sprintf(
+		`for d in %s; do t=""; while [ ! -e "$d" ]; do t="$d"; d=$(dirname "$d"); done; [ -z "$t" ] || echo "$t"; done`,
+		strings.Join(m.dirs, " "),
+	)
+	stdout, _, err := exec.Execute(cmd, true)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	m.exec = exec
+	m.created = nil
+	seen := make(map[string]bool)
+	for _, dir := range strings.Fields(string(stdout)) {
+		// the sibling directories may share the same topmost one
+		if !seen[dir] {
+			seen[dir] = true
+			m.created = append(m.created, dir)
+		}
+	}
+
+	cmd = fmt.Sprintf(
 		`mkdir -p %[1]s && chown -R %[2]s:%[2]s %[1]s`,
 		strings.Join(m.dirs, " "),
 		m.user,
 	)
-	_, _, err := exec.Execute(cmd, true) // use root to create the dir
+	_, _, err = exec.Execute(cmd, true) // use root to create the dir
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -52,9 +77,14 @@ func (m *Mkdir) Execute(ctx *Context) error {
 	return nil
 }
 
-// Rollback implements the Task interface
+// Rollback implements the Task interface, it removes the directories created
+// along with everything in them.
 func (m *Mkdir) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	if len(m.created) == 0 {
+		return nil
+	}
+	_, _, err := m.exec.Execute(fmt.Sprintf("rm -rf %s", strings.Join(m.created, " ")), true)
+	return errors.Trace(err)
 }
 
 // String implements the fmt.Stringer interface
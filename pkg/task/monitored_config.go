@@ -37,6 +37,8 @@ type MonitoredConfig struct {
 	options    meta.MonitoredOptions
 	deployUser string
 	paths      meta.DirPaths
+
+	unit *systemdUnit
 }
 
 // Execute implements the Task interface
@@ -51,10 +53,17 @@ func (m *MonitoredConfig) Execute(ctx *Context) error {
 		return ErrNoExecutor
 	}
 
+	m.paths.Cache = ctx.cachePath(m.paths.Cache)
 	if err := os.MkdirAll(m.paths.Cache, 0755); err != nil {
 		return err
 	}
 
+	unit, err := probeSystemdUnit(exec, fmt.Sprintf("%s-%d.service", m.component, ports[m.component]))
+	if err != nil {
+		return err
+	}
+	m.unit = unit
+
 	if err := m.syncMonitoredSystemConfig(exec, m.component, ports[m.component]); err != nil {
 		return err
 	}
@@ -142,11 +151,11 @@ func (m *MonitoredConfig) syncBlackboxConfig(exec executor.TiOpsExecutor, cfg te
 
 // Rollback implements the Task interface
 func (m *MonitoredConfig) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	return m.unit.rollback()
 }
 
 // String implements the fmt.Stringer interface
 func (m *MonitoredConfig) String() string {
-	return fmt.Sprintf("MonitoredConfig: cluster=%s, user=%s, node_exporter_port=%d, blackbox_exporter_port=%d, %v",
-		m.name, m.deployUser, m.options.NodeExporterPort, m.options.BlackboxExporterPort, m.paths)
+	return fmt.Sprintf("MonitoredConfig: cluster=%s, user=%s, component=%s, host=%s, node_exporter_port=%d, blackbox_exporter_port=%d, %v",
+		m.name, m.deployUser, m.component, m.host, m.options.NodeExporterPort, m.options.BlackboxExporterPort, m.paths)
 }
@@ -0,0 +1,148 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+// RollbackResult is the result of rolling back an executed task
+type RollbackResult struct {
+	Task string
+	// Err is nil if the task is undone, ErrUnsupportedRollback if it can't
+	// be undone, or the error met while undoing it
+	Err error
+}
+
+// rollbackState keeps the tasks executed with a context and the results of
+// rolling them back.
+type rollbackState struct {
+	sync.Mutex
+	executed map[Task]struct{}
+	results  []RollbackResult
+}
+
+// markExecuted records that t is going to be executed, so that it's rolled
+// back if the operation fails.
+func (ctx *Context) markExecuted(t Task) {
+	ctx.rollback.Lock()
+	defer ctx.rollback.Unlock()
+	if ctx.rollback.executed == nil {
+		ctx.rollback.executed = make(map[Task]struct{})
+	}
+	ctx.rollback.executed[t] = struct{}{}
+}
+
+func (ctx *Context) isExecuted(t Task) bool {
+	ctx.rollback.Lock()
+	defer ctx.rollback.Unlock()
+	_, ok := ctx.rollback.executed[t]
+	return ok
+}
+
+func (ctx *Context) addRollbackResult(t Task, err error) {
+	ctx.rollback.Lock()
+	defer ctx.rollback.Unlock()
+	ctx.rollback.results = append(ctx.rollback.results, RollbackResult{
+		Task: t.String(),
+		Err:  err,
+	})
+}
+
+// RollbackResults returns the results of the tasks rolled back in order
+func (ctx *Context) RollbackResults() []RollbackResult {
+	ctx.rollback.Lock()
+	defer ctx.rollback.Unlock()
+	return append([]RollbackResult(nil), ctx.rollback.results...)
+}
+
+// isStateTask returns whether t only sets up the state of the context, e.g:
+// the executors and keys, which is not worth reporting when rolled back.
+func isStateTask(t Task) bool {
+	switch t.(type) {
+	case *RootSSH, *UserSSH, *SSHKeySet, *TrustHostKey, *Downloader:
+		return true
+	}
+	return false
+}
+
+// rollbackTask rolls back t if it has been executed with ctx, the tasks not
+// executed are left untouched. ErrUnsupportedRollback of t is recorded in the
+// results but not returned, so that the other tasks are still rolled back.
+func rollbackTask(ctx *Context, t Task) error {
+	if isDisplayTask(t) {
+		return t.Rollback(ctx)
+	}
+	if !ctx.isExecuted(t) {
+		return nil
+	}
+
+	err := t.Rollback(ctx)
+	if !isStateTask(t) {
+		ctx.addRollbackResult(t, err)
+	}
+	if err == ErrUnsupportedRollback {
+		return nil
+	}
+	if err != nil {
+		log.Warnf("Failed to roll back %s: %s", t, err)
+		return newTaskError(t, err)
+	}
+	return nil
+}
+
+// systemdUnit is the systemd unit of an instance installed by a config task,
+// it's removed on rollback if it's not there before.
+type systemdUnit struct {
+	exec    executor.TiOpsExecutor
+	name    string
+	created bool
+}
+
+// probeSystemdUnit finds out whether the unit exists before it's installed
+func probeSystemdUnit(exec executor.TiOpsExecutor, name string) (*systemdUnit, error) {
+	stdout, _, err := exec.Execute(fmt.Sprintf("test -e /etc/systemd/system/%s && echo exists; true", name), false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &systemdUnit{
+		exec:    exec,
+		name:    name,
+		created: strings.TrimSpace(string(stdout)) != "exists",
+	}, nil
+}
+
+// rollback stops and removes the unit if it's created, the instance may have
+// been started by the later tasks. The config of an existing unit has been
+// overwritten and can't be restored.
+func (u *systemdUnit) rollback() error {
+	if u == nil {
+		return nil
+	}
+	if !u.created {
+		return ErrUnsupportedRollback
+	}
+	cmd := fmt.Sprintf("systemctl stop %[1]s; systemctl disable %[1]s; rm -f /etc/systemd/system/%[1]s && systemctl daemon-reload", u.name)
+	_, stderr, err := u.exec.Execute(cmd, true)
+	if err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/check"
+)
+
+type rollbackSuite struct{}
+
+var _ = check.Suite(&rollbackSuite{})
+
+// fakeExecutor records the commands and their stdin. The commands containing
+// a key of outputs are answered with the value, the others are run with the
+// local shell as the current user if shell is set.
+type fakeExecutor struct {
+	sync.Mutex
+	shell   bool
+	outputs map[string]string
+	cmds    []string
+	stdins  []string
+}
+
+func (e *fakeExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	return e.ExecuteWithStdin(cmd, nil, sudo, timeout...)
+}
+
+func (e *fakeExecutor) ExecuteWithStdin(cmd string, stdin io.Reader, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	var in []byte
+	if stdin != nil {
+		var err error
+		if in, err = ioutil.ReadAll(stdin); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	e.Lock()
+	e.cmds = append(e.cmds, cmd)
+	e.stdins = append(e.stdins, string(in))
+	e.Unlock()
+
+	for k, v := range e.outputs {
+		if strings.Contains(cmd, k) {
+			return []byte(v), nil, nil
+		}
+	}
+	if !e.shell {
+		return nil, nil, nil
+	}
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	c := exec.Command("bash", "-c", cmd)
+	c.Stdin = bytes.NewReader(in)
+	c.Stdout = stdout
+	c.Stderr = stderr
+	err := c.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+func (e *fakeExecutor) Transfer(src string, dst string, download bool) error {
+	return nil
+}
+
+func (e *fakeExecutor) UploadWithMode(src string, dst string, mode os.FileMode) error {
+	return nil
+}
+
+// reset forgets the commands issued before
+func (e *fakeExecutor) reset() {
+	e.Lock()
+	defer e.Unlock()
+	e.cmds = nil
+	e.stdins = nil
+}
+
+func newFakeContext(e *fakeExecutor) *Context {
+	ctx := NewContext()
+	ctx.SetExecutor("127.0.0.1", e)
+	return ctx
+}
+
+func (s *rollbackSuite) TestMkdir(c *check.C) {
+	base := c.MkDir()
+	c.Assert(os.Mkdir(filepath.Join(base, "existing"), 0755), check.IsNil)
+
+	e := &fakeExecutor{shell: true}
+	ctx := newFakeContext(e)
+	defer ctx.Close()
+	m := &Mkdir{
+		user: fmt.Sprint(os.Getuid()),
+		host: "127.0.0.1",
+		dirs: []string{
+			filepath.Join(base, "existing", "a", "b"),
+			filepath.Join(base, "existing", "a", "c"),
+			filepath.Join(base, "new", "d"),
+		},
+	}
+	c.Assert(m.Execute(ctx), check.IsNil)
+	for _, dir := range m.dirs {
+		_, err := os.Stat(dir)
+		c.Assert(err, check.IsNil)
+	}
+
+	// only the topmost directories created are removed
+	e.reset()
+	c.Assert(m.Rollback(ctx), check.IsNil)
+	c.Assert(e.cmds, check.DeepEquals, []string{
+		fmt.Sprintf("rm -rf %s %s", filepath.Join(base, "existing", "a"), filepath.Join(base, "new")),
+	})
+	_, err := os.Stat(filepath.Join(base, "existing"))
+	c.Assert(err, check.IsNil)
+	_, err = os.Stat(filepath.Join(base, "existing", "a"))
+	c.Assert(os.IsNotExist(err), check.IsTrue)
+	_, err = os.Stat(filepath.Join(base, "new"))
+	c.Assert(os.IsNotExist(err), check.IsTrue)
+
+	// nothing is removed if all the directories exist before
+	c.Assert(m.Execute(ctx), check.IsNil)
+	c.Assert(m.Execute(ctx), check.IsNil)
+	e.reset()
+	c.Assert(m.Rollback(ctx), check.IsNil)
+	c.Assert(e.cmds, check.HasLen, 0)
+}
+
+func envInitContext(c *check.C, e *fakeExecutor) *Context {
+	ctx := newFakeContext(e)
+	ctx.PublicKeyPath = filepath.Join(c.MkDir(), "id_rsa.pub")
+	c.Assert(ioutil.WriteFile(ctx.PublicKeyPath, []byte("ssh-rsa AAAA tiops\n"), 0644), check.IsNil)
+	return ctx
+}
+
+func (s *rollbackSuite) TestEnvInitCreated(c *check.C) {
+	e := &fakeExecutor{outputs: map[string]string{
+		"&& echo user;": "",
+		"grep -qxF":     "added\n",
+	}}
+	ctx := envInitContext(c, e)
+	defer ctx.Close()
+	t := &EnvInit{host: "127.0.0.1", deployUser: "tidb"}
+	c.Assert(t.Execute(ctx), check.IsNil)
+
+	// the user is removed along with the key
+	e.reset()
+	c.Assert(t.Rollback(ctx), check.IsNil)
+	c.Assert(e.cmds, check.HasLen, 2)
+	c.Assert(e.cmds[0], check.Equals, "rm -f /etc/sudoers.d/tidb")
+	c.Assert(e.cmds[1], check.Matches, ".*userdel -r tidb.*")
+}
+
+func (s *rollbackSuite) TestEnvInitExisting(c *check.C) {
+	e := &fakeExecutor{outputs: map[string]string{
+		"&& echo user;": "user\nsudoer\n",
+		"grep -qxF":     "Last login: Mon Jan 1 00:00:00 2020\nadded\n",
+	}}
+	ctx := envInitContext(c, e)
+	defer ctx.Close()
+	t := &EnvInit{host: "127.0.0.1", deployUser: "tidb"}
+	c.Assert(t.Execute(ctx), check.IsNil)
+
+	// only the key added is removed
+	e.reset()
+	c.Assert(t.Rollback(ctx), check.IsNil)
+	c.Assert(e.cmds, check.HasLen, 1)
+	c.Assert(e.cmds[0], check.Matches, `su - tidb -c 'key=\$\(cat\); grep -vxF -- "\$key" ~/.ssh/authorized_keys > .*`)
+	c.Assert(e.stdins[0], check.Equals, "ssh-rsa AAAA tiops")
+
+	// nothing is undone if the key is there before
+	e.outputs["grep -qxF"] = ""
+	c.Assert(t.Execute(ctx), check.IsNil)
+	e.reset()
+	c.Assert(t.Rollback(ctx), check.IsNil)
+	c.Assert(e.cmds, check.HasLen, 0)
+}
+
+func (s *rollbackSuite) TestLimit(c *check.C) {
+	path := filepath.Join(c.MkDir(), "limits.conf")
+	c.Assert(ioutil.WriteFile(path, []byte("tidb    soft    nofile    1000\n*    hard    core    0\n"), 0644), check.IsNil)
+	defer func(p string) { limitsFilePath = p }(limitsFilePath)
+	limitsFilePath = path
+
+	e := &fakeExecutor{shell: true}
+	ctx := newFakeContext(e)
+	defer ctx.Close()
+	l := &Limit{host: "127.0.0.1", domain: "tidb", limit: "soft", item: "nofile", value: "1000000"}
+	c.Assert(l.Execute(ctx), check.IsNil)
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "*    hard    core    0\ntidb    soft    nofile    1000000\n")
+
+	c.Assert(l.Rollback(ctx), check.IsNil)
+	data, err = ioutil.ReadFile(path)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "*    hard    core    0\ntidb    soft    nofile    1000\n")
+}
+
+func (s *rollbackSuite) TestSysctl(c *check.C) {
+	e := &fakeExecutor{outputs: map[string]string{
+		"sed -n":    "vm.swappiness = 10\n",
+		"sysctl -n": "60\n",
+	}}
+	ctx := newFakeContext(e)
+	defer ctx.Close()
+	t := &Sysctl{host: "127.0.0.1", key: "vm.swappiness", val: "0"}
+
+	// nothing is undone if it's not executed
+	c.Assert(t.Rollback(ctx), check.IsNil)
+	c.Assert(e.cmds, check.HasLen, 0)
+
+	c.Assert(t.Execute(ctx), check.IsNil)
+	e.reset()
+	c.Assert(t.Rollback(ctx), check.IsNil)
+	c.Assert(e.cmds, check.DeepEquals, []string{fmt.Sprintf(
+		"sed -i '/vm.swappiness/d' %[1]s 2>/dev/null && cat >> %[1]s && sysctl -w 'vm.swappiness=60'",
+		sysctlFilePath,
+	)})
+	c.Assert(e.stdins, check.DeepEquals, []string{"vm.swappiness = 10\n"})
+}
+
+func (s *rollbackSuite) TestBackupComponent(c *check.C) {
+	deployDir := c.MkDir()
+	binFile := filepath.Join(deployDir, "bin", "tikv-server")
+	c.Assert(os.Mkdir(filepath.Dir(binFile), 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(binFile, []byte("v4.0.0"), 0755), check.IsNil)
+
+	e := &fakeExecutor{shell: true}
+	ctx := newFakeContext(e)
+	defer ctx.Close()
+	t := &BackupComponent{component: "tikv", fromVer: "v4.0.0", host: "127.0.0.1", deployDir: deployDir}
+	c.Assert(t.Execute(ctx), check.IsNil)
+
+	// the binaries are restored from the backup
+	c.Assert(ioutil.WriteFile(binFile, []byte("v4.0.1"), 0755), check.IsNil)
+	c.Assert(t.Rollback(ctx), check.IsNil)
+	data, err := ioutil.ReadFile(binFile)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "v4.0.0")
+
+	// the binaries are left alone without the backup
+	c.Assert(os.RemoveAll(filepath.Join(deployDir, "bin.old.v4.0.0")), check.IsNil)
+	c.Assert(ioutil.WriteFile(binFile, []byte("v4.0.1"), 0755), check.IsNil)
+	c.Assert(t.Rollback(ctx), check.IsNil)
+	data, err = ioutil.ReadFile(binFile)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "v4.0.1")
+}
+
+func (s *rollbackSuite) TestSerial(c *check.C) {
+	var rolledBack []string
+	task := func(name string, err, rollbackErr error) *Func {
+		return NewFunc(name, func(ctx *Context) error {
+			return err
+		}).WithRollback(func(ctx *Context) error {
+			rolledBack = append(rolledBack, name)
+			return rollbackErr
+		})
+	}
+	bErr := errors.New("b failed to roll back")
+	serial := &Serial{inner: []Task{
+		task("a", nil, nil),
+		task("b", nil, bErr),
+		NewFunc("c", func(ctx *Context) error { return nil }),
+		task("d", errors.New("d failed"), nil),
+		task("e", nil, nil),
+	}}
+
+	ctx := NewContext()
+	defer ctx.Close()
+	c.Assert(serial.Execute(ctx), check.NotNil)
+
+	// the executed tasks are all rolled back in reverse order, and the first
+	// error is returned
+	err := serial.Rollback(ctx)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Matches, ".*b failed to roll back.*")
+	c.Assert(rolledBack, check.DeepEquals, []string{"d", "b", "a"})
+	c.Assert(ctx.RollbackResults(), check.DeepEquals, []RollbackResult{
+		{Task: "d"},
+		{Task: "c", Err: ErrUnsupportedRollback},
+		{Task: "b", Err: bErr},
+		{Task: "a"},
+	})
+}
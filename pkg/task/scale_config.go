@@ -28,6 +28,8 @@ type ScaleConfig struct {
 	base           *meta.TopologySpecification
 	deployUser     string
 	paths          meta.DirPaths
+
+	unit *systemdUnit
 }
 
 // Execute implements the Task interface
@@ -38,17 +40,23 @@ func (c *ScaleConfig) Execute(ctx *Context) error {
 		return ErrNoExecutor
 	}
 
-	c.paths.Cache = meta.ClusterPath(c.clusterName, meta.TempConfigPath)
+	c.paths.Cache = ctx.cachePath(meta.ClusterPath(c.clusterName, meta.TempConfigPath))
 	if err := os.MkdirAll(c.paths.Cache, 0755); err != nil {
 		return err
 	}
 
+	unit, err := probeSystemdUnit(exec, c.instance.ServiceName())
+	if err != nil {
+		return err
+	}
+	c.unit = unit
+
 	return c.instance.ScaleConfig(exec, c.base, c.clusterName, c.clusterVersion, c.deployUser, c.paths)
 }
 
 // Rollback implements the Task interface
 func (c *ScaleConfig) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	return c.unit.rollback()
 }
 
 // String implements the fmt.Stringer interface
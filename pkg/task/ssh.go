@@ -15,10 +15,14 @@ package task
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/joomcode/errorx"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 )
 
 var (
@@ -34,11 +38,13 @@ type RootSSH struct {
 	keyFile    string // path to the private key file
 	passphrase string // passphrase of the private key file
 	timeout    int64  // timeout in seconds when connecting via SSH
+	sshType    executor.SSHType
+	proxy      *meta.SSHProxy // the jump host, connect directly if it's nil
 }
 
 // Execute implements the Task interface
 func (s *RootSSH) Execute(ctx *Context) error {
-	e := executor.NewSSHExecutor(executor.SSHConfig{
+	e, err := ctx.newExecutor(s.sshType, s.user != "root", executor.SSHConfig{
 		Host:       s.host,
 		Port:       s.port,
 		User:       s.user,
@@ -46,7 +52,15 @@ func (s *RootSSH) Execute(ctx *Context) error {
 		KeyFile:    s.keyFile,
 		Passphrase: s.passphrase,
 		Timeout:    time.Second * time.Duration(s.timeout),
-	}, s.user != "root") // using sudo by default if user is not root
+		Proxy:      proxyConfig(s.proxy, s.timeout),
+		KnownHosts: ctx.KnownHosts,
+		// the root SSH is used to initialize new hosts
+		AcceptNewHostKey: true,
+		SudoPassword:     ctx.SudoPassword,
+	}) // using sudo by default if user is not root
+	if err != nil {
+		return err
+	}
 
 	ctx.SetExecutor(s.host, e)
 	return nil
@@ -63,9 +77,9 @@ func (s *RootSSH) Rollback(ctx *Context) error {
 // String implements the fmt.Stringer interface
 func (s RootSSH) String() string {
 	if len(s.keyFile) > 0 {
-		return fmt.Sprintf("RootSSH: user=%s, host=%s, port=%d, key=%s", s.user, s.host, s.port, s.keyFile)
+		return fmt.Sprintf("RootSSH: user=%s, host=%s, port=%d, key=%s%s", s.user, s.host, s.port, s.keyFile, proxyString(s.proxy))
 	}
-	return fmt.Sprintf("RootSSH: user=%s, host=%s, port=%d", s.user, s.host, s.port)
+	return fmt.Sprintf("RootSSH: user=%s, host=%s, port=%d%s", s.user, s.host, s.port, proxyString(s.proxy))
 }
 
 // UserSSH is used to establish a SSH connection to the target host with generated key
@@ -74,17 +88,26 @@ type UserSSH struct {
 	port       int
 	deployUser string
 	timeout    int64
+	sshType    executor.SSHType
+	proxy      *meta.SSHProxy
 }
 
 // Execute implements the Task interface
 func (s *UserSSH) Execute(ctx *Context) error {
-	e := executor.NewSSHExecutor(executor.SSHConfig{
+	e, err := ctx.newExecutor(s.sshType, false /* sudo */, executor.SSHConfig{
 		Host:    s.host,
 		Port:    s.port,
 		KeyFile: ctx.PrivateKeyPath,
 		User:    s.deployUser,
 		Timeout: time.Second * time.Duration(s.timeout),
-	}, false) // not using sudo by default
+		Proxy:   proxyConfig(s.proxy, s.timeout),
+
+		KnownHosts:   ctx.KnownHosts,
+		SudoPassword: ctx.DeploySudoPassword,
+	})
+	if err != nil {
+		return err
+	}
 
 	ctx.SetExecutor(s.host, e)
 	return nil
@@ -100,5 +123,36 @@ func (s *UserSSH) Rollback(ctx *Context) error {
 
 // String implements the fmt.Stringer interface
 func (s UserSSH) String() string {
-	return fmt.Sprintf("UserSSH: user=%s, host=%s", s.deployUser, s.host)
+	return fmt.Sprintf("UserSSH: user=%s, host=%s%s", s.deployUser, s.host, proxyString(s.proxy))
+}
+
+// proxyConfig builds the executor config of the jump host, the current user is
+// used if it's not specified in topology, and so are ssh-agent and the default
+// keys if the identity file is not.
+func proxyConfig(proxy *meta.SSHProxy, timeout int64) *executor.SSHConfig {
+	if proxy == nil {
+		return nil
+	}
+
+	c := &executor.SSHConfig{
+		Host:    proxy.Host,
+		Port:    proxy.Port,
+		User:    proxy.User,
+		KeyFile: proxy.IdentityFile,
+		Timeout: time.Second * time.Duration(timeout),
+	}
+	if c.User == "" {
+		c.User = utils.CurrentUser()
+	}
+	if strings.HasPrefix(c.KeyFile, "~/") {
+		c.KeyFile = filepath.Join(utils.UserHome(), c.KeyFile[2:])
+	}
+	return c
+}
+
+func proxyString(proxy *meta.SSHProxy) string {
+	if proxy == nil {
+		return ""
+	}
+	return fmt.Sprintf(", proxy=%s", proxy.Host)
 }
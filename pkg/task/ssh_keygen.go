@@ -23,6 +23,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup/pkg/utils"
 	"github.com/pingcap/errors"
 	"golang.org/x/crypto/ssh"
@@ -30,7 +31,8 @@ import (
 
 // SSHKeyGen is used to generate SSH key
 type SSHKeyGen struct {
-	keypath string
+	keypath   string
+	generated bool // the keys are generated instead of existing before
 }
 
 // Execute implements the Task interface
@@ -40,6 +42,9 @@ func (s *SSHKeyGen) Execute(ctx *Context) error {
 	savePrivateFileTo := s.keypath
 	savePublicFileTo := s.keypath + ".pub"
 
+	// hosts are connected for the first time when the keys are generated
+	ctx.KnownHosts = executor.NewKnownHosts(knownHostsPath(s.keypath), true)
+
 	// Skip ssh key generate
 	if utils.IsExist(savePrivateFileTo) && utils.IsExist(savePublicFileTo) {
 		ctx.PublicKeyPath = savePublicFileTo
@@ -47,6 +52,13 @@ func (s *SSHKeyGen) Execute(ctx *Context) error {
 		return nil
 	}
 
+	if ctx.DryRun() {
+		ctx.Recorder.Note("", "generate SSH key pair %s and %s", savePrivateFileTo, savePublicFileTo)
+		ctx.PublicKeyPath = savePublicFileTo
+		ctx.PrivateKeyPath = savePrivateFileTo
+		return nil
+	}
+
 	bitSize := 4096
 
 	ctx.ev.PublishTaskProgress(s, "Generate private key")
@@ -64,6 +76,7 @@ func (s *SSHKeyGen) Execute(ctx *Context) error {
 	privateKeyBytes := s.encodePrivateKeyToPEM(privateKey)
 
 	ctx.ev.PublishTaskProgress(s, "Persist keys")
+	s.generated = true
 	err = s.writeKeyToFile(privateKeyBytes, savePrivateFileTo)
 	if err != nil {
 		return errors.Trace(err)
@@ -131,9 +144,17 @@ func (s *SSHKeyGen) writeKeyToFile(keyBytes []byte, saveFileTo string) error {
 	return ioutil.WriteFile(saveFileTo, keyBytes, 0600)
 }
 
-// Rollback implements the Task interface
+// Rollback implements the Task interface, the keys existing before are kept
 func (s *SSHKeyGen) Rollback(ctx *Context) error {
-	return os.Remove(s.keypath)
+	if !s.generated {
+		return nil
+	}
+	for _, path := range []string{s.keypath, s.keypath + ".pub"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Trace(err)
+		}
+	}
+	return nil
 }
 
 // String implements the fmt.Stringer interface
@@ -13,7 +13,13 @@
 
 package task
 
-import "fmt"
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup/pkg/utils"
+)
 
 // SSHKeySet is used to set the Context private/public key path
 type SSHKeySet struct {
@@ -25,6 +31,7 @@ type SSHKeySet struct {
 func (s *SSHKeySet) Execute(ctx *Context) error {
 	ctx.PublicKeyPath = s.publicKeyPath
 	ctx.PrivateKeyPath = s.privateKeyPath
+	ctx.KnownHosts = newKnownHosts(s.privateKeyPath)
 	return nil
 }
 
@@ -32,6 +39,7 @@ func (s *SSHKeySet) Execute(ctx *Context) error {
 func (s *SSHKeySet) Rollback(ctx *Context) error {
 	ctx.PublicKeyPath = ""
 	ctx.PrivateKeyPath = ""
+	ctx.KnownHosts = nil
 	return nil
 }
 
@@ -39,3 +47,17 @@ func (s *SSHKeySet) Rollback(ctx *Context) error {
 func (s *SSHKeySet) String() string {
 	return fmt.Sprintf("SSHKeySet: privateKey=%s, publicKey=%s", s.privateKeyPath, s.publicKeyPath)
 }
+
+// knownHostsPath returns the path of known_hosts, which is in the same directory
+// as the private key
+func knownHostsPath(privateKeyPath string) string {
+	return filepath.Join(filepath.Dir(privateKeyPath), "known_hosts")
+}
+
+// newKnownHosts creates the KnownHosts of the private key, new hosts are trusted
+// on first use only if the file doesn't exist, e.g: the cluster is deployed by
+// an old version which doesn't record host keys.
+func newKnownHosts(privateKeyPath string) *executor.KnownHosts {
+	path := knownHostsPath(privateKeyPath)
+	return executor.NewKnownHosts(path, !utils.IsExist(path))
+}
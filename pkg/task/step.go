@@ -47,6 +47,13 @@ func addChildren(m map[Task]struct{}, task Task) {
 				addChildren(m, tx)
 			}
 		}
+	} else if t, ok := task.(*Graph); ok {
+		t.hideDetailDisplay = true
+		for _, node := range t.nodes {
+			if _, exists := m[node.task]; !exists {
+				addChildren(m, node.task)
+			}
+		}
 	}
 }
 
@@ -173,3 +180,44 @@ func (ps *ParallelStepDisplay) Rollback(ctx *Context) error {
 func (ps *ParallelStepDisplay) String() string {
 	return ps.inner.String()
 }
+
+// GraphStepDisplay is a task that will display multiple progress bars for the steps in a Graph.
+// Inner tasks will be executed by their dependencies.
+type GraphStepDisplay struct {
+	inner       *Graph
+	prefix      string
+	progressBar *progress.MultiBar
+}
+
+func newGraphStepDisplay(prefix string, g *Graph) *GraphStepDisplay {
+	bar := progress.NewMultiBar(prefix)
+	g.hideDetailDisplay = true
+	for _, node := range g.nodes {
+		if t, ok := node.task.(*StepDisplay); ok && !t.hidden {
+			t.resetAsMultiBarItem(bar)
+		}
+	}
+	return &GraphStepDisplay{
+		inner:       g,
+		prefix:      prefix,
+		progressBar: bar,
+	}
+}
+
+// Execute implements the Task interface
+func (gs *GraphStepDisplay) Execute(ctx *Context) error {
+	gs.progressBar.StartRenderLoop()
+	err := gs.inner.Execute(ctx)
+	gs.progressBar.StopRenderLoop()
+	return err
+}
+
+// Rollback implements the Task interface
+func (gs *GraphStepDisplay) Rollback(ctx *Context) error {
+	return gs.inner.Rollback(ctx)
+}
+
+// String implements the fmt.Stringer interface
+func (gs *GraphStepDisplay) String() string {
+	return gs.inner.String()
+}
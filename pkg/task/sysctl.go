@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap/errors"
 )
 
@@ -29,6 +30,10 @@ type Sysctl struct {
 	host string
 	key  string
 	val  string
+
+	exec     executor.TiOpsExecutor
+	oldLines string // the lines of the key in the file before
+	oldVal   string // the value of the key before
 }
 
 // Execute implements the Task interface
@@ -38,14 +43,28 @@ func (s *Sysctl) Execute(ctx *Context) error {
 		return ErrNoExecutor
 	}
 
+	// keep the current settings to be restored on rollback
+	stdout, _, err := e.Execute(fmt.Sprintf("sed -n '/%s/p' %s 2>/dev/null; true", s.key, sysctlFilePath), true)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s.oldLines = string(stdout)
+	stdout, _, err = e.Execute(fmt.Sprintf("sysctl -n %s", s.key), true)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s.oldVal = strings.TrimSpace(string(stdout))
+	s.exec = e
+
 	cmd := strings.Join([]string{
 		fmt.Sprintf("cp %s{,.bak} 2>/dev/null", sysctlFilePath),
 		fmt.Sprintf("sed -i '/%s/d' %s 2>/dev/null", s.key, sysctlFilePath),
-		fmt.Sprintf("echo '%s=%s' >> %s", s.key, s.val, sysctlFilePath),
+		fmt.Sprintf("cat >> %s", sysctlFilePath),
 		fmt.Sprintf("sysctl -p %s", sysctlFilePath),
 	}, " && ")
 
-	stdout, stderr, err := e.Execute(cmd, true)
+	stdin := strings.NewReader(fmt.Sprintf("%s=%s\n", s.key, s.val))
+	stdout, stderr, err := e.ExecuteWithStdin(cmd, stdin, true)
 	ctx.SetOutputs(s.host, stdout, stderr)
 	if err != nil {
 		return errors.Trace(err)
@@ -54,9 +73,21 @@ func (s *Sysctl) Execute(ctx *Context) error {
 	return nil
 }
 
-// Rollback implements the Task interface
+// Rollback implements the Task interface, the lines in the file and the value
+// of the kernel param are restored.
 func (s *Sysctl) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	if s.exec == nil {
+		return nil
+	}
+
+	cmd := strings.Join([]string{
+		fmt.Sprintf("sed -i '/%s/d' %s 2>/dev/null", s.key, sysctlFilePath),
+		fmt.Sprintf("cat >> %s", sysctlFilePath),
+		fmt.Sprintf("sysctl -w '%s=%s'", s.key, s.oldVal),
+	}, " && ")
+	stdout, stderr, err := s.exec.ExecuteWithStdin(cmd, strings.NewReader(s.oldLines), true)
+	ctx.SetOutputs(s.host, stdout, stderr)
+	return errors.Trace(err)
 }
 
 // String implements the fmt.Stringer interface
@@ -26,6 +26,14 @@ type SystemCtl struct {
 	action string
 }
 
+// the actions of systemctl which undo each other
+var systemctlUndoActions = map[string]string{
+	"start":   "stop",
+	"stop":    "start",
+	"enable":  "disable",
+	"disable": "enable",
+}
+
 // Execute implements the Task interface
 func (c *SystemCtl) Execute(ctx *Context) error {
 	e, ok := ctx.GetExecutor(c.host)
@@ -47,9 +55,15 @@ func (c *SystemCtl) Execute(ctx *Context) error {
 	return nil
 }
 
-// Rollback implements the Task interface
+// Rollback implements the Task interface, it performs the opposite action of
+// the unit, e.g: stop it if it's started.
 func (c *SystemCtl) Rollback(ctx *Context) error {
-	return ErrUnsupportedRollback
+	action, ok := systemctlUndoActions[c.action]
+	if !ok {
+		return ErrUnsupportedRollback
+	}
+	undo := &SystemCtl{host: c.host, unit: c.unit, action: action}
+	return undo.Execute(ctx)
 }
 
 // String implements the fmt.Stringer interface
@@ -14,8 +14,10 @@
 package task
 
 import (
+	"context"
 	stderrors "errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"sync"
 
@@ -55,9 +57,48 @@ type (
 			checkResults map[string][]*operator.CheckResult
 		}
 
+		// SSH connections shared by all executors of this context
+		sshConnPool *executor.SSHConnPool
+
 		// The public/private key is used to access remote server via the user `tidb`
 		PrivateKeyPath string
 		PublicKeyPath  string
+		// KnownHosts verifies the host keys of remote servers, it's kept along
+		// with the keys, no host key is verified if it's nil
+		KnownHosts *executor.KnownHosts
+		// SudoPassword is the password to run sudo on remote servers, it's
+		// only needed if the users are not allowed to sudo without password
+		SudoPassword string
+		// DeploySudoPassword is the password of the deploy user to run sudo
+		// with, the deploy user is allowed to sudo without password if it's
+		// empty, which is refused if SudoPassword is set
+		DeploySudoPassword string
+
+		// Recorder records the operations instead of performing them if
+		// it's not nil, see SetDryRun
+		Recorder *executor.Recorder
+		// the directory to keep the files generated in dry run
+		dryRunDir string
+		// Checkpoint records the tasks done so that they are skipped when
+		// the operation is resumed, no task is recorded if it's nil
+		Checkpoint *Checkpoint
+		// Concurrency is the max number of tasks executed at the same time
+		// by all the Serial, Parallel and Graph, however they are nested,
+		// unlimited if it's not positive. See acquireSlot.
+		Concurrency int
+		slotsOnce   sync.Once
+		slots       chan struct{}
+
+		// the tasks executed and the results of rolling them back
+		rollback rollbackState
+
+		// it's done when the operation is interrupted, see SetContext
+		stdCtx        context.Context
+		stopInterrupt func()
+		// the steps to be run when the context is closed, see AddCleanup
+		cleanup cleanupState
+		// it collects the time taken by the tasks, see SetTiming
+		timing *Timing
 	}
 
 	// Serial will execute a bundle of task in serialized way
@@ -89,7 +130,61 @@ func NewContext() *Context {
 			stderrs:      make(map[string][]byte),
 			checkResults: make(map[string][]*operator.CheckResult),
 		},
+		sshConnPool: executor.NewSSHConnPool(),
+	}
+}
+
+// Close runs the cleanup steps left and releases the resources held by the
+// context, e.g: the SSH connections. It should be called when all tasks using
+// the context are done.
+func (ctx *Context) Close() error {
+	ctx.runCleanups()
+	if ctx.stopInterrupt != nil {
+		ctx.stopInterrupt()
 	}
+	return ctx.sshConnPool.Close()
+}
+
+// SetMaxHostSessions limits the number of concurrent SSH sessions to each
+// host, see executor.SSHConnPool.SetMaxHostSessions
+func (ctx *Context) SetMaxHostSessions(n int) {
+	ctx.sshConnPool.SetMaxHostSessions(n)
+}
+
+// SetDryRun makes the tasks of the context record the operations on hosts
+// instead of performing them, the files generated on the control machine,
+// e.g: the config files of instances, are kept in dir for review.
+func (ctx *Context) SetDryRun(dir string) {
+	ctx.Recorder = executor.NewRecorder()
+	ctx.dryRunDir = dir
+}
+
+// DryRun returns whether the tasks only record the operations
+func (ctx *Context) DryRun() bool {
+	return ctx.Recorder != nil
+}
+
+// DryRunDir returns the directory of the files generated in dry run
+func (ctx *Context) DryRunDir() string {
+	return ctx.dryRunDir
+}
+
+// cachePath returns the directory to put the generated files in, it's in
+// the dry run directory if it's a dry run so the cache is not touched.
+func (ctx *Context) cachePath(dir string) string {
+	if !ctx.DryRun() {
+		return dir
+	}
+	return filepath.Join(ctx.dryRunDir, filepath.Base(dir))
+}
+
+// newExecutor creates an executor of the host in c, which is a recording one
+// if it's a dry run.
+func (ctx *Context) newExecutor(etype executor.SSHType, sudo bool, c executor.SSHConfig) (executor.TiOpsExecutor, error) {
+	if ctx.DryRun() {
+		return executor.NewRecordingExecutor(c, sudo, ctx.Recorder), nil
+	}
+	return executor.New(etype, sudo, c, ctx.sshConnPool)
 }
 
 // Get implements operation ExecutorGetter interface.
@@ -168,9 +263,82 @@ func isDisplayTask(t Task) bool {
 	if _, ok := t.(*ParallelStepDisplay); ok {
 		return true
 	}
+	if _, ok := t.(*Graph); ok {
+		return true
+	}
+	if _, ok := t.(*GraphStepDisplay); ok {
+		return true
+	}
 	return false
 }
 
+// taskSlots returns the slots shared by all the tasks executed with ctx, or
+// nil if t doesn't take one. Only the tasks doing the work take the slots,
+// the tasks running others, e.g: Serial, don't, so that the nested tasks are
+// limited as a whole without waiting for the slots held by their parents.
+func (ctx *Context) taskSlots(t Task) chan struct{} {
+	if ctx.Concurrency <= 0 || isDisplayTask(t) {
+		return nil
+	}
+	ctx.slotsOnce.Do(func() {
+		ctx.slots = make(chan struct{}, ctx.Concurrency)
+	})
+	return ctx.slots
+}
+
+// acquireSlot waits until t can be executed without exceeding ctx.Concurrency,
+// the returned function must be called to release the slot after it's done.
+func (ctx *Context) acquireSlot(t Task) func() {
+	slots := ctx.taskSlots(t)
+	if slots == nil {
+		return func() {}
+	}
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+// executeTask executes t if it's not done before according to the checkpoint
+// of ctx, and records it to the checkpoint if it's done successfully. It fails
+// without executing t if the operation is interrupted.
+func executeTask(ctx *Context, t Task) error {
+	// no new task is started once the operation is interrupted
+	if err := ctx.Interrupted(); err != nil {
+		return err
+	}
+
+	var key string
+	cp := ctx.Checkpoint
+	if cp != nil && isCheckpointable(t) {
+		// some tasks change their fields while executing, so the key is
+		// taken before executing
+		key = t.String()
+		if cp.isDone(key) {
+			log.Debugf("Skip the task done before: %s", key)
+			return nil
+		}
+	} else {
+		cp = nil
+	}
+
+	if !isDisplayTask(t) {
+		ctx.markExecuted(t)
+	}
+	ctx.ev.PublishTaskBegin(t)
+	err := t.Execute(ctx)
+	ctx.ev.PublishTaskFinish(t, err)
+	if err != nil {
+		if isDisplayTask(t) {
+			return err
+		}
+		return newTaskError(t, err)
+	}
+
+	if cp != nil {
+		return cp.record(key)
+	}
+	return nil
+}
+
 // Execute implements the Task interface
 func (s *Serial) Execute(ctx *Context) error {
 	for _, t := range s.inner {
@@ -179,9 +347,9 @@ func (s *Serial) Execute(ctx *Context) error {
 				log.Infof("+ [ Serial ] - %s", t.String())
 			}
 		}
-		ctx.ev.PublishTaskBegin(t)
-		err := t.Execute(ctx)
-		ctx.ev.PublishTaskFinish(t, err)
+		release := ctx.acquireSlot(t)
+		err := executeTask(ctx, t)
+		release()
 		if err != nil {
 			return err
 		}
@@ -189,16 +357,20 @@ func (s *Serial) Execute(ctx *Context) error {
 	return nil
 }
 
-// Rollback implements the Task interface
+// Rollback implements the Task interface, the executed tasks are rolled back
+// in reverse order. It goes on when a task fails to roll back and returns the
+// first error.
 func (s *Serial) Rollback(ctx *Context) error {
-	// Rollback in reverse order
+	var firstError error
 	for i := len(s.inner) - 1; i >= 0; i-- {
-		err := s.inner[i].Rollback(ctx)
-		if err != nil {
-			return err
+		release := ctx.acquireSlot(s.inner[i])
+		err := rollbackTask(ctx, s.inner[i])
+		release()
+		if err != nil && firstError == nil {
+			firstError = err
 		}
 	}
-	return nil
+	return firstError
 }
 
 // String implements the fmt.Stringer interface
@@ -220,55 +392,49 @@ func NewParallel(hideDetailDisplay bool, tasks ...Task) *Parallel {
 
 // Execute implements the Task interface
 func (pt *Parallel) Execute(ctx *Context) error {
-	var firstError error
-	var mu sync.Mutex
-	wg := sync.WaitGroup{}
-	for _, t := range pt.inner {
-		wg.Add(1)
-		go func(t Task) {
-			defer wg.Done()
-			if !isDisplayTask(t) {
-				if !pt.hideDetailDisplay {
-					log.Infof("+ [Parallel] - %s", t.String())
-				}
-			}
-			ctx.ev.PublishTaskBegin(t)
-			err := t.Execute(ctx)
-			ctx.ev.PublishTaskFinish(t, err)
-			if err != nil {
-				mu.Lock()
-				if firstError == nil {
-					firstError = err
-				}
-				mu.Unlock()
+	return pt.each(ctx, func(t Task) error {
+		if !isDisplayTask(t) {
+			if !pt.hideDetailDisplay {
+				log.Infof("+ [Parallel] - %s", t.String())
 			}
-		}(t)
-	}
-	wg.Wait()
-	return firstError
+		}
+		return executeTask(ctx, t)
+	})
 }
 
 // Rollback implements the Task interface
 func (pt *Parallel) Rollback(ctx *Context) error {
-	var firstError error
-	var mu sync.Mutex
+	return pt.each(ctx, func(t Task) error {
+		return rollbackTask(ctx, t)
+	})
+}
+
+// each calls fn with each of the inner tasks in a goroutine, the tasks are
+// started in order as the slots of ctx are available. The errors of all the
+// failed tasks are returned in a MultiError if there are more than one.
+func (pt *Parallel) each(ctx *Context, fn func(t Task) error) error {
+	errs := make([]error, len(pt.inner))
 	wg := sync.WaitGroup{}
-	for _, t := range pt.inner {
+	for i, t := range pt.inner {
+		release := ctx.acquireSlot(t)
 		wg.Add(1)
-		go func(t Task) {
+		go func(i int, t Task) {
 			defer wg.Done()
-			err := t.Rollback(ctx)
-			if err != nil {
-				mu.Lock()
-				if firstError == nil {
-					firstError = err
-				}
-				mu.Unlock()
-			}
-		}(t)
+			defer release()
+			errs[i] = fn(t)
+		}(i, t)
 	}
 	wg.Wait()
-	return firstError
+
+	var failed []Task
+	var failedErrs []error
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, pt.inner[i])
+			failedErrs = append(failedErrs, err)
+		}
+	}
+	return mergeTaskErrors(failed, failedErrs)
 }
 
 // String implements the fmt.Stringer interface
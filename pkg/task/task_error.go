@@ -0,0 +1,182 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joomcode/errorx"
+)
+
+// TaskError is the error of a failed task, the task and the host it operates
+// on are kept along with the error.
+type TaskError struct {
+	Task string // the description of the task, i.e: its String()
+	Host string // the host the task operates on, empty if it's not on a single host
+	Err  error
+}
+
+// newTaskError wraps err of t, the errors already wrapped are returned as is
+func newTaskError(t Task, err error) error {
+	switch err.(type) {
+	case *TaskError, *MultiError:
+		return err
+	}
+	return &TaskError{
+		Task: t.String(),
+		Host: taskHost(t),
+		Err:  err,
+	}
+}
+
+// Error implements the error interface, it's the same as the error of the task
+func (e *TaskError) Error() string {
+	return e.Err.Error()
+}
+
+// Cause returns the error of the task
+func (e *TaskError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the error of the task
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// Name returns the name of the task, e.g: CopyComponent
+func (e *TaskError) Name() string {
+	line := strings.SplitN(e.Task, "\n", 2)[0]
+	return strings.SplitN(line, ":", 2)[0]
+}
+
+// MultiError is the errors of the tasks failed in a Parallel, each one is
+// kept instead of only the first one.
+type MultiError struct {
+	Errors []*TaskError
+}
+
+// Error implements the error interface
+func (e *MultiError) Error() string {
+	var lines []string
+	for _, err := range e.Errors {
+		if err.Host != "" {
+			lines = append(lines, fmt.Sprintf("%s on %s: %s", err.Name(), err.Host, err.Err))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: %s", err.Name(), err.Err))
+		}
+	}
+	return fmt.Sprintf("%d tasks failed:\n  %s", len(e.Errors), strings.Join(lines, "\n  "))
+}
+
+// TaskErrors returns the errors of the failed tasks found in the chain of err,
+// which may be wrapped by errors.Trace and alike. It returns nil if err is not
+// from the failed tasks.
+func TaskErrors(err error) []*TaskError {
+	for err != nil {
+		switch e := err.(type) {
+		case *MultiError:
+			return e.Errors
+		case *TaskError:
+			return []*TaskError{e}
+		case *errorx.Error:
+			// the errors of tasks are never wrapped by errorx
+			return nil
+		case interface{ Cause() error }:
+			err = e.Cause()
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// mergeTaskErrors merges the errors of the tasks in a Parallel, the nested
+// MultiErrors are flattened. ErrInterrupted is only returned if no task fails.
+func mergeTaskErrors(tasks []Task, errs []error) error {
+	var merged []*TaskError
+	var interrupted error
+	for i, err := range errs {
+		switch e := newTaskError(tasks[i], err).(type) {
+		case *MultiError:
+			merged = append(merged, e.Errors...)
+		case *TaskError:
+			if errorx.IsOfType(e.Err, ErrInterrupted) {
+				interrupted = e.Err
+				continue
+			}
+			merged = append(merged, e)
+		}
+	}
+
+	switch len(merged) {
+	case 0:
+		return interrupted
+	case 1:
+		return merged[0]
+	default:
+		return &MultiError{Errors: merged}
+	}
+}
+
+// taskHost returns the host a task operates on, or empty if it's not on a
+// single host
+func taskHost(t Task) string {
+	switch t := t.(type) {
+	case *RootSSH:
+		return t.host
+	case *UserSSH:
+		return t.host
+	case *TrustHostKey:
+		return t.host
+	case *EnvInit:
+		return t.host
+	case *Mkdir:
+		return t.host
+	case *Rmdir:
+		return t.host
+	case *CopyComponent:
+		return t.host
+	case *InstallPackage:
+		return t.host
+	case *BackupComponent:
+		return t.host
+	case *InitConfig:
+		return t.instance.GetHost()
+	case *ScaleConfig:
+		return t.instance.GetHost()
+	case *TLSCert:
+		return t.instance.GetHost()
+	case *MonitoredConfig:
+		return t.host
+	case *CopyFile:
+		return t.remote
+	case *Shell:
+		return t.host
+	case *SystemCtl:
+		return t.host
+	case *Sysctl:
+		return t.host
+	case *Limit:
+		return t.host
+	case *Firewall:
+		return t.host
+	case *CheckSys:
+		return t.host
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pingcap/check"
+)
+
+type parallelSuite struct{}
+
+var _ = check.Suite(&parallelSuite{})
+
+func (s *parallelSuite) TestConcurrency(c *check.C) {
+	for _, tc := range []struct {
+		concurrency int
+		max         int
+	}{
+		{0, 6}, // unlimited
+		{2, 2},
+		{10, 6},
+	} {
+		r := &graphRecorder{}
+		var tasks []Task
+		for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+			tasks = append(tasks, r.task(name, 20*time.Millisecond, nil))
+		}
+
+		ctx := NewContext()
+		ctx.Concurrency = tc.concurrency
+		c.Assert(NewBuilder().Parallel(tasks...).Build().Execute(ctx), check.IsNil)
+		ctx.Close()
+		c.Assert(r.executed, check.HasLen, 6)
+		c.Assert(r.maxRunning, check.Equals, tc.max, check.Commentf("concurrency %d", tc.concurrency))
+	}
+}
+
+func (s *parallelSuite) TestNestedConcurrency(c *check.C) {
+	r := &graphRecorder{}
+	g := NewGraph()
+	for _, group := range []string{"a", "b", "c"} {
+		var tasks []Task
+		for _, name := range []string{"1", "2", "3"} {
+			tasks = append(tasks, r.task(group+name, 10*time.Millisecond, nil))
+		}
+		g.Add(NewBuilder().Parallel(tasks...).Build())
+	}
+
+	ctx := NewContext()
+	defer ctx.Close()
+	ctx.Concurrency = 2
+	c.Assert(NewBuilder().Parallel(g, r.task("d", 10*time.Millisecond, nil)).Build().Execute(ctx), check.IsNil)
+	// the limit is shared by all the nested tasks
+	c.Assert(r.executed, check.HasLen, 10)
+	c.Assert(r.maxRunning, check.Equals, 2)
+}
+
+func (s *parallelSuite) TestFailures(c *check.C) {
+	r := &graphRecorder{}
+	ctx := NewContext()
+	defer ctx.Close()
+	ctx.Concurrency = 1
+	err := NewBuilder().Parallel(
+		r.task("a", 0, errors.New("a failed")),
+		r.task("b", 0, nil),
+		r.task("c", 0, errors.New("c failed")),
+	).Build().Execute(ctx)
+
+	// all the tasks are executed in order, and the errors are merged
+	c.Assert(r.executed, check.DeepEquals, []string{"a", "b", "c"})
+	multi, ok := err.(*MultiError)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(multi.Errors, check.HasLen, 2)
+}
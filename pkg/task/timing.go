@@ -0,0 +1,268 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+)
+
+// TimingSummary is the time taken by a step, host or kind of tasks
+type TimingSummary struct {
+	Name  string
+	Count int           // number of the tasks or the times of the step
+	Total time.Duration // wall-clock time of a step, or the sum of the time of the tasks
+	Max   time.Duration // the longest of the tasks or the times of the step
+}
+
+type timingTable struct {
+	order []string
+	items map[string]*TimingSummary
+}
+
+func (tt *timingTable) add(name string, d time.Duration) {
+	if tt.items == nil {
+		tt.items = make(map[string]*TimingSummary)
+	}
+	s, ok := tt.items[name]
+	if !ok {
+		s = &TimingSummary{Name: name}
+		tt.items[name] = s
+		tt.order = append(tt.order, name)
+	}
+	s.Count++
+	s.Total += d
+	if d > s.Max {
+		s.Max = d
+	}
+}
+
+// list returns the summaries in the order of being added
+func (tt *timingTable) list() []TimingSummary {
+	list := make([]TimingSummary, 0, len(tt.order))
+	for _, name := range tt.order {
+		list = append(list, *tt.items[name])
+	}
+	return list
+}
+
+// Timing collects how long the tasks take from the task events, it's broken
+// down by the steps, i.e: the StepDisplay prefixes, the hosts and the kinds of
+// tasks, e.g: download, copy and config. The phases of the cluster operations,
+// e.g: transferring leaders, are also collected if they're reported by
+// RecordTiming. It can be shared by multiple contexts.
+type Timing struct {
+	mu     sync.Mutex
+	begins map[Task]time.Time
+	steps  map[Task]struct{} // the tasks in the steps being timed
+
+	stepTable timingTable
+	hostTable timingTable
+	kindTable timingTable
+}
+
+// NewTiming creates an empty Timing
+func NewTiming() *Timing {
+	return &Timing{
+		begins: make(map[Task]time.Time),
+		steps:  make(map[Task]struct{}),
+	}
+}
+
+// SetTiming makes the time taken by the tasks executed with ctx collected by t
+func (ctx *Context) SetTiming(t *Timing) {
+	ctx.timing = t
+	ctx.ev.Subscribe(EventTaskBegin, t.handleTaskBegin)
+	ctx.ev.Subscribe(EventTaskFinish, t.handleTaskFinish)
+}
+
+// RecordTiming implements the operation.TimingRecorder interface
+func (ctx *Context) RecordTiming(kind, host string, d time.Duration) {
+	if ctx.timing == nil {
+		return
+	}
+	ctx.timing.mu.Lock()
+	defer ctx.timing.mu.Unlock()
+	ctx.timing.kindTable.add(kind, d)
+	if host != "" {
+		ctx.timing.hostTable.add(host, d)
+	}
+}
+
+// Steps returns the wall-clock time of the outermost steps in the order of
+// being executed.
+func (t *Timing) Steps() []TimingSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stepTable.list()
+}
+
+// Hosts returns the time of the tasks on each host, the longest first
+func (t *Timing) Hosts() []TimingSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return sortByTotal(t.hostTable.list())
+}
+
+// Kinds returns the time of each kind of tasks, the longest first. The phases
+// of a cluster operation are counted along with the operation itself.
+func (t *Timing) Kinds() []TimingSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return sortByTotal(t.kindTable.list())
+}
+
+func sortByTotal(list []TimingSummary) []TimingSummary {
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Total > list[j].Total
+	})
+	return list
+}
+
+func (t *Timing) handleTaskBegin(task Task) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if isDisplayTask(task) {
+		// only the outermost steps are timed
+		if _, ok := stepPrefix(task); !ok {
+			return
+		}
+		if _, ok := t.steps[task]; ok {
+			return
+		}
+		walkTasks(task, func(sub Task) {
+			t.steps[sub] = struct{}{}
+		})
+	}
+	t.begins[task] = time.Now()
+}
+
+func (t *Timing) handleTaskFinish(task Task, _ *error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	begin, ok := t.begins[task]
+	if !ok {
+		return
+	}
+	delete(t.begins, task)
+	d := time.Since(begin)
+
+	if prefix, ok := stepPrefix(task); ok {
+		// the step may be executed again
+		walkTasks(task, func(sub Task) {
+			delete(t.steps, sub)
+		})
+		t.stepTable.add(strings.TrimSpace(strings.TrimLeft(prefix, " +-")), d)
+		return
+	}
+	t.kindTable.add(taskKind(task), d)
+	if host := taskHost(task); host != "" {
+		t.hostTable.add(host, d)
+	}
+}
+
+// stepPrefix returns the prefix of t if it's a step
+func stepPrefix(t Task) (string, bool) {
+	switch t := t.(type) {
+	case *StepDisplay:
+		return t.prefix, true
+	case *ParallelStepDisplay:
+		return t.prefix, true
+	case *GraphStepDisplay:
+		return t.prefix, true
+	}
+	return "", false
+}
+
+// walkTasks calls fn with t and all the tasks in it
+func walkTasks(t Task, fn func(t Task)) {
+	fn(t)
+	switch t := t.(type) {
+	case *Serial:
+		for _, sub := range t.inner {
+			walkTasks(sub, fn)
+		}
+	case *Parallel:
+		for _, sub := range t.inner {
+			walkTasks(sub, fn)
+		}
+	case *Graph:
+		for _, node := range t.nodes {
+			walkTasks(node.task, fn)
+		}
+	case *StepDisplay:
+		walkTasks(t.inner, fn)
+	case *ParallelStepDisplay:
+		walkTasks(t.inner, fn)
+	case *GraphStepDisplay:
+		walkTasks(t.inner, fn)
+	}
+}
+
+// taskKind returns the kind of the task shown in the timing report
+func taskKind(t Task) string {
+	switch t := t.(type) {
+	case *Downloader:
+		return "download"
+	case *CopyComponent, *InstallPackage, *BackupComponent, *CopyFile:
+		return "copy"
+	case *InitConfig, *ScaleConfig, *MonitoredConfig:
+		return "config"
+	case *RootSSH, *UserSSH, *SSHKeySet, *SSHKeyGen, *TrustHostKey:
+		return "ssh"
+	case *EnvInit, *Mkdir, *Rmdir, *Sysctl, *Limit, *Firewall:
+		return "environment"
+	case *TLSCAGen, *TLSRotate, *TLSCert:
+		return "tls"
+	case *SystemCtl:
+		return "systemctl"
+	case *CheckSys:
+		return "check"
+	case *Shell:
+		return "shell"
+	case *ClusterOperate:
+		return operationKind(t.op)
+	}
+	return "other"
+}
+
+// operationKind returns the kind of the cluster operation, e.g: "scale-in"
+// for ScaleInOperation
+func operationKind(op operator.Operation) string {
+	switch op {
+	case operator.StartOperation:
+		return "start"
+	case operator.StopOperation:
+		return "stop"
+	case operator.RestartOperation:
+		return "restart"
+	case operator.DestroyOperation:
+		return "destroy"
+	case operator.UpgradeOperation:
+		return "upgrade"
+	case operator.ScaleInOperation:
+		return "scale-in"
+	case operator.ScaleOutOperation:
+		return "scale-out"
+	case operator.DestroyTombstoneOperation:
+		return "destroy tombstone"
+	}
+	return op.String()
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"time"
+
+	"github.com/pingcap/check"
+)
+
+type timingSuite struct{}
+
+var _ = check.Suite(&timingSuite{})
+
+func sleepTask(name string, d time.Duration) *Func {
+	return NewFunc(name, func(ctx *Context) error {
+		time.Sleep(d)
+		return nil
+	})
+}
+
+func (s *timingSuite) TestSteps(c *check.C) {
+	ctx := newFakeContext(&fakeExecutor{})
+	defer ctx.Close()
+	timing := NewTiming()
+	ctx.SetTiming(timing)
+
+	inner := newStepDisplay("  - Inner", sleepTask("a", 10*time.Millisecond)).SetHidden(true)
+	outer := newStepDisplay("+ Outer", &Serial{inner: []Task{inner, sleepTask("b", 0)}}).SetHidden(true)
+	last := newStepDisplay("+ Last", sleepTask("c", 0)).SetHidden(true)
+	c.Assert((&Serial{inner: []Task{outer, last}}).Execute(ctx), check.IsNil)
+
+	// only the outermost steps are timed
+	steps := timing.Steps()
+	c.Assert(steps, check.HasLen, 2)
+	c.Assert(steps[0].Name, check.Equals, "Outer")
+	c.Assert(steps[0].Count, check.Equals, 1)
+	c.Assert(steps[0].Total >= 10*time.Millisecond, check.IsTrue)
+	c.Assert(steps[0].Max, check.Equals, steps[0].Total)
+	c.Assert(steps[1].Name, check.Equals, "Last")
+
+	// the step executed again is timed again
+	c.Assert((&Serial{inner: []Task{outer}}).Execute(ctx), check.IsNil)
+	steps = timing.Steps()
+	c.Assert(steps, check.HasLen, 2)
+	c.Assert(steps[0].Count, check.Equals, 2)
+	c.Assert(steps[0].Total >= 20*time.Millisecond, check.IsTrue)
+	c.Assert(steps[0].Max < steps[0].Total, check.IsTrue)
+}
+
+func (s *timingSuite) TestHostsAndKinds(c *check.C) {
+	ctx := newFakeContext(&fakeExecutor{})
+	ctx.SetExecutor("127.0.0.2", &fakeExecutor{})
+	defer ctx.Close()
+	timing := NewTiming()
+	ctx.SetTiming(timing)
+
+	c.Assert(NewParallel(false,
+		&Mkdir{user: "tidb", host: "127.0.0.1", dirs: []string{"/data"}},
+		&Mkdir{user: "tidb", host: "127.0.0.2", dirs: []string{"/data"}},
+		&BackupComponent{component: "tikv", fromVer: "v4.0.0", host: "127.0.0.1", deployDir: "/deploy"},
+		sleepTask("a", 0),
+	).Execute(ctx), check.IsNil)
+
+	// the phases reported are counted along with the tasks
+	ctx.RecordTiming("transfer leader", "127.0.0.2", 2*time.Second)
+	ctx.RecordTiming("transfer leader", "127.0.0.2", time.Second)
+	ctx.RecordTiming("wait", "", time.Second)
+
+	hosts := timing.Hosts()
+	c.Assert(hosts, check.HasLen, 2)
+	c.Assert(hosts[0].Name, check.Equals, "127.0.0.2")
+	c.Assert(hosts[0].Count, check.Equals, 3)
+	c.Assert(hosts[0].Total > 3*time.Second, check.IsTrue)
+	c.Assert(hosts[0].Max, check.Equals, 2*time.Second)
+	c.Assert(hosts[1].Name, check.Equals, "127.0.0.1")
+	c.Assert(hosts[1].Count, check.Equals, 2)
+
+	counts := make(map[string]int)
+	for _, k := range timing.Kinds() {
+		counts[k.Name] = k.Count
+	}
+	c.Assert(counts, check.DeepEquals, map[string]int{
+		"environment":     2,
+		"copy":            1,
+		"other":           1,
+		"transfer leader": 2,
+		"wait":            1,
+	})
+	kinds := timing.Kinds()
+	c.Assert(kinds[0].Name, check.Equals, "transfer leader")
+	c.Assert(kinds[0].Total, check.Equals, 3*time.Second)
+	c.Assert(kinds[1].Name, check.Equals, "wait")
+
+	// nothing is recorded without timing
+	ctx = NewContext()
+	defer ctx.Close()
+	ctx.RecordTiming("transfer leader", "127.0.0.1", time.Second)
+}
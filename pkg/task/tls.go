@@ -0,0 +1,290 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/certutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+// tlsClientName is the common name of the client certificate used to access
+// the APIs of the cluster
+const tlsClientName = "tiup"
+
+// TLSCAGen generates the CA of the cluster, and the client certificate signed
+// by it to access the APIs of the cluster
+type TLSCAGen struct {
+	clusterName string
+	generated   []string // the files generated instead of existing before
+}
+
+// Execute implements the Task interface
+func (g *TLSCAGen) Execute(ctx *Context) error {
+	dir := meta.ClusterPath(g.clusterName, meta.TLSCertKeyDir)
+	caCert, caKey := filepath.Join(dir, meta.TLSCACert), filepath.Join(dir, meta.TLSCAKey)
+	clientCert, clientKey := filepath.Join(dir, meta.TLSClientCert), filepath.Join(dir, meta.TLSClientKey)
+
+	// the CA is kept once generated, so that the certificates of the instances
+	// deployed before are still valid
+	if utils.IsExist(caCert) && utils.IsExist(caKey) && utils.IsExist(clientCert) && utils.IsExist(clientKey) {
+		return nil
+	}
+
+	if ctx.DryRun() {
+		ctx.Recorder.Note("", "generate the CA of the cluster %s and %s, and the client certificate %s and %s",
+			caCert, caKey, clientCert, clientKey)
+		return nil
+	}
+
+	ctx.ev.PublishTaskProgress(g, "Generate CA")
+	ca, err := certutil.NewCA(g.clusterName)
+	if err != nil {
+		return err
+	}
+	g.generated = append(g.generated, caCert, caKey)
+	if err := ca.Save(caCert, caKey); err != nil {
+		return err
+	}
+
+	ctx.ev.PublishTaskProgress(g, "Generate client certificate")
+	certPEM, keyPEM, err := ca.Sign(tlsClientName, nil)
+	if err != nil {
+		return err
+	}
+	g.generated = append(g.generated, clientCert, clientKey)
+	return writeCertKey(clientCert, clientKey, certPEM, keyPEM)
+}
+
+// Rollback implements the Task interface, the files existing before are kept
+func (g *TLSCAGen) Rollback(ctx *Context) error {
+	for _, path := range g.generated {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// String implements the fmt.Stringer interface
+func (g *TLSCAGen) String() string {
+	return fmt.Sprintf("TLSCAGen: cluster=%s", g.clusterName)
+}
+
+// TLSRotate renews the certificates in the cluster directory on rotation. If
+// ca is set, a new CA is generated to sign the certificates, and the previous
+// one is still trusted until the next rotation of the CA, so that the instances
+// keep trusting each other while they're restarted one by one. Otherwise, the
+// client certificate is signed again with the CA.
+type TLSRotate struct {
+	clusterName string
+	ca          bool
+
+	backup []fileBackup // the files before rotation
+}
+
+type fileBackup struct {
+	path string
+	data []byte
+	mode os.FileMode
+}
+
+// Execute implements the Task interface
+func (r *TLSRotate) Execute(ctx *Context) error {
+	dir := meta.ClusterPath(r.clusterName, meta.TLSCertKeyDir)
+	caCert, caKey := filepath.Join(dir, meta.TLSCACert), filepath.Join(dir, meta.TLSCAKey)
+	clientCert, clientKey := filepath.Join(dir, meta.TLSClientCert), filepath.Join(dir, meta.TLSClientKey)
+
+	ca, err := certutil.LoadCA(caCert, caKey)
+	if err != nil {
+		return err
+	}
+
+	if r.ca {
+		// the client certificate is signed again after the certificates of
+		// all the instances, so the ones signed by the previous CA, which
+		// would not be trusted after the rotation, are found by it
+		cert, err := certutil.LoadCertificate(clientCert)
+		if err != nil {
+			return err
+		}
+		if err := cert.CheckSignatureFrom(ca.Cert); err != nil {
+			return errors.New("the certificates are not all signed by the current CA, they must be rotated before rotating the CA again")
+		}
+	}
+
+	if ctx.DryRun() {
+		if r.ca {
+			ctx.Recorder.Note("", "generate a new CA of the cluster to %s and %s, the current one is still trusted", caCert, caKey)
+		} else {
+			ctx.Recorder.Note("", "sign the client certificate %s and %s again with the CA of the cluster", clientCert, clientKey)
+		}
+		return nil
+	}
+
+	if r.ca {
+		if err := r.backupFiles(caCert, caKey); err != nil {
+			return err
+		}
+		ctx.ev.PublishTaskProgress(r, "Generate CA")
+		newCA, err := certutil.NewCA(r.clusterName)
+		if err != nil {
+			return err
+		}
+		return newCA.Save(caCert, caKey, ca)
+	}
+
+	if err := r.backupFiles(clientCert, clientKey); err != nil {
+		return err
+	}
+	ctx.ev.PublishTaskProgress(r, "Generate client certificate")
+	certPEM, keyPEM, err := ca.Sign(tlsClientName, nil)
+	if err != nil {
+		return err
+	}
+	return writeCertKey(clientCert, clientKey, certPEM, keyPEM)
+}
+
+func (r *TLSRotate) backupFiles(paths ...string) error {
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		r.backup = append(r.backup, fileBackup{path: path, data: data, mode: fi.Mode()})
+	}
+	return nil
+}
+
+// Rollback implements the Task interface, the files before rotation are restored
+func (r *TLSRotate) Rollback(ctx *Context) error {
+	for _, b := range r.backup {
+		if err := ioutil.WriteFile(b.path, b.data, b.mode); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// String implements the fmt.Stringer interface
+func (r *TLSRotate) String() string {
+	return fmt.Sprintf("TLSRotate: cluster=%s, ca=%v", r.clusterName, r.ca)
+}
+
+// TLSCert signs the certificate of the instance with the CA of the cluster, and
+// copies it to the deploy directory along with the certificate of the CA
+type TLSCert struct {
+	clusterName string
+	instance    meta.Instance
+	deployDir   string
+
+	signed bool // the certificate is signed in the cluster directory
+	exec   executor.TiOpsExecutor
+}
+
+// Execute implements the Task interface
+func (c *TLSCert) Execute(ctx *Context) error {
+	e, ok := ctx.GetExecutor(c.instance.GetHost())
+	if !ok {
+		return ErrNoExecutor
+	}
+
+	dir := meta.ClusterPath(c.clusterName, meta.TLSCertKeyDir)
+	certFile, keyFile := meta.TLSSignedCertKeyPaths(c.clusterName, c.instance)
+
+	if ctx.DryRun() {
+		ctx.Recorder.Note("", "sign the certificate of %s with the CA of the cluster to %s and %s",
+			c.instance.ID(), certFile, keyFile)
+	} else {
+		ca, err := certutil.LoadCA(filepath.Join(dir, meta.TLSCACert), filepath.Join(dir, meta.TLSCAKey))
+		if err != nil {
+			return err
+		}
+		// the instance may be accessed by the address in topology or locally
+		hosts := []string{c.instance.GetHost(), "127.0.0.1", "localhost"}
+		certPEM, keyPEM, err := ca.Sign(c.instance.ComponentName(), hosts)
+		if err != nil {
+			return err
+		}
+		c.signed = true
+		if err := writeCertKey(certFile, keyFile, certPEM, keyPEM); err != nil {
+			return err
+		}
+	}
+
+	c.exec = e
+	caDst, certDst, keyDst := meta.TLSCertKeyPaths(c.deployDir, c.instance.ComponentName())
+	for _, f := range []struct {
+		src, dst string
+		mode     os.FileMode
+	}{
+		{filepath.Join(dir, meta.TLSCACert), caDst, 0644},
+		{certFile, certDst, 0644},
+		// the private key is never readable by others
+		{keyFile, keyDst, 0600},
+	} {
+		if err := e.UploadWithMode(f.src, f.dst, f.mode); err != nil {
+			return errors.Annotatef(err, "failed to transfer %s to %s:%s", f.src, c.instance.GetHost(), f.dst)
+		}
+	}
+	return nil
+}
+
+// Rollback implements the Task interface, the signed and copied certificates
+// are removed
+func (c *TLSCert) Rollback(ctx *Context) error {
+	if c.signed {
+		certFile, keyFile := meta.TLSSignedCertKeyPaths(c.clusterName, c.instance)
+		for _, path := range []string{certFile, keyFile} {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return errors.Trace(err)
+			}
+		}
+	}
+	if c.exec == nil {
+		return nil
+	}
+	caDst, certDst, keyDst := meta.TLSCertKeyPaths(c.deployDir, c.instance.ComponentName())
+	_, _, err := c.exec.Execute(fmt.Sprintf("rm -f %s %s %s", caDst, certDst, keyDst), false)
+	return errors.Trace(err)
+}
+
+// String implements the fmt.Stringer interface
+func (c *TLSCert) String() string {
+	return fmt.Sprintf("TLSCert: cluster=%s, instance=%s, path=%s",
+		c.clusterName, c.instance.ID(), filepath.Join(c.deployDir, meta.TLSCertKeyDir))
+}
+
+// writeCertKey writes the certificate and the private key, which is only
+// readable by the current user
+func writeCertKey(certFile, keyFile string, certPEM, keyPEM []byte) error {
+	if err := os.MkdirAll(filepath.Dir(certFile), 0755); err != nil {
+		return errors.Trace(err)
+	}
+	if err := ioutil.WriteFile(certFile, certPEM, 0644); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ioutil.WriteFile(keyFile, keyPEM, 0600))
+}
@@ -0,0 +1,75 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// TrustHostKey replaces the saved SSH host key of a host with the one it presents
+// now, the type and fingerprint of the new key are set as the stdout of the host.
+type TrustHostKey struct {
+	host    string
+	port    int
+	user    string
+	timeout int64
+	sshType executor.SSHType
+	proxy   *meta.SSHProxy
+}
+
+// Execute implements the Task interface
+func (t *TrustHostKey) Execute(ctx *Context) error {
+	if ctx.KnownHosts == nil {
+		return errors.Errorf("context has no KnownHosts")
+	}
+
+	addr, key, err := executor.ScanHostKey(t.sshType, executor.SSHConfig{
+		Host:    t.host,
+		Port:    t.port,
+		User:    t.user,
+		Timeout: time.Second * time.Duration(t.timeout),
+		Proxy:   proxyConfig(t.proxy, t.timeout),
+
+		KnownHosts: ctx.KnownHosts,
+	})
+	if err != nil {
+		return err
+	}
+	// the host is not connected via SSH
+	if key == nil {
+		return nil
+	}
+
+	if err := ctx.KnownHosts.Trust(addr, key); err != nil {
+		return errors.Annotatef(err, "failed to save the host key of %s", addr)
+	}
+	ctx.SetOutputs(t.host, []byte(fmt.Sprintf("%s %s", key.Type(), ssh.FingerprintSHA256(key))), nil)
+	return nil
+}
+
+// Rollback implements the Task interface
+func (t *TrustHostKey) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (t *TrustHostKey) String() string {
+	return fmt.Sprintf("TrustHostKey: host=%s, port=%d%s", t.host, t.port, proxyString(t.proxy))
+}
@@ -101,11 +101,18 @@ func (u *UpdateMeta) Execute(ctx *Context) error {
 		}
 		newMeta.Topology.Alertmanager = append(newMeta.Topology.Alertmanager, topo.Alertmanager[i])
 	}
+	if ctx.DryRun() {
+		ctx.Recorder.Note("", "save the meta of cluster %s", u.cluster)
+		return nil
+	}
 	return meta.SaveClusterMeta(u.cluster, newMeta)
 }
 
 // Rollback implements the Task interface
 func (u *UpdateMeta) Rollback(ctx *Context) error {
+	if ctx.DryRun() {
+		return nil
+	}
 	return meta.SaveClusterMeta(u.cluster, u.metadata)
 }
 
@@ -25,7 +25,16 @@ func (u *UpdateTopology) String() string {
 
 // Execute implements the Task interface
 func (u *UpdateTopology) Execute(ctx *Context) error {
-	client, err := u.metadata.Topology.GetEtcdClient()
+	if ctx.DryRun() {
+		ctx.Recorder.Note("", "update the topology of monitoring components in the etcd of PD")
+		return nil
+	}
+
+	tlsCfg, err := u.metadata.Topology.TLSConfig(meta.ClusterPath(u.cluster, meta.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+	client, err := u.metadata.Topology.GetEtcdClient(tlsCfg)
 	if err != nil {
 		return err
 	}
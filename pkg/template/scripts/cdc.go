@@ -29,6 +29,7 @@ type CDCScript struct {
 	DeployDir string
 	LogDir    string
 	NumaNode  string
+	TLS       bool
 	Endpoints []*PDScript
 }
 
@@ -54,6 +55,12 @@ func (c *CDCScript) WithNumaNode(numa string) *CDCScript {
 	return c
 }
 
+// WithTLS set TLS field of CDCScript
+func (c *CDCScript) WithTLS(enabled bool) *CDCScript {
+	c.TLS = enabled
+	return c
+}
+
 // Config generate the config file data.
 func (c *CDCScript) Config() ([]byte, error) {
 	fp := path.Join("/templates", "scripts", "run_cdc.sh.tpl")
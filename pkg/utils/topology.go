@@ -15,6 +15,8 @@ package utils
 
 import (
 	"io/ioutil"
+	"strconv"
+	"strings"
 
 	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
@@ -62,3 +64,71 @@ Please check the syntax of your topology file {{ColorKeyword}}{{.File}}{{ColorRe
 
 	return nil
 }
+
+// YamlLine returns the line number of the node at the path in the yaml, e.g:
+// tidb_servers.1.port, or of its nearest ancestor if the node is not written
+// in the yaml, 0 if nothing is found. Only the block style is looked into.
+func YamlLine(data []byte, path string) int {
+	lines := strings.Split(string(data), "\n")
+	line := 0
+	start, end := 0, len(lines) // the lines of the content of the current node
+	for _, key := range strings.Split(path, ".") {
+		index, err := strconv.Atoi(key)
+		isIndex := err == nil
+
+		found := -1
+		indent := -1 // the indent of the children of the current node
+		for i, n := start, 0; i < end && found < 0; i++ {
+			text, ind, ok := yamlContent(lines[i])
+			if !ok {
+				continue
+			}
+			if indent < 0 {
+				indent = ind
+			}
+			switch {
+			case ind != indent:
+			case isIndex && (text == "-" || strings.HasPrefix(text, "- ")):
+				if n == index {
+					found = i
+				}
+				n++
+			case !isIndex && strings.HasPrefix(text, key+":"):
+				found = i
+			}
+		}
+		if found < 0 {
+			break
+		}
+		line = found + 1
+
+		// the content of an item starts from its line, after the "-"
+		start = found + 1
+		if isIndex {
+			lines[found] = strings.Replace(lines[found], "-", " ", 1)
+			start = found
+		}
+		for end = found + 1; end < len(lines); end++ {
+			text, ind, ok := yamlContent(lines[end])
+			if !ok {
+				continue
+			}
+			// the items of a sequence can be at the same indent as its key
+			if ind < indent || (ind == indent && (isIndex || !strings.HasPrefix(text, "-"))) {
+				break
+			}
+		}
+	}
+	return line
+}
+
+// yamlContent returns the line without the indent and the indent, ok is false
+// for empty lines and comments
+func yamlContent(line string) (text string, indent int, ok bool) {
+	line = strings.TrimRight(line, "\r")
+	text = strings.TrimLeft(line, " ")
+	if text == "" || strings.HasPrefix(text, "#") {
+		return "", 0, false
+	}
+	return text, len(line) - len(text), true
+}
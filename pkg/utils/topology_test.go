@@ -22,3 +22,29 @@ func (s *topoSuite) TestParseTopologyYaml(c *check.C) {
 	err := ParseTopologyYaml(file, &mp)
 	c.Assert(err, check.IsNil)
 }
+
+func (s *topoSuite) TestYamlLine(c *check.C) {
+	data := []byte(`
+global:
+  user: "tidb"
+
+tidb_servers:
+- host: 172.16.5.138
+  # comment
+  port: 4000
+- host: 172.16.5.139
+  config:
+    log.level: warn
+pd_servers:
+  -   host: 172.16.5.140
+      client_port: 2379
+`)
+	c.Assert(YamlLine(data, "global.user"), check.Equals, 3)
+	c.Assert(YamlLine(data, "tidb_servers.0.port"), check.Equals, 8)
+	c.Assert(YamlLine(data, "tidb_servers.1.host"), check.Equals, 9)
+	c.Assert(YamlLine(data, "tidb_servers.1.config"), check.Equals, 10)
+	c.Assert(YamlLine(data, "tidb_servers.1.port"), check.Equals, 9)
+	c.Assert(YamlLine(data, "pd_servers.0.client_port"), check.Equals, 14)
+	c.Assert(YamlLine(data, "pd_servers.1.port"), check.Equals, 12)
+	c.Assert(YamlLine(data, "monitored.node_exporter_port"), check.Equals, 0)
+}
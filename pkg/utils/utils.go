@@ -14,6 +14,7 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -41,6 +42,7 @@ type RetryOption struct {
 	Attempts int64
 	Delay    time.Duration
 	Timeout  time.Duration
+	Context  context.Context // stop retrying once it's done, optional
 }
 
 // default values for RetryOption
@@ -51,7 +53,7 @@ var (
 )
 
 // Retry retries the func until it returns no error or reaches attempts limit or
-// timed out, either one is earlier
+// timed out or the context is done, either one is earlier
 func Retry(doFunc func() error, opts ...RetryOption) error {
 	var cfg RetryOption
 	if len(opts) > 0 {
@@ -77,6 +79,10 @@ func Retry(doFunc func() error, opts ...RetryOption) error {
 	}
 
 	timeoutChan := time.After(cfg.Timeout)
+	var done <-chan struct{}
+	if cfg.Context != nil {
+		done = cfg.Context.Done()
+	}
 
 	// call the function
 	var attemptCount int64
@@ -89,8 +95,14 @@ func Retry(doFunc func() error, opts ...RetryOption) error {
 		select {
 		case <-timeoutChan:
 			return fmt.Errorf("operation timed out after %s", cfg.Timeout)
+		case <-done:
+			return cfg.Context.Err()
 		default:
-			time.Sleep(cfg.Delay)
+			select {
+			case <-time.After(cfg.Delay):
+			case <-done:
+				return cfg.Context.Err()
+			}
 		}
 	}
 
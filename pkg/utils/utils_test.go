@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pingcap/check"
+)
+
+type retrySuite struct{}
+
+var _ = check.Suite(&retrySuite{})
+
+func (s *retrySuite) TestRetryContext(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	err := Retry(func() error {
+		attempts++
+		return errors.New("not ready")
+	}, RetryOption{
+		Delay:   time.Second,
+		Timeout: time.Minute,
+		Context: ctx,
+	})
+	c.Assert(err, check.Equals, context.Canceled)
+	c.Assert(attempts, check.Equals, 1)
+	// it's stopped in the delay between attempts
+	c.Assert(time.Since(start) < time.Second, check.IsTrue)
+
+	// the context is not checked once it succeeds
+	err = Retry(func() error { return nil }, RetryOption{Timeout: time.Second, Context: ctx})
+	c.Assert(err, check.IsNil)
+}
@@ -24,4 +24,9 @@ exec bin/cdc server \
     --addr "0.0.0.0:{{.Port}}" \
     --advertise-addr "{{.IP}}:{{.Port}}" \
     --pd "{{template "PDList" .Endpoints}}" \
+{{- if .TLS}}
+    --ca "tls/ca.crt" \
+    --cert "tls/cdc.crt" \
+    --key "tls/cdc.pem" \
+{{- end}}
     --log-file "{{.LogDir}}/cdc.log" 2>> "{{.LogDir}}/cdc_stderr.log"
@@ -0,0 +1,62 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	var dest string
+	rootCmd := &cobra.Command{
+		Use:          "schema",
+		Short:        "Generate the JSON Schema of the topology files",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generate(dest)
+		},
+	}
+
+	rootCmd.Flags().StringVarP(&dest, "destination", "d", "schema", "The destination directory path")
+
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func generate(dest string) error {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+
+	for file, schema := range meta.JSONSchemas() {
+		data, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dest, file)
+		if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+		fmt.Println("Generated", path)
+	}
+	return nil
+}